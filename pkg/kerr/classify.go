@@ -0,0 +1,219 @@
+package kerr
+
+import "errors"
+
+// Category is a broad classification of an error that groups errors which
+// are handled the same way: rather than switching on every error a request
+// can fail with, you can switch on the category, or on the category's
+// suggested Action.
+type Category uint8
+
+const (
+	// Unknown is the category of UnknownServerError, and of any error
+	// that is not a Kafka error.
+	Unknown Category = iota
+
+	// Transient errors are temporary: the cluster is moving leadership,
+	// loading a coordinator, rebalancing a group, and so on. The request
+	// can be retried, and the client retries most of these internally.
+	Transient
+
+	// Auth errors mean the client failed to authenticate (SASL), or the
+	// authenticated principal is not authorized for the request (ACLs).
+	// Retrying does not help until credentials or ACLs change.
+	Auth
+
+	// Config errors mean the request was invalid for how the client or the
+	// cluster is configured: a record is too large, a topic name is
+	// invalid, a timeout is out of the broker's allowed range, and so on.
+	Config
+
+	// FatalProducer errors mean the producer's idempotent or transactional
+	// state is broken beyond what the client can recover from: sequence
+	// numbers are out of order, or the producer ID is unknown or invalid
+	// for the transaction state. The producer (client) must be recreated.
+	FatalProducer
+
+	// Fenced errors mean another instance has taken over this instance's
+	// identity: a newer producer with the same transactional ID, or a
+	// newer static group member with the same instance ID. This instance
+	// should stop.
+	Fenced
+
+	// DataLoss errors mean records that were being consumed no longer
+	// exist, because of retention, truncation, or an unclean leader
+	// election.
+	DataLoss
+
+	// Rejected errors mean the broker rejected the request for a reason
+	// specific to that request: the topic already exists, the group is
+	// not empty, the resource does not exist. Errors that are only used
+	// between brokers are also in this category.
+	Rejected
+)
+
+func (c Category) String() string {
+	switch c {
+	case Transient:
+		return "TRANSIENT"
+	case Auth:
+		return "AUTH"
+	case Config:
+		return "CONFIG"
+	case FatalProducer:
+		return "FATAL_PRODUCER"
+	case Fenced:
+		return "FENCED"
+	case DataLoss:
+		return "DATA_LOSS"
+	case Rejected:
+		return "REJECTED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Action is the suggested way to handle an error in a given Category.
+type Action uint8
+
+const (
+	// Alert suggests surfacing the error to an operator: the error is
+	// either unknown or depends on what the request was for.
+	Alert Action = iota
+
+	// Retry suggests retrying, ideally with backoff.
+	Retry
+
+	// Reconfigure suggests fixing the client, broker, or topic
+	// configuration, or credentials or ACLs, before trying again.
+	Reconfigure
+
+	// Crash suggests stopping (and, for FatalProducer, restarting) the
+	// client, because it cannot make further progress as is.
+	Crash
+)
+
+func (a Action) String() string {
+	switch a {
+	case Retry:
+		return "RETRY"
+	case Reconfigure:
+		return "RECONFIGURE"
+	case Crash:
+		return "CRASH"
+	default:
+		return "ALERT"
+	}
+}
+
+// Action returns the suggested action for errors in the category.
+func (c Category) Action() Action {
+	switch c {
+	case Transient:
+		return Retry
+	case Auth, Config:
+		return Reconfigure
+	case FatalProducer, Fenced:
+		return Crash
+	default:
+		return Alert
+	}
+}
+
+// Category returns the category of the error. A nil error is Unknown.
+func (e *Error) Category() Category {
+	switch e {
+	case nil, UnknownServerError:
+		return Unknown
+
+	case IllegalGeneration,
+		UnknownMemberID,
+		RebalanceInProgress,
+		ConcurrentTransactions,
+		MemberIDRequired,
+		FencedMemberEpoch, // the member rejoins with a new epoch
+		UnreleasedInstanceID,
+		StaleMemberEpoch:
+		return Transient
+
+	case TopicAuthorizationFailed,
+		GroupAuthorizationFailed,
+		ClusterAuthorizationFailed,
+		UnsupportedSaslMechanism,
+		IllegalSaslState,
+		TransactionalIDAuthorizationFailed,
+		SecurityDisabled,
+		SaslAuthenticationFailed,
+		DelegationTokenAuthDisabled,
+		DelegationTokenNotFound,
+		DelegationTokenOwnerMismatch,
+		DelegationTokenRequestNotAllowed,
+		DelegationTokenAuthorizationFailed,
+		DelegationTokenExpired,
+		InvalidPrincipalType,
+		UnacceptableCredential,
+		PrincipalDeserializationFailure:
+		return Auth
+
+	case InvalidFetchSize,
+		MessageTooLarge,
+		OffsetMetadataTooLarge,
+		InvalidTopicException,
+		RecordListTooLarge,
+		InvalidRequiredAcks,
+		InconsistentGroupProtocol,
+		InvalidGroupID,
+		InvalidSessionTimeout,
+		InvalidCommitOffsetSize,
+		InvalidTimestamp,
+		UnsupportedVersion,
+		InvalidPartitions,
+		InvalidReplicationFactor,
+		InvalidReplicaAssignment,
+		InvalidConfig,
+		InvalidRequest,
+		UnsupportedForMessageFormat,
+		PolicyViolation,
+		InvalidTransactionTimeout,
+		TopicDeletionDisabled,
+		UnsupportedCompressionType,
+		GroupMaxSizeReached,
+		InvalidRecord,
+		UnsupportedAssignor,
+		MismatchedEndpointType,
+		UnsupportedEndpointType:
+		return Config
+
+	case OutOfOrderSequenceNumber,
+		DuplicateSequenceNumber,
+		InvalidTxnState,
+		InvalidProducerIDMapping,
+		UnknownProducerID,
+		TransactionalIDNotFound:
+		return FatalProducer
+
+	case InvalidProducerEpoch,
+		TransactionCoordinatorFenced,
+		FencedInstanceID,
+		ProducerFenced:
+		return Fenced
+
+	case OffsetOutOfRange:
+		return DataLoss
+	}
+
+	if e.Retriable {
+		return Transient
+	}
+	return Rejected
+}
+
+// Classify returns the category of the first Kafka error in err's chain, or
+// Unknown if err does not wrap a Kafka error.
+func Classify(err error) Category {
+	var kerr *Error
+	if errors.As(err, &kerr) {
+		return kerr.Category()
+	}
+	return Unknown
+}
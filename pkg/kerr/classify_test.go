@@ -0,0 +1,42 @@
+package kerr
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	for code, err := range code2err {
+		if err == nil {
+			continue
+		}
+		kerr := err.(*Error)
+		c := kerr.Category()
+		if c == Unknown && kerr != UnknownServerError {
+			t.Errorf("%s: unexpectedly unknown", kerr.Message)
+		}
+		if kerr.Retriable && c != Transient {
+			t.Errorf("%s (%d): retriable error classified as %s", kerr.Message, code, c)
+		}
+	}
+
+	for _, test := range []struct {
+		err    error
+		exp    Category
+		action Action
+	}{
+		{nil, Unknown, Alert},
+		{fmt.Errorf("wrapped: %w", NotLeaderForPartition), Transient, Retry},
+		{fmt.Errorf("wrapped: %w", SaslAuthenticationFailed), Auth, Reconfigure},
+		{MessageTooLarge, Config, Reconfigure},
+		{OutOfOrderSequenceNumber, FatalProducer, Crash},
+		{fmt.Errorf("wrapped: %w", ProducerFenced), Fenced, Crash},
+		{OffsetOutOfRange, DataLoss, Alert},
+		{TopicAlreadyExists, Rejected, Alert},
+		{fmt.Errorf("not a kafka error"), Unknown, Alert},
+	} {
+		if got := Classify(test.err); got != test.exp || got.Action() != test.action {
+			t.Errorf("%v: got %s/%s, expected %s/%s", test.err, got, got.Action(), test.exp, test.action)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package kfailover
+
+import (
+	"context"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type cfg struct {
+	opts [2][]kgo.Opt
+
+	interval       time.Duration
+	unhealthyAfter int
+	healthyAfter   int
+	maxErrRate     float64
+	minProduced    int
+	check          func(context.Context, *kgo.Client) error
+	failback       bool
+	onEvent        func(Event)
+	translate      OffsetTranslator
+}
+
+func defaultCfg() cfg {
+	return cfg{
+		interval:       5 * time.Second,
+		unhealthyAfter: 3,
+		healthyAfter:   12,
+		maxErrRate:     0.5,
+		minProduced:    10,
+		check:          func(ctx context.Context, cl *kgo.Client) error { return cl.Ping(ctx) },
+		failback:       true,
+		onEvent:        func(Event) {},
+		translate:      TranslateByTimestamp,
+	}
+}
+
+type (
+	// Opt is an option to configure a failover client.
+	Opt interface{ apply(*cfg) }
+	opt struct{ fn func(*cfg) }
+)
+
+func (o opt) apply(cfg *cfg) { o.fn(cfg) }
+
+// CheckInterval sets how often the health of clusters is checked, overriding
+// the default 5s. This is also the timeout for each health check and for
+// translating offsets when failing over.
+func CheckInterval(interval time.Duration) Opt {
+	return opt{func(cfg *cfg) { cfg.interval = interval }}
+}
+
+// UnhealthyAfter sets how many consecutive health checks of the active
+// cluster must fail before switching to the other cluster, overriding the
+// default 3.
+func UnhealthyAfter(checks int) Opt {
+	return opt{func(cfg *cfg) { cfg.unhealthyAfter = checks }}
+}
+
+// HealthyAfter sets how many consecutive health checks of the primary must
+// succeed while the secondary is active before failing back, overriding the
+// default 12.
+func HealthyAfter(checks int) Opt {
+	return opt{func(cfg *cfg) { cfg.healthyAfter = checks }}
+}
+
+// HealthCheck sets the function used to check the health of a cluster,
+// overriding the default of pinging any broker in the cluster with
+// kgo.Client.Ping. The function is passed a context that is canceled after
+// the check interval.
+//
+// For example, the client's State can be used to require that metadata is
+// loading successfully:
+//
+//	kfailover.HealthCheck(func(ctx context.Context, cl *kgo.Client) error {
+//		if s := cl.State(); !s.Healthy() {
+//			return fmt.Errorf("metadata is stale: %s", s.MetadataErr)
+//		}
+//		return nil
+//	})
+func HealthCheck(fn func(context.Context, *kgo.Client) error) Opt {
+	return opt{func(cfg *cfg) { cfg.check = fn }}
+}
+
+// MaxProduceErrorRate sets the rate of failing produced records that makes the
+// active cluster unhealthy, overriding the default of more than 0.5 (half of
+// all records). The rate is computed over each check interval, and is only
+// considered if at least minRecords were produced in the interval (default
+// 10). Use a rate of 1 or more to disable this check.
+func MaxProduceErrorRate(rate float64, minRecords int) Opt {
+	return opt{func(cfg *cfg) { cfg.maxErrRate, cfg.minProduced = rate, minRecords }}
+}
+
+// DisableFailback disables failing back to the primary once it is healthy
+// again. Once failed over, the client stays on the secondary unless the
+// secondary becomes unhealthy while the primary is healthy.
+func DisableFailback() Opt {
+	return opt{func(cfg *cfg) { cfg.failback = false }}
+}
+
+// OnEvent sets a function that is called for every failover, failback, and
+// skipped failover. The function is called in the health checking goroutine
+// and should not block for long.
+func OnEvent(fn func(Event)) Opt {
+	return opt{func(cfg *cfg) { cfg.onEvent = fn }}
+}
+
+// TranslateOffsets sets the function used to translate consumed positions to
+// offsets on the new cluster when failing over or back, overriding the default
+// TranslateByTimestamp.
+func TranslateOffsets(fn OffsetTranslator) Opt {
+	return opt{func(cfg *cfg) { cfg.translate = fn }}
+}
@@ -0,0 +1,479 @@
+// Package kfailover provides an active/passive client that fails over between
+// two Kafka clusters.
+//
+// The client produces to and consumes from a primary cluster. Health checks
+// run against the active cluster on an interval, and if the primary is
+// unhealthy for long enough (by default, it cannot be pinged or too many
+// produced records are failing) and the secondary is healthy, the client fails
+// over to the secondary. Once the primary is healthy again for long enough,
+// the client fails back. The secondary is checked the same while it is active,
+// and if it becomes unhealthy while the primary is healthy, the client fails
+// back immediately.
+//
+// When failing over while consuming, the position of the last record consumed
+// from each partition is translated to an offset on the new cluster (by
+// default, by record timestamp) and consuming resumes from there. Group
+// consumers commit the translated offsets to the group on the new cluster
+// before joining; direct consumers consume the translated partitions with
+// kgo.ConsumePartitions. Translation is at least once: the last consumed
+// record is generally consumed again.
+//
+// Because clusters are only configured with kgo options, failing over can be
+// tested with two kfake clusters:
+//
+//	c1, _ := kfake.NewCluster(kfake.SeedTopics(1, "foo"))
+//	c2, _ := kfake.NewCluster(kfake.SeedTopics(1, "foo"))
+//	cl, _ := kfailover.NewClient(
+//		[]kgo.Opt{kgo.SeedBrokers(c1.ListenAddrs()...)},
+//		[]kgo.Opt{kgo.SeedBrokers(c2.ListenAddrs()...)},
+//		kfailover.CheckInterval(100*time.Millisecond),
+//		kfailover.OnEvent(func(e kfailover.Event) { ... }),
+//	)
+//	c1.Close() // the client fails over to c2
+package kfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// Cluster is either the primary or secondary cluster.
+type Cluster int8
+
+const (
+	// Primary is the cluster the client prefers.
+	Primary Cluster = iota
+	// Secondary is the cluster the client fails over to.
+	Secondary
+)
+
+func (c Cluster) String() string {
+	if c == Primary {
+		return "primary"
+	}
+	return "secondary"
+}
+
+func (c Cluster) other() Cluster { return 1 - c }
+
+// EventType is the type of a failover event.
+type EventType int8
+
+const (
+	// EventFailover is when the client switches from the primary to the
+	// secondary because the primary is unhealthy.
+	EventFailover EventType = iota
+	// EventFailback is when the client switches from the secondary back
+	// to the primary because the primary is healthy again, or because the
+	// secondary is unhealthy while the primary is healthy.
+	EventFailback
+	// EventFailoverSkipped is when the active cluster is unhealthy but the
+	// client does not switch because the other cluster is also unhealthy.
+	EventFailoverSkipped
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventFailover:
+		return "failover"
+	case EventFailback:
+		return "failback"
+	default:
+		return "failover skipped"
+	}
+}
+
+// Event is a failover event, passed to the OnEvent function.
+type Event struct {
+	// Type is the type of event.
+	Type EventType
+	// From is the cluster that was active before this event.
+	From Cluster
+	// To is the cluster that is active after this event. This is the same
+	// as From for EventFailoverSkipped.
+	To Cluster
+	// Reason is the health check error of the active cluster that
+	// triggered a switch, or the other cluster's health check error if a
+	// switch was skipped. This is nil for failbacks because the primary is
+	// healthy again.
+	Reason error
+	// OffsetsErr is any error translating or committing consumed offsets
+	// to the new cluster. If non-nil, consuming from partitions on the new
+	// cluster resumes from the group's committed offsets or from the
+	// client's reset offset. Committing fails if the group on the new
+	// cluster is not empty, which includes when this client's own member
+	// from the last time it consumed from that cluster could not leave the
+	// group; that member is removed once its session times out.
+	OffsetsErr error
+}
+
+// member is a kgo client for one of the clusters.
+type member struct {
+	cluster Cluster
+	cl      *kgo.Client
+}
+
+// Client is an active/passive client across two clusters. Producing and
+// consuming go to the active cluster, which is initially the primary.
+type Client struct {
+	cfg cfg
+
+	ctx    context.Context
+	cancel func()
+	loopWg sync.WaitGroup
+	bgWg   sync.WaitGroup // closing replaced clients
+
+	switchMu sync.Mutex
+	active   atomic.Pointer[member]
+	passive  *member // only changed in the health loop, while holding switchMu
+
+	consuming bool
+	posMu     sync.Mutex
+	positions map[string]map[int32]Position // from the active cluster
+
+	promiseMu sync.Mutex // serializes promises across both clusters
+
+	produced, failed atomic.Int64 // for the active cluster, since the last check
+}
+
+// NewClient returns a new failover client. The primary and secondary options
+// are the kgo options for each cluster, and must include at least
+// kgo.SeedBrokers. Consuming options should be the same for both clusters.
+//
+// The client creates one kgo client per cluster: the active cluster's client
+// is created with all options, and the passive cluster's client is created
+// without consumer or group options and is only used for health checks and
+// for committing translated offsets when failing over. Whenever the client
+// fails over or back, both kgo clients are replaced.
+func NewClient(primary, secondary []kgo.Opt, opts ...Opt) (*Client, error) {
+	cfg := defaultCfg()
+	cfg.opts = [2][]kgo.Opt{primary, secondary}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	if cfg.interval <= 0 {
+		return nil, errors.New("invalid non-positive check interval")
+	}
+	if cfg.unhealthyAfter < 1 || cfg.healthyAfter < 1 {
+		return nil, errors.New("invalid unhealthy or healthy check count, must be at least 1")
+	}
+
+	active, err := kgo.NewClient(primary...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create primary client: %w", err)
+	}
+	passive, err := kgo.NewClient(passiveOpts(secondary)...)
+	if err != nil {
+		active.Close()
+		return nil, fmt.Errorf("unable to create secondary client: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cl := &Client{
+		cfg:       cfg,
+		ctx:       ctx,
+		cancel:    cancel,
+		passive:   &member{Secondary, passive},
+		consuming: len(passiveOpts(primary)) != len(primary),
+		positions: make(map[string]map[int32]Position),
+	}
+	cl.active.Store(&member{Primary, active})
+
+	cl.loopWg.Add(1)
+	go cl.loop()
+	return cl, nil
+}
+
+// passiveOpts returns opts without any consumer or group options.
+func passiveOpts(opts []kgo.Opt) []kgo.Opt {
+	var keep []kgo.Opt
+	for _, opt := range opts {
+		switch opt.(type) {
+		case kgo.ConsumerOpt, kgo.GroupOpt:
+		default:
+			keep = append(keep, opt)
+		}
+	}
+	return keep
+}
+
+// Active returns the cluster that is currently active.
+func (cl *Client) Active() Cluster { return cl.active.Load().cluster }
+
+// Client returns the kgo client for the currently active cluster. The
+// returned client is closed if the client fails over or back, so it should not
+// be held on to.
+func (cl *Client) Client() *kgo.Client { return cl.active.Load().cl }
+
+// Close stops health checking and closes the clients for both clusters.
+func (cl *Client) Close() {
+	cl.cancel()
+	cl.loopWg.Wait()
+
+	cl.switchMu.Lock()
+	defer cl.switchMu.Unlock()
+	cl.active.Load().cl.Close()
+	cl.passive.cl.Close()
+	cl.bgWg.Wait()
+}
+
+// Produce produces a record to the active cluster, calling promise once the
+// record is produced or fails.
+//
+// If the record fails after the client has switched clusters, the record is
+// produced once more to the new active cluster. This includes records that
+// were buffered in the old cluster's client when it was replaced: these fail
+// with kgo.ErrClientClosed and are reproduced. It is recommended to set
+// kgo.RecordDeliveryTimeout so that records do not retry on an unhealthy
+// cluster for long.
+//
+// As with kgo, promises are called serially.
+func (cl *Client) Produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error)) {
+	cl.produce(ctx, r, promise, true)
+}
+
+func (cl *Client) produce(ctx context.Context, r *kgo.Record, promise func(*kgo.Record, error), reproduce bool) {
+	m := cl.active.Load()
+	m.cl.Produce(ctx, r, func(r *kgo.Record, err error) {
+		if cl.active.Load() == m {
+			cl.produced.Add(1)
+			if err != nil {
+				cl.failed.Add(1)
+			}
+		} else if err != nil && reproduce && ctx.Err() == nil {
+			cl.produce(ctx, r, promise, false)
+			return
+		}
+		if promise != nil {
+			cl.promiseMu.Lock()
+			defer cl.promiseMu.Unlock()
+			promise(r, err)
+		}
+	})
+}
+
+// ProduceSync is a synchronous produce; see kgo.Client.ProduceSync.
+func (cl *Client) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	var (
+		wg      sync.WaitGroup
+		results = make(kgo.ProduceResults, 0, len(rs))
+		promise = func(r *kgo.Record, err error) {
+			results = append(results, kgo.ProduceResult{Record: r, Err: err})
+			wg.Done()
+		}
+	)
+	wg.Add(len(rs))
+	for _, r := range rs {
+		cl.Produce(ctx, r, promise)
+	}
+	wg.Wait()
+	return results
+}
+
+// Flush flushes the active cluster's client; see kgo.Client.Flush.
+func (cl *Client) Flush(ctx context.Context) error {
+	return cl.active.Load().cl.Flush(ctx)
+}
+
+// PollFetches polls the active cluster; see kgo.Client.PollFetches.
+//
+// If the client switches clusters while polling, this polls again from the
+// new active cluster rather than returning kgo.ErrClientClosed.
+func (cl *Client) PollFetches(ctx context.Context) kgo.Fetches {
+	return cl.PollRecords(ctx, 0)
+}
+
+// PollRecords polls the active cluster; see kgo.Client.PollRecords.
+//
+// If the client switches clusters while polling, this polls again from the
+// new active cluster rather than returning kgo.ErrClientClosed.
+func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) kgo.Fetches {
+	for {
+		m := cl.active.Load()
+		fs := m.cl.PollRecords(ctx, maxPollRecords)
+		if fs.IsClientClosed() && cl.active.Load() != m && cl.ctx.Err() == nil {
+			continue
+		}
+		cl.track(m, fs)
+		return fs
+	}
+}
+
+// track saves the position of the last record in every partition in fs.
+func (cl *Client) track(m *member, fs kgo.Fetches) {
+	cl.posMu.Lock()
+	defer cl.posMu.Unlock()
+	if cl.active.Load() != m {
+		return // the client switched while we were polling; these positions are for the old cluster
+	}
+	fs.EachPartition(func(p kgo.FetchTopicPartition) {
+		if len(p.Records) == 0 {
+			return
+		}
+		last := p.Records[len(p.Records)-1]
+		ps := cl.positions[p.Topic]
+		if ps == nil {
+			ps = make(map[int32]Position)
+			cl.positions[p.Topic] = ps
+		}
+		ps[p.Partition] = Position{Offset: last.Offset, Timestamp: last.Timestamp}
+	})
+}
+
+// loop runs health checks until the client is closed.
+func (cl *Client) loop() {
+	defer cl.loopWg.Done()
+
+	ticker := time.NewTicker(cl.cfg.interval)
+	defer ticker.Stop()
+
+	var unhealthy, healthy int
+	for {
+		select {
+		case <-cl.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// The active cluster is always checked: if it is unhealthy
+		// for long enough, we switch to the other cluster if that is
+		// healthy. This fails over from the primary, and fails back
+		// from an unhealthy secondary (even if failback is disabled).
+		active := cl.active.Load()
+		if err := cl.checkActive(active); err != nil {
+			healthy = 0
+			if unhealthy++; unhealthy < cl.cfg.unhealthyAfter {
+				continue
+			}
+			unhealthy = 0
+			if perr := cl.check(cl.passive.cl); perr != nil {
+				cl.cfg.onEvent(Event{Type: EventFailoverSkipped, From: active.cluster, To: active.cluster, Reason: perr})
+				continue
+			}
+			typ := EventFailover
+			if active.cluster == Secondary {
+				typ = EventFailback
+			}
+			cl.switchTo(active.cluster.other(), typ, err)
+			continue
+		}
+		unhealthy = 0
+
+		if active.cluster == Primary || !cl.cfg.failback {
+			continue
+		}
+		if err := cl.check(cl.passive.cl); err != nil {
+			healthy = 0
+			continue
+		}
+		if healthy++; healthy >= cl.cfg.healthyAfter {
+			healthy = 0
+			cl.switchTo(Primary, EventFailback, nil)
+		}
+	}
+}
+
+// check runs the health check against a cluster's client.
+func (cl *Client) check(kcl *kgo.Client) error {
+	ctx, cancel := context.WithTimeout(cl.ctx, cl.cfg.interval)
+	defer cancel()
+	return cl.cfg.check(ctx, kcl)
+}
+
+// checkActive runs the health check against the active cluster and checks
+// the produce error rate since the last check.
+func (cl *Client) checkActive(m *member) error {
+	if err := cl.check(m.cl); err != nil {
+		return err
+	}
+	produced, failed := cl.produced.Swap(0), cl.failed.Swap(0)
+	if produced < int64(cl.cfg.minProduced) {
+		return nil
+	}
+	if rate := float64(failed) / float64(produced); rate > cl.cfg.maxErrRate {
+		return fmt.Errorf("produce error rate %.2f (%d of %d records) exceeds %.2f", rate, failed, produced, cl.cfg.maxErrRate)
+	}
+	return nil
+}
+
+// switchTo switches the active cluster, translating consumed offsets if
+// consuming, and replacing both kgo clients.
+func (cl *Client) switchTo(to Cluster, typ EventType, reason error) {
+	cl.switchMu.Lock()
+	defer cl.switchMu.Unlock()
+
+	var (
+		from   = to.other()
+		old    = cl.active.Load()
+		dst    = cl.passive
+		extra  []kgo.Opt
+		offErr error
+	)
+
+	cl.posMu.Lock()
+	positions := cl.positions
+	cl.positions = make(map[string]map[int32]Position)
+	cl.posMu.Unlock()
+
+	if cl.consuming && len(positions) > 0 {
+		ctx, cancel := context.WithTimeout(cl.ctx, cl.cfg.interval)
+		var offsets map[string]map[int32]int64
+		offsets, offErr = cl.cfg.translate(ctx, dst.cl, positions)
+		if offErr == nil && len(offsets) > 0 {
+			if group, _ := old.cl.OptValue(kgo.ConsumerGroup).(string); group != "" {
+				offErr = commitOffsets(ctx, dst.cl, group, offsets)
+			} else {
+				extra = append(extra, kgo.ConsumePartitions(toOffsets(offsets)))
+			}
+		}
+		cancel()
+	}
+
+	active, err := kgo.NewClient(append(append([]kgo.Opt(nil), cl.cfg.opts[to]...), extra...)...)
+	if err != nil {
+		// The options were valid when creating the passive client, so
+		// this is unexpected; we stay on the current cluster.
+		cl.cfg.onEvent(Event{Type: EventFailoverSkipped, From: from, To: from, Reason: fmt.Errorf("unable to create %s client: %w", to, err)})
+		return
+	}
+	passive, err := kgo.NewClient(passiveOpts(cl.cfg.opts[from])...)
+	if err != nil {
+		active.Close()
+		cl.cfg.onEvent(Event{Type: EventFailoverSkipped, From: from, To: from, Reason: fmt.Errorf("unable to create %s client: %w", from, err)})
+		return
+	}
+
+	cl.active.Store(&member{to, active})
+	cl.passive = &member{from, passive}
+	cl.produced.Store(0)
+	cl.failed.Store(0)
+
+	// The old cluster may be unhealthy, in which case closing (leaving a
+	// group, failing buffered records) may take a while.
+	cl.bgWg.Add(1)
+	go func() {
+		defer cl.bgWg.Done()
+		dst.cl.Close()
+		old.cl.Close()
+	}()
+
+	cl.cfg.onEvent(Event{Type: typ, From: from, To: to, Reason: reason, OffsetsErr: offErr})
+}
+
+func toOffsets(offsets map[string]map[int32]int64) map[string]map[int32]kgo.Offset {
+	m := make(map[string]map[int32]kgo.Offset, len(offsets))
+	for topic, ps := range offsets {
+		mps := make(map[int32]kgo.Offset, len(ps))
+		for p, o := range ps {
+			mps[p] = kgo.NewOffset().At(o)
+		}
+		m[topic] = mps
+	}
+	return m
+}
@@ -0,0 +1,139 @@
+package kfailover
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestFailoverFailback(t *testing.T) {
+	var primaryDown atomic.Bool
+	primaryDown.Store(true)
+
+	events := make(chan Event, 10)
+	cl, err := NewClient(
+		[]kgo.Opt{kgo.SeedBrokers("127.0.0.1:1"), kgo.ConsumeTopics("foo")},
+		[]kgo.Opt{kgo.SeedBrokers("127.0.0.1:2"), kgo.ConsumeTopics("foo")},
+		CheckInterval(10*time.Millisecond),
+		UnhealthyAfter(2),
+		HealthyAfter(2),
+		HealthCheck(func(_ context.Context, cl *kgo.Client) error {
+			if seeds := cl.OptValue(kgo.SeedBrokers).([]string); seeds[0] == "127.0.0.1:1" && primaryDown.Load() {
+				return errors.New("primary down")
+			}
+			return nil
+		}),
+		OnEvent(func(e Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	if !cl.consuming {
+		t.Error("expected client to detect consuming options")
+	}
+	cl.switchMu.Lock()
+	if topics := cl.passive.cl.GetConsumeTopics(); len(topics) != 0 {
+		t.Errorf("passive client is consuming %v, expected nothing", topics)
+	}
+	cl.switchMu.Unlock()
+
+	wait := func(exp EventType, to Cluster) {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Type != exp || e.To != to {
+				t.Fatalf("got event %s to %s, exp %s to %s", e.Type, e.To, exp, to)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", exp)
+		}
+	}
+
+	wait(EventFailover, Secondary)
+	if cl.Active() != Secondary {
+		t.Errorf("got active %s after failover, exp secondary", cl.Active())
+	}
+	if topics := cl.Client().GetConsumeTopics(); len(topics) != 1 {
+		t.Errorf("active client is consuming %v, exp [foo]", topics)
+	}
+
+	primaryDown.Store(false)
+	wait(EventFailback, Primary)
+	if cl.Active() != Primary {
+		t.Errorf("got active %s after failback, exp primary", cl.Active())
+	}
+}
+
+func TestIdenticalOffsets(t *testing.T) {
+	got, err := IdenticalOffsets(context.Background(), nil, map[string]map[int32]Position{
+		"foo": {0: {Offset: 9}, 3: {Offset: 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["foo"][0] != 10 || got["foo"][3] != 1 || len(got["foo"]) != 2 {
+		t.Errorf("got unexpected offsets %v", got)
+	}
+	if offsets := toOffsets(got); offsets["foo"][0] != kgo.NewOffset().At(10) {
+		t.Errorf("got unexpected kgo offsets %v", offsets)
+	}
+}
+
+func TestFailbackFromUnhealthySecondary(t *testing.T) {
+	var primaryDown, secondaryDown atomic.Bool
+	primaryDown.Store(true)
+
+	events := make(chan Event, 10)
+	cl, err := NewClient(
+		[]kgo.Opt{kgo.SeedBrokers("127.0.0.1:1")},
+		[]kgo.Opt{kgo.SeedBrokers("127.0.0.1:2")},
+		CheckInterval(10*time.Millisecond),
+		UnhealthyAfter(2),
+		DisableFailback(),
+		HealthCheck(func(_ context.Context, cl *kgo.Client) error {
+			down := &secondaryDown
+			if seeds := cl.OptValue(kgo.SeedBrokers).([]string); seeds[0] == "127.0.0.1:1" {
+				down = &primaryDown
+			}
+			if down.Load() {
+				return errors.New("down")
+			}
+			return nil
+		}),
+		OnEvent(func(e Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			return e
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for an event")
+			return Event{}
+		}
+	}
+
+	if e := next(); e.Type != EventFailover {
+		t.Fatalf("got event %s, exp failover", e.Type)
+	}
+
+	// With failback disabled, a healthy primary does not fail back, but
+	// an unhealthy secondary does once the primary is healthy.
+	primaryDown.Store(false)
+	secondaryDown.Store(true)
+	e := next()
+	if e.Type != EventFailback || e.From != Secondary || e.To != Primary || e.Reason == nil {
+		t.Fatalf("got event %+v, exp failback from the unhealthy secondary", e)
+	}
+}
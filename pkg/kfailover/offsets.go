@@ -0,0 +1,154 @@
+package kfailover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// Position is the position of the last record consumed from a partition on
+// the cluster being switched away from.
+type Position struct {
+	// Offset is the offset of the last consumed record.
+	Offset int64
+	// Timestamp is the timestamp of the last consumed record.
+	Timestamp time.Time
+}
+
+// OffsetTranslator translates consumed positions on one cluster to offsets to
+// resume consuming from on the destination cluster. The returned offsets are
+// the offsets of the next records to consume. Partitions that are not
+// returned resume from the group's committed offset or from the client's reset
+// offset.
+type OffsetTranslator func(ctx context.Context, dst *kgo.Client, consumed map[string]map[int32]Position) (map[string]map[int32]int64, error)
+
+// IdenticalOffsets is an OffsetTranslator for clusters that replicate with
+// identical offsets: consuming resumes on the new cluster one after the last
+// consumed offset.
+func IdenticalOffsets(_ context.Context, _ *kgo.Client, consumed map[string]map[int32]Position) (map[string]map[int32]int64, error) {
+	offsets := make(map[string]map[int32]int64, len(consumed))
+	for topic, ps := range consumed {
+		tps := make(map[int32]int64, len(ps))
+		for p, pos := range ps {
+			tps[p] = pos.Offset + 1
+		}
+		offsets[topic] = tps
+	}
+	return offsets, nil
+}
+
+// TranslateByTimestamp is the default OffsetTranslator, which works with any
+// replication that preserves record timestamps (such as MirrorMaker 2).
+//
+// For every partition, this lists the first offset on the destination cluster
+// with a timestamp at or after the last consumed record's timestamp. If no
+// such offset exists, the partition resumes at the end. Because multiple
+// records can have the same timestamp, this is at least once: the last
+// consumed record and any records with the same timestamp are consumed again.
+func TranslateByTimestamp(ctx context.Context, dst *kgo.Client, consumed map[string]map[int32]Position) (map[string]map[int32]int64, error) {
+	offsets, err := listOffsets(ctx, dst, consumed, func(pos Position) int64 { return pos.Timestamp.UnixMilli() })
+	if err != nil {
+		return nil, err
+	}
+
+	// Any partition with no record at or after the timestamp has an
+	// offset of -1; these resume at the end.
+	atEnd := make(map[string]map[int32]Position)
+	for topic, ps := range offsets {
+		for p, o := range ps {
+			if o >= 0 {
+				continue
+			}
+			if atEnd[topic] == nil {
+				atEnd[topic] = make(map[int32]Position)
+			}
+			atEnd[topic][p] = Position{}
+		}
+	}
+	if len(atEnd) == 0 {
+		return offsets, nil
+	}
+	ends, err := listOffsets(ctx, dst, atEnd, func(Position) int64 { return -1 })
+	if err != nil {
+		return nil, err
+	}
+	for topic, ps := range ends {
+		for p, o := range ps {
+			offsets[topic][p] = o
+		}
+	}
+	return offsets, nil
+}
+
+func listOffsets(ctx context.Context, cl *kgo.Client, ps map[string]map[int32]Position, ts func(Position) int64) (map[string]map[int32]int64, error) {
+	req := kmsg.NewPtrListOffsetsRequest()
+	req.ReplicaID = -1
+	for topic, partitions := range ps {
+		rt := kmsg.NewListOffsetsRequestTopic()
+		rt.Topic = topic
+		for p, pos := range partitions {
+			rp := kmsg.NewListOffsetsRequestTopicPartition()
+			rp.Partition = p
+			rp.Timestamp = ts(pos)
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, rt)
+	}
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	offsets := make(map[string]map[int32]int64)
+	for _, t := range resp.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				errs = append(errs, fmt.Errorf("unable to list offsets for %s[%d]: %w", t.Topic, p.Partition, err))
+				continue
+			}
+			if offsets[t.Topic] == nil {
+				offsets[t.Topic] = make(map[int32]int64)
+			}
+			offsets[t.Topic][p.Partition] = p.Offset
+		}
+	}
+	return offsets, errors.Join(errs...)
+}
+
+// commitOffsets commits offsets to a group outside of the group's generation,
+// which only succeeds if the group is empty.
+func commitOffsets(ctx context.Context, cl *kgo.Client, group string, offsets map[string]map[int32]int64) error {
+	req := kmsg.NewPtrOffsetCommitRequest()
+	req.Group = group
+	req.Generation = -1
+	for topic, ps := range offsets {
+		rt := kmsg.NewOffsetCommitRequestTopic()
+		rt.Topic = topic
+		for p, o := range ps {
+			rp := kmsg.NewOffsetCommitRequestTopicPartition()
+			rp.Partition = p
+			rp.Offset = o
+			rt.Partitions = append(rt.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, rt)
+	}
+	resp, err := req.RequestWith(ctx, cl)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, t := range resp.Topics {
+		for _, p := range t.Partitions {
+			if err := kerr.ErrorForCode(p.ErrorCode); err != nil {
+				errs = append(errs, fmt.Errorf("unable to commit offset for %s[%d] to group %q: %w", t.Topic, p.Partition, group, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,186 @@
+package kfake
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// ReplayEntry is a recorded request and its response, as read from a traffic
+// capture written by kgo.RecordTraffic (see kgo.ReadTraffic). The fields
+// mirror kgo.TrafficEntry.
+type ReplayEntry struct {
+	// Request is the recorded request; only its key is used.
+	Request kmsg.Request
+	// Response is the recorded response, and is nil if the request
+	// failed or if it was a produce request with no acks.
+	Response kmsg.Response
+	// Err is the recorded error writing the request or reading the
+	// response, if any.
+	Err string
+}
+
+type replayed struct {
+	resp kmsg.Response
+	err  string
+}
+
+// Replay replays recorded traffic: every request the cluster receives is
+// answered with the next recorded response for the same request key, in the
+// order the responses were recorded. This allows reproducing an issue from a
+// capture offline by pointing a client at the cluster and running the same
+// workload. The capture format is owned by kgo; entries are read with
+// kgo.ReadTraffic and converted to ReplayEntry:
+//
+//	r := kgo.ReadTraffic(f)
+//	var entries []kfake.ReplayEntry
+//	for {
+//		e, err := r.Next()
+//		if err == io.EOF {
+//			break
+//		}
+//		...
+//		entries = append(entries, kfake.ReplayEntry{
+//			Request:  e.Request,
+//			Response: e.Response,
+//			Err:      e.Err,
+//		})
+//	}
+//	err := c.Replay(entries...)
+//
+// Replaying ignores the contents of requests. A recorded response is
+// converted to the version of the request it is answering, which drops any
+// fields the version does not support. Requests that failed when recorded
+// (a write or read error) close the client connection when replayed. Once
+// all recorded responses for a key are used, requests for that key are
+// handled by the cluster as normal. ApiVersions and SASL requests are always
+// handled by the cluster.
+//
+// Brokers in recorded Metadata, FindCoordinator, and DescribeCluster
+// responses are rewritten to the addresses of this cluster's brokers (each
+// distinct recorded broker maps to one of this cluster's brokers, in order of
+// node ID), so that clients stay connected to this cluster. Node IDs are not
+// rewritten.
+//
+// This returns an error, and replays nothing, if any entry has no request or
+// has a response for a different request key.
+func (c *Cluster) Replay(entries ...ReplayEntry) error {
+	queues := make(map[int16][]replayed)
+	nodes := make(map[int32]struct{})
+	for i, e := range entries {
+		if e.Request == nil {
+			return fmt.Errorf("invalid replay entry %d: missing request", i)
+		}
+		key := e.Request.Key()
+		if e.Response != nil && e.Response.Key() != key {
+			return fmt.Errorf("invalid replay entry %d: %s response for a %s request", i, kmsg.NameForKey(e.Response.Key()), kmsg.NameForKey(key))
+		}
+		switch key {
+		case 17, 18, 36: // SASLHandshake, ApiVersions, SASLAuthenticate
+			continue
+		}
+		for _, id := range respNodes(e.Response) {
+			nodes[id] = struct{}{}
+		}
+		queues[key] = append(queues[key], replayed{e.Response, e.Err})
+	}
+
+	// We map recorded brokers to our brokers in order of node ID. We
+	// must look up our listen addresses outside of a control function.
+	addrs := c.ListenAddrs()
+	ids := make([]int32, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	hostports := make(map[int32]hostport, len(ids))
+	for i, id := range ids {
+		host, port, _ := net.SplitHostPort(addrs[i%len(addrs)])
+		p, _ := strconv.Atoi(port)
+		hostports[id] = hostport{host, int32(p)}
+	}
+
+	for key, q := range queues {
+		q := q
+		c.ControlKey(key, func(req kmsg.Request) (kmsg.Response, error, bool) {
+			if len(q) == 0 {
+				return nil, nil, false
+			}
+			c.KeepControl()
+			rr := q[0]
+			q = q[1:]
+			if rr.resp == nil {
+				if rr.err != "" {
+					return nil, fmt.Errorf("replaying recorded error: %s", rr.err), true
+				}
+				return nil, nil, true // produce with no acks
+			}
+			rr.resp.SetVersion(req.GetVersion())
+			rewriteNodes(rr.resp, hostports)
+			return rr.resp, nil, true
+		})
+	}
+	return nil
+}
+
+type hostport struct {
+	host string
+	port int32
+}
+
+// respNodes returns the node IDs of brokers with addresses in a response.
+func respNodes(resp kmsg.Response) []int32 {
+	var ids []int32
+	switch resp := resp.(type) {
+	case *kmsg.MetadataResponse:
+		for _, b := range resp.Brokers {
+			ids = append(ids, b.NodeID)
+		}
+	case *kmsg.FindCoordinatorResponse:
+		if resp.Host != "" {
+			ids = append(ids, resp.NodeID)
+		}
+		for _, co := range resp.Coordinators {
+			if co.Host != "" {
+				ids = append(ids, co.NodeID)
+			}
+		}
+	case *kmsg.DescribeClusterResponse:
+		for _, b := range resp.Brokers {
+			ids = append(ids, b.NodeID)
+		}
+	}
+	return ids
+}
+
+// rewriteNodes rewrites broker addresses in a response to our addresses.
+func rewriteNodes(resp kmsg.Response, hostports map[int32]hostport) {
+	switch resp := resp.(type) {
+	case *kmsg.MetadataResponse:
+		for i := range resp.Brokers {
+			b := &resp.Brokers[i]
+			hp := hostports[b.NodeID]
+			b.Host, b.Port = hp.host, hp.port
+		}
+	case *kmsg.FindCoordinatorResponse:
+		if resp.Host != "" {
+			hp := hostports[resp.NodeID]
+			resp.Host, resp.Port = hp.host, hp.port
+		}
+		for i := range resp.Coordinators {
+			if co := &resp.Coordinators[i]; co.Host != "" {
+				hp := hostports[co.NodeID]
+				co.Host, co.Port = hp.host, hp.port
+			}
+		}
+	case *kmsg.DescribeClusterResponse:
+		for i := range resp.Brokers {
+			b := &resp.Brokers[i]
+			hp := hostports[b.NodeID]
+			b.Host, b.Port = hp.host, hp.port
+		}
+	}
+}
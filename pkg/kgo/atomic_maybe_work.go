@@ -74,3 +74,10 @@ type lazyI32 int32
 
 func (v *lazyI32) store(s int32) { atomic.StoreInt32((*int32)(v), s) }
 func (v *lazyI32) load() int32   { return atomic.LoadInt32((*int32)(v)) }
+
+// lazyI64 is the same as lazyI32, but for int64 (and time.Duration) settings
+// that can be changed with Reconfigure.
+type lazyI64 int64
+
+func (v *lazyI64) store(s int64) { atomic.StoreInt64((*int64)(v), s) }
+func (v *lazyI64) load() int64   { return atomic.LoadInt64((*int64)(v)) }
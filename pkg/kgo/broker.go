@@ -81,6 +81,15 @@ type promisedResp struct {
 	writeWait    time.Duration
 	timeToWrite  time.Duration
 	readEnqueue  time.Time
+
+	// sample is whether the request does not wait within the broker,
+	// meaning its latency is tracked for adaptive timeouts.
+	sample bool
+
+	// The following are used for RecordTraffic; reqBody is nil if we
+	// are not recording.
+	enqueue time.Time
+	reqBody []byte
 }
 
 // NodeName returns the name of a node, given the kgo internal node ID.
@@ -157,18 +166,43 @@ type broker struct {
 	// Produce requests go to cxnProduce, fetch to cxnFetch, join/sync go
 	// to cxnGroup, anything with TimeoutMillis goes to cxnSlow, and
 	// everything else goes to cxnNormal.
+	//
+	// There is one produce and fetch connection per sink and source for
+	// this broker (see ProduceConnectionsPerBroker and
+	// FetchConnectionsPerBroker).
 	cxnNormal  *brokerCxn
-	cxnProduce *brokerCxn
-	cxnFetch   *brokerCxn
+	cxnProduce []*brokerCxn
+	cxnFetch   []*brokerCxn
 	cxnGroup   *brokerCxn
 	cxnSlow    *brokerCxn
 
 	reapMu sync.Mutex // held when modifying a brokerCxn
 
+	// If the client resolves hosts itself (DNSResolver, or SRV seeds), we
+	// cache what this broker resolved to for the DNS refresh interval.
+	resolveMu  sync.Mutex
+	resolved   []resolvedAddr
+	resolvedAt time.Time
+
+	// errMu guards the last error opening or initializing a connection,
+	// for State.
+	errMu     sync.Mutex
+	lastErr   error
+	lastErrAt time.Time
+
 	// reqs manages incoming message requests.
 	reqs ringReq
+	// If there is more than one produce or fetch connection, each produce
+	// and fetch connection has its own queue of requests (produce first,
+	// then fetch), so that writes to each connection are not serialized
+	// behind each other.
+	laneReqs []ringReq
 	// dead is an atomic so a backed up reqs cannot block broker stoppage.
 	dead atomicBool
+
+	// health tracks request failures and latencies for
+	// BrokerCircuitBreaker and AdaptiveRequestTimeoutOverhead.
+	health brokerHealth
 }
 
 // brokerVersions is loaded once (and potentially a few times concurrently if
@@ -212,7 +246,7 @@ func unknownSeedID(seedNum int) int32 {
 }
 
 func (cl *Client) newBroker(nodeID int32, host string, port int32, rack *string) *broker {
-	return &broker{
+	b := &broker{
 		cl: cl,
 
 		addr: net.JoinHostPort(host, strconv.Itoa(int(port))),
@@ -222,7 +256,48 @@ func (cl *Client) newBroker(nodeID int32, host string, port int32, rack *string)
 			Port:   port,
 			Rack:   rack,
 		},
+
+		cxnProduce: make([]*brokerCxn, cl.cfg.produceConns),
+		cxnFetch:   make([]*brokerCxn, cl.cfg.fetchConns),
+	}
+	if cl.cfg.produceConns > 1 || cl.cfg.fetchConns > 1 {
+		b.laneReqs = make([]ringReq, cl.cfg.produceConns+cl.cfg.fetchConns)
 	}
+	return b
+}
+
+// cxnIdx returns which of the broker's produce or fetch connections a request
+// uses. Produce and fetch requests issued directly with Client.Request use
+// the first connection.
+func cxnIdx(req kmsg.Request) int {
+	switch r := req.(type) {
+	case *produceRequest:
+		return r.cxnIdx
+	case *fetchRequest:
+		return r.cxnIdx
+	}
+	return 0
+}
+
+// reqsFor returns the queue a request is pushed to.
+func (b *broker) reqsFor(req kmsg.Request) *ringReq {
+	if b.laneReqs != nil {
+		switch req.Key() {
+		case 0:
+			return &b.laneReqs[cxnIdx(req)]
+		case 1:
+			return &b.laneReqs[len(b.cxnProduce)+cxnIdx(req)]
+		}
+	}
+	return &b.reqs
+}
+
+// allCxns returns all of the broker's connections, some of which may be nil.
+func (b *broker) allCxns() []*brokerCxn {
+	cxns := make([]*brokerCxn, 0, 3+len(b.cxnProduce)+len(b.cxnFetch))
+	cxns = append(cxns, b.cxnNormal, b.cxnGroup, b.cxnSlow)
+	cxns = append(cxns, b.cxnProduce...)
+	return append(cxns, b.cxnFetch...)
 }
 
 // stopForever permanently disables this broker.
@@ -232,15 +307,16 @@ func (b *broker) stopForever() {
 	}
 
 	b.reqs.die() // no more pushing
+	for i := range b.laneReqs {
+		b.laneReqs[i].die()
+	}
 
 	b.reapMu.Lock()
 	defer b.reapMu.Unlock()
 
-	b.cxnNormal.die()
-	b.cxnProduce.die()
-	b.cxnFetch.die()
-	b.cxnGroup.die()
-	b.cxnSlow.die()
+	for _, cxn := range b.allCxns() {
+		cxn.die()
+	}
 }
 
 // do issues a request to the broker, eventually calling the response
@@ -254,10 +330,11 @@ func (b *broker) do(
 ) {
 	pr := promisedReq{ctx, req, promise, time.Now()}
 
-	first, dead := b.reqs.push(pr)
+	reqs := b.reqsFor(req)
+	first, dead := reqs.push(pr)
 
 	if first {
-		go b.handleReqs(pr)
+		go b.handleReqs(reqs, pr)
 	} else if dead {
 		promise(nil, errChosenBrokerDead)
 	}
@@ -277,7 +354,7 @@ func (b *broker) waitResp(ctx context.Context, req kmsg.Request) (kmsg.Response,
 	return resp, err
 }
 
-func (b *broker) handleReqs(pr promisedReq) {
+func (b *broker) handleReqs(reqs *ringReq, pr promisedReq) {
 	var more, dead bool
 start:
 	if dead {
@@ -286,7 +363,7 @@ start:
 		b.handleReq(pr)
 	}
 
-	pr, more, dead = b.reqs.dropPeek()
+	pr, more, dead = reqs.dropPeek()
 	if more {
 		goto start
 	}
@@ -294,6 +371,10 @@ start:
 
 func (b *broker) handleReq(pr promisedReq) {
 	req := pr.req
+	if err := b.allow(); err != nil {
+		pr.promise(nil, err)
+		return
+	}
 	var cxn *brokerCxn
 	var retriedOnNewConnection bool
 start:
@@ -438,9 +519,16 @@ start:
 		noResp.Version = req.GetVersion()
 	}
 
-	corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, writeErr := cxn.writeRequest(pr.ctx, pr.enqueue, req)
+	wireReq, err := b.cl.nsReq(req)
+	if err != nil {
+		pr.promise(nil, err)
+		return
+	}
+	corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, reqBody, writeErr := cxn.writeRequest(pr.ctx, pr.enqueue, wireReq)
 
 	if writeErr != nil {
+		b.observe(writeErr, 0, false)
+		b.cl.recordTraffic(b.meta.NodeID, req.Key(), req.GetVersion(), pr.enqueue, reqBody, nil, writeErr)
 		pr.promise(nil, writeErr)
 		cxn.die()
 		cxn.hookWriteE2E(req.Key(), bytesWritten, writeWait, timeToWrite, writeErr)
@@ -448,12 +536,15 @@ start:
 	}
 
 	if isNoResp {
+		b.cl.recordTraffic(b.meta.NodeID, req.Key(), req.GetVersion(), pr.enqueue, reqBody, nil, nil)
 		pr.promise(noResp, nil)
 		cxn.hookWriteE2E(req.Key(), bytesWritten, writeWait, timeToWrite, writeErr)
 		return
 	}
 
-	rt, _ := cxn.cl.connTimeouter.timeouts(req)
+	rt, _ := cxn.cl.connTimeouter.timeoutsWith(b.timeoutOverhead(), req)
+	_, isTimeout := req.(kmsg.TimeoutRequest)
+	key := req.Key()
 
 	cxn.waitResp(promisedResp{
 		pr.ctx,
@@ -466,6 +557,9 @@ start:
 		writeWait,
 		timeToWrite,
 		readEnqueue,
+		!isTimeout && key != 0 && key != 1 && key != 11 && key != 14, // not produce, fetch, join, sync
+		pr.enqueue,
+		reqBody,
 	})
 }
 
@@ -505,10 +599,10 @@ func (b *broker) loadConnection(ctx context.Context, req kmsg.Request) (*brokerC
 	)
 	switch {
 	case reqKey == 0:
-		pcxn = &b.cxnProduce
+		pcxn = &b.cxnProduce[cxnIdx(req)]
 		isProduceCxn = true
 	case reqKey == 1:
-		pcxn = &b.cxnFetch
+		pcxn = &b.cxnFetch[cxnIdx(req)]
 	case reqKey == 11 || reqKey == 14: // join || sync
 		pcxn = &b.cxnGroup
 	case isTimeout:
@@ -521,6 +615,8 @@ func (b *broker) loadConnection(ctx context.Context, req kmsg.Request) (*brokerC
 
 	conn, err := b.connect(ctx)
 	if err != nil {
+		b.setLastErr(err)
+		b.observe(err, 0, false)
 		return nil, err
 	}
 
@@ -535,6 +631,8 @@ func (b *broker) loadConnection(ctx context.Context, req kmsg.Request) (*brokerC
 	if err = cxn.init(isProduceCxn); err != nil {
 		b.cl.cfg.logger.Log(LogLevelDebug, "connection initialization failed", "addr", b.addr, "broker", logID(b.meta.NodeID), "err", err)
 		cxn.closeConn()
+		b.setLastErr(err)
+		b.observe(err, 0, false)
 		return nil, err
 	}
 	b.cl.cfg.logger.Log(LogLevelDebug, "connection initialized successfully", "addr", b.addr, "broker", logID(b.meta.NodeID))
@@ -545,6 +643,12 @@ func (b *broker) loadConnection(ctx context.Context, req kmsg.Request) (*brokerC
 	return cxn, nil
 }
 
+func (b *broker) setLastErr(err error) {
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	b.lastErr, b.lastErrAt = err, time.Now()
+}
+
 func (cl *Client) reapConnectionsLoop() {
 	idleTimeout := cl.cfg.connIdleTimeout
 	if idleTimeout < 0 { // impossible due to cfg.validate, but just in case
@@ -588,13 +692,7 @@ func (b *broker) reapConnections(idleTimeout time.Duration) (total int) {
 	b.reapMu.Lock()
 	defer b.reapMu.Unlock()
 
-	for _, cxn := range []*brokerCxn{
-		b.cxnNormal,
-		b.cxnProduce,
-		b.cxnFetch,
-		b.cxnGroup,
-		b.cxnSlow,
-	} {
+	for _, cxn := range b.allCxns() {
 		if cxn == nil || cxn.dead.Load() {
 			continue
 		}
@@ -624,7 +722,7 @@ func (b *broker) reapConnections(idleTimeout time.Duration) (total int) {
 func (b *broker) connect(ctx context.Context) (net.Conn, error) {
 	b.cl.cfg.logger.Log(LogLevelDebug, "opening connection to broker", "addr", b.addr, "broker", logID(b.meta.NodeID))
 	start := time.Now()
-	conn, err := b.cl.cfg.dialFn(ctx, "tcp", b.addr)
+	conn, err := b.dial(ctx)
 	since := time.Since(start)
 	b.cl.cfg.hooks.each(func(h Hook) {
 		if h, ok := h.(HookBrokerConnect); ok {
@@ -645,6 +743,112 @@ func (b *broker) connect(ctx context.Context) (net.Conn, error) {
 	return conn, nil
 }
 
+// dialHostKey is a context key for the host an address was resolved from, so
+// that our default TLS dialer can use the host as the ServerName when we dial
+// a resolved IP.
+type dialHostKey struct{}
+
+// resolvedAddr is an ip:port to dial and the host it was resolved from.
+type resolvedAddr struct {
+	host string
+	addr string
+}
+
+// isSRVName returns whether a host is of the form _service._proto.name.
+func isSRVName(host string) bool {
+	labels := strings.SplitN(host, ".", 3)
+	return len(labels) == 3 && strings.HasPrefix(labels[0], "_") && strings.HasPrefix(labels[1], "_")
+}
+
+// dial opens a connection to the broker. If the client does not resolve hosts
+// itself, this simply dials our addr. Otherwise, we try every resolved
+// address in order (KIP-302), and forget what we resolved if all fail so that
+// the next dial resolves again.
+func (b *broker) dial(ctx context.Context) (net.Conn, error) {
+	srv := isSRVName(b.meta.Host)
+	if b.cl.cfg.resolver == nil && !srv {
+		return b.cl.cfg.dialFn(ctx, "tcp", b.addr)
+	}
+
+	addrs, err := b.resolve(ctx, srv)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	for i, addr := range addrs {
+		conn, err = b.cl.cfg.dialFn(context.WithValue(ctx, dialHostKey{}, addr.host), "tcp", addr.addr)
+		if err == nil {
+			return conn, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		if i < len(addrs)-1 {
+			b.cl.cfg.logger.Log(LogLevelDebug, "unable to dial resolved broker address, trying the next address", "addr", b.addr, "resolved_addr", addr.addr, "broker", logID(b.meta.NodeID), "err", err)
+		}
+	}
+
+	b.resolveMu.Lock()
+	b.resolved = nil
+	b.resolveMu.Unlock()
+	return nil, err
+}
+
+// resolve returns the addresses to dial for this broker, looking up SRV
+// records if necessary and caching the results for the DNS refresh interval.
+func (b *broker) resolve(ctx context.Context, srv bool) ([]resolvedAddr, error) {
+	b.resolveMu.Lock()
+	defer b.resolveMu.Unlock()
+
+	if len(b.resolved) > 0 && time.Since(b.resolvedAt) < b.cl.cfg.dnsRefreshInterval {
+		return b.resolved, nil
+	}
+
+	r := b.cl.cfg.resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	targets := []hostport{{b.meta.Host, b.meta.Port}}
+	if srv {
+		_, records, err := r.LookupSRV(ctx, "", "", b.meta.Host)
+		if err != nil {
+			return nil, fmt.Errorf("unable to look up SRV records for %s: %w", b.meta.Host, err)
+		}
+		targets = targets[:0]
+		for _, record := range records {
+			targets = append(targets, hostport{strings.TrimSuffix(record.Target, "."), int32(record.Port)})
+		}
+	}
+
+	var (
+		addrs    []resolvedAddr
+		firstErr error
+	)
+	for _, target := range targets {
+		ips, err := r.LookupHost(ctx, target.host)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, ip := range ips {
+			addrs = append(addrs, resolvedAddr{target.host, net.JoinHostPort(ip, strconv.Itoa(int(target.port)))})
+		}
+	}
+	if len(addrs) == 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no addresses found")
+		}
+		return nil, fmt.Errorf("unable to resolve %s: %w", b.addr, firstErr)
+	}
+
+	b.cl.cfg.logger.Log(LogLevelDebug, "resolved broker addresses", "addr", b.addr, "broker", logID(b.meta.NodeID), "resolved", len(addrs))
+	b.resolved, b.resolvedAt = addrs, time.Now()
+	return addrs, nil
+}
+
 // brokerCxn manages an actual connection to a Kafka broker. This is separate
 // the broker struct to allow lazy connection (re)creation.
 type brokerCxn struct {
@@ -730,7 +934,7 @@ start:
 	req.ClientSoftwareName = cxn.cl.cfg.softwareName
 	req.ClientSoftwareVersion = cxn.cl.cfg.softwareVersion
 	cxn.cl.cfg.logger.Log(LogLevelDebug, "issuing api versions request", "broker", logID(cxn.b.meta.NodeID), "version", maxVersion)
-	corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, writeErr := cxn.writeRequest(nil, time.Now(), req)
+	corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, _, writeErr := cxn.writeRequest(nil, time.Now(), req)
 	if writeErr != nil {
 		cxn.hookWriteE2E(req.Key(), bytesWritten, writeWait, timeToWrite, writeErr)
 		return writeErr
@@ -788,10 +992,22 @@ start:
 }
 
 func (cxn *brokerCxn) sasl() error {
-	if len(cxn.cl.cfg.sasls) == 0 {
+	dyn := cxn.cl.loadDyn()
+	for !dyn.saslUsers.acquire() {
+		// Retired by a concurrent Reconfigure, which stores new
+		// mechanisms first, or by Close, which does not.
+		next := cxn.cl.loadDyn()
+		if next == dyn {
+			return ErrClientClosed
+		}
+		dyn = next
+	}
+	defer dyn.saslUsers.release()
+	sasls := dyn.sasls
+	if len(sasls) == 0 {
 		return nil
 	}
-	mechanism := cxn.cl.cfg.sasls[0]
+	mechanism := sasls[0]
 	retried := false
 	authenticate := false
 
@@ -803,7 +1019,7 @@ start:
 		req.Mechanism = mechanism.Name()
 		req.Version = v.versions[req.Key()]
 		cxn.cl.cfg.logger.Log(LogLevelDebug, "issuing SASLHandshakeRequest", "broker", logID(cxn.b.meta.NodeID))
-		corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, writeErr := cxn.writeRequest(nil, time.Now(), req)
+		corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, _, writeErr := cxn.writeRequest(nil, time.Now(), req)
 		if writeErr != nil {
 			cxn.hookWriteE2E(req.Key(), bytesWritten, writeWait, timeToWrite, writeErr)
 			return writeErr
@@ -822,7 +1038,7 @@ start:
 		err = kerr.ErrorForCode(resp.ErrorCode)
 		if err != nil {
 			if !retried && err == kerr.UnsupportedSaslMechanism {
-				for _, ours := range cxn.cl.cfg.sasls[1:] {
+				for _, ours := range sasls[1:] {
 					for _, supported := range resp.SupportedMechanisms {
 						if supported == ours.Name() {
 							mechanism = ours
@@ -844,7 +1060,7 @@ start:
 func (cxn *brokerCxn) doSasl(authenticate bool) error {
 	session, clientWrite, err := cxn.mechanism.Authenticate(cxn.cl.ctx, cxn.addr)
 	if err != nil {
-		return err
+		return &errSASLMechanism{cxn.mechanism.Name(), err}
 	}
 	if len(clientWrite) == 0 {
 		return fmt.Errorf("unexpected server-write sasl with mechanism %s", cxn.mechanism.Name())
@@ -894,7 +1110,7 @@ func (cxn *brokerCxn) doSasl(authenticate bool) error {
 			// Lifetime: we take the timestamp before we write our
 			// request; see usage below for why.
 			prereq = time.Now()
-			corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, writeErr := cxn.writeRequest(nil, time.Now(), req)
+			corrID, bytesWritten, writeWait, timeToWrite, readEnqueue, _, writeErr := cxn.writeRequest(nil, time.Now(), req)
 
 			// As mentioned above, we could have one final write
 			// without reading a response back (kerberos). If this
@@ -930,7 +1146,7 @@ func (cxn *brokerCxn) doSasl(authenticate bool) error {
 
 		if !done {
 			if done, clientWrite, err = session.Challenge(challenge); err != nil {
-				return err
+				return &errSASLMechanism{cxn.mechanism.Name(), err}
 			}
 		}
 	}
@@ -995,7 +1211,7 @@ func maybeUpdateCtxErr(clientCtx, reqCtx context.Context, err *error) {
 
 // writeRequest writes a message request to the broker connection, bumping the
 // connection's correlation ID as appropriate for the next write.
-func (cxn *brokerCxn) writeRequest(ctx context.Context, enqueuedForWritingAt time.Time, req kmsg.Request) (corrID int32, bytesWritten int, writeWait, timeToWrite time.Duration, readEnqueue time.Time, writeErr error) {
+func (cxn *brokerCxn) writeRequest(ctx context.Context, enqueuedForWritingAt time.Time, req kmsg.Request) (corrID int32, bytesWritten int, writeWait, timeToWrite time.Duration, readEnqueue time.Time, reqBody []byte, writeErr error) {
 	// A nil ctx means we cannot be throttled.
 	if ctx != nil {
 		throttleUntil := time.Unix(0, cxn.throttleUntil.Load())
@@ -1025,6 +1241,14 @@ func (cxn *brokerCxn) writeRequest(ctx context.Context, enqueuedForWritingAt tim
 		cxn.corrID,
 	)
 
+	// If recording traffic, we copy the request body before our buffer
+	// is reused. Note the body may be empty but must be non-nil.
+	if cxn.cl.traffic != nil {
+		body := buf[cxn.cl.requestHeaderLen(req):]
+		reqBody = make([]byte, len(body))
+		copy(reqBody, body)
+	}
+
 	_, wt := cxn.cl.connTimeouter.timeouts(req)
 	bytesWritten, writeWait, timeToWrite, readEnqueue, writeErr = cxn.writeConn(ctx, buf, wt, enqueuedForWritingAt)
 
@@ -1462,7 +1686,7 @@ func (cxn *brokerCxn) handleResp(pr promisedResp) {
 	)
 	if err != nil {
 		if !errors.Is(err, ErrClientClosed) && !errors.Is(err, context.Canceled) {
-			if cxn.successes > 0 || len(cxn.b.cl.cfg.sasls) > 0 {
+			if cxn.successes > 0 || len(cxn.b.cl.loadDyn().sasls) > 0 {
 				cxn.b.cl.cfg.logger.Log(LogLevelDebug, "read from broker errored, killing connection", "req", kmsg.Key(pr.resp.Key()).Name(), "addr", cxn.b.addr, "broker", logID(cxn.b.meta.NodeID), "successful_reads", cxn.successes, "err", err)
 			} else {
 				cxn.b.cl.cfg.logger.Log(LogLevelWarn, "read from broker errored, killing connection after 0 successful responses (is SASL missing?)", "req", kmsg.Key(pr.resp.Key()).Name(), "addr", cxn.b.addr, "broker", logID(cxn.b.meta.NodeID), "err", err)
@@ -1471,13 +1695,20 @@ func (cxn *brokerCxn) handleResp(pr promisedResp) {
 				}
 			}
 		}
+		cxn.b.observe(err, 0, false)
+		cxn.cl.recordTraffic(cxn.b.meta.NodeID, pr.resp.Key(), pr.resp.GetVersion(), pr.enqueue, pr.reqBody, nil, err)
 		pr.promise(nil, err)
 		cxn.die()
 		return
 	}
 
+	cxn.b.observe(nil, pr.timeToWrite+time.Since(pr.readEnqueue), pr.sample)
+	cxn.cl.recordTraffic(cxn.b.meta.NodeID, pr.resp.Key(), pr.resp.GetVersion(), pr.enqueue, pr.reqBody, rawResp, nil)
 	cxn.successes++
 	readErr := pr.resp.ReadFrom(rawResp)
+	if readErr == nil {
+		cxn.cl.nsResp(pr.resp)
+	}
 
 	// If we had no error, we read the response successfully.
 	//
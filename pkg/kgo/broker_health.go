@@ -0,0 +1,136 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The number of recent latencies tracked per broker for adaptive timeouts,
+// and the number we need before adapting.
+const (
+	healthLatencies    = 128
+	healthMinLatencies = 20
+)
+
+// brokerHealth tracks consecutive request failures to a broker for
+// BrokerCircuitBreaker, and recent latencies for
+// AdaptiveRequestTimeoutOverhead.
+type brokerHealth struct {
+	mu sync.Mutex
+
+	failures int
+	// If openUntil is non-zero, the breaker is open. Once we are past
+	// openUntil, one request is allowed through and openUntil is pushed
+	// out another cooldown.
+	openUntil time.Time
+	degraded  bool
+
+	latencies [healthLatencies]time.Duration
+	nlat      int           // total latencies ever observed
+	overhead  time.Duration // cached adaptive overhead, recomputed as latencies are observed
+}
+
+// allow returns errBrokerDegraded if the broker's circuit breaker is open and
+// this request is not the one request allowed through per cooldown.
+func (b *broker) allow() error {
+	if b.cl.cfg.breakerFailures == 0 {
+		return nil
+	}
+	h := &b.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.degraded {
+		return nil
+	}
+	now := time.Now()
+	if now.Before(h.openUntil) {
+		return errBrokerDegraded
+	}
+	h.openUntil = now.Add(b.cl.cfg.breakerCooldown)
+	return nil
+}
+
+// isDegraded returns whether the broker's circuit breaker is open.
+func (b *broker) isDegraded() bool {
+	if b.cl.cfg.breakerFailures == 0 {
+		return false
+	}
+	b.health.mu.Lock()
+	defer b.health.mu.Unlock()
+	return b.health.degraded
+}
+
+// observe tracks the result of a request to the broker. If sample is true,
+// the request does not wait within the broker and latency is tracked for
+// adaptive timeouts.
+func (b *broker) observe(err error, latency time.Duration, sample bool) {
+	if err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, ErrClientClosed) || errors.Is(err, errBrokerDegraded)) {
+		return
+	}
+	cfg := &b.cl.cfg
+	h := &b.health
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err != nil {
+		h.failures++
+		if cfg.breakerFailures == 0 || h.failures < cfg.breakerFailures {
+			return
+		}
+		h.openUntil = time.Now().Add(cfg.breakerCooldown)
+		if !h.degraded {
+			h.degraded = true
+			cfg.logger.Log(LogLevelWarn, "broker circuit breaker opened, failing requests to the broker fast until it recovers", "broker", logID(b.meta.NodeID), "consecutive_failures", h.failures, "cooldown", cfg.breakerCooldown, "err", err)
+		}
+		return
+	}
+
+	h.failures = 0
+	if h.degraded {
+		h.degraded = false
+		h.openUntil = time.Time{}
+		cfg.logger.Log(LogLevelInfo, "broker circuit breaker closed, broker recovered", "broker", logID(b.meta.NodeID))
+	}
+
+	if !sample || cfg.adaptiveMult == 0 {
+		return
+	}
+	h.latencies[h.nlat%healthLatencies] = latency
+	h.nlat++
+	if h.nlat >= healthMinLatencies && (h.nlat < healthLatencies || h.nlat%(healthLatencies/8) == 0) {
+		h.overhead = adaptOverhead(h.latencies[:min(h.nlat, healthLatencies)], cfg.adaptiveMult, cfg.adaptiveMin, cfg.requestTimeoutOverhead)
+	}
+}
+
+// adaptOverhead returns the p99 of latencies times mult, bounded by lo and hi.
+func adaptOverhead(latencies []time.Duration, mult float64, lo, hi time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	p99 := sorted[(len(sorted)*99)/100]
+	overhead := time.Duration(float64(p99) * mult)
+	if overhead < lo {
+		overhead = lo
+	}
+	if overhead > hi {
+		overhead = hi
+	}
+	return overhead
+}
+
+// timeoutOverhead returns the overhead to use when deadlining requests to
+// this broker.
+func (b *broker) timeoutOverhead() time.Duration {
+	def := b.cl.cfg.requestTimeoutOverhead
+	if b.cl.cfg.adaptiveMult == 0 {
+		return def
+	}
+	b.health.mu.Lock()
+	defer b.health.mu.Unlock()
+	if b.health.overhead == 0 {
+		return def
+	}
+	return b.health.overhead
+}
@@ -0,0 +1,89 @@
+package kgo
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBrokerCircuitBreaker(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), BrokerCircuitBreaker(2, 100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	b := cl.newBroker(1, "127.0.0.1", 1, nil)
+	fail := errors.New("read timeout")
+
+	b.observe(fail, 0, false)
+	if b.isDegraded() {
+		t.Fatal("degraded after one failure, exp two")
+	}
+	b.observe(fail, 0, false)
+	if !b.isDegraded() {
+		t.Fatal("not degraded after two failures")
+	}
+	if err := b.allow(); !errors.Is(err, errBrokerDegraded) || !isRetryableBrokerErr(err) {
+		t.Errorf("got allow err %v, exp retryable errBrokerDegraded", err)
+	}
+
+	// Preferring non-degraded brokers for any-broker requests.
+	healthy := cl.newBroker(2, "127.0.0.1", 2, nil)
+	cl.brokersMu.Lock()
+	cl.brokers = []*broker{b, healthy}
+	cl.anyBrokerOrd = []int32{0, 1}
+	cl.brokersMu.Unlock()
+	if got := cl.broker(); got != healthy {
+		t.Errorf("any broker chose degraded broker %d", got.meta.NodeID)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if err := b.allow(); err != nil {
+		t.Errorf("probe after cooldown was not allowed: %v", err)
+	}
+	if err := b.allow(); err == nil {
+		t.Error("second request after cooldown was allowed, exp one probe")
+	}
+	b.observe(nil, time.Millisecond, true)
+	if b.isDegraded() || b.allow() != nil {
+		t.Error("still degraded after a success")
+	}
+}
+
+func TestAdaptiveRequestTimeoutOverhead(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), AdaptiveRequestTimeoutOverhead(4, 200*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	b := cl.newBroker(1, "127.0.0.1", 1, nil)
+	for i := 0; i < healthMinLatencies-1; i++ {
+		b.observe(nil, 100*time.Millisecond, true)
+	}
+	if got := b.timeoutOverhead(); got != 10*time.Second {
+		t.Errorf("got overhead %v before enough samples, exp the 10s default", got)
+	}
+	b.observe(nil, 100*time.Millisecond, false) // unsampled: no change
+	b.observe(nil, 100*time.Millisecond, true)
+	if got := b.timeoutOverhead(); got != 400*time.Millisecond {
+		t.Errorf("got overhead %v, exp 400ms", got)
+	}
+
+	for _, test := range []struct {
+		lat    time.Duration
+		expect time.Duration
+	}{
+		{time.Millisecond, 200 * time.Millisecond}, // bounded below
+		{time.Minute, 10 * time.Second},            // bounded above
+	} {
+		if got := adaptOverhead([]time.Duration{test.lat}, 4, 200*time.Millisecond, 10*time.Second); got != test.expect {
+			t.Errorf("latency %v: got %v, exp %v", test.lat, got, test.expect)
+		}
+	}
+
+	if _, err := NewClient(AdaptiveRequestTimeoutOverhead(0.5, time.Second)); err == nil {
+		t.Error("expected error for multiplier below 1")
+	}
+}
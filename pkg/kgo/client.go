@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"hash/fnv"
 	"math/rand"
 	"net"
 	"reflect"
@@ -26,7 +27,6 @@ import (
 
 	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kmsg"
-	"github.com/twmb/franz-go/pkg/sasl"
 )
 
 var crc32c = crc32.MakeTable(crc32.Castagnoli) // record crc's use Castagnoli table; for consuming/producing
@@ -58,6 +58,8 @@ type Client struct {
 	sinksAndSources   map[int32]sinkAndSource
 
 	reqFormatter  *kmsg.RequestFormatter
+	traffic       *trafficRecorder // non-nil if RecordTraffic
+	metaWatch     metaWatch
 	connTimeouter connTimeouter
 
 	bufPool bufPool // for to brokers to share underlying reusable request buffers
@@ -78,8 +80,14 @@ type Client struct {
 	producer producer
 	consumer consumer
 
-	compressor   *compressor
 	decompressor *decompressor
+	recordPool   *recordPool // non-nil if consuming with PooledRecords
+
+	// dyn contains the non-scalar options that can be changed with
+	// Reconfigure; reconfigureMu serializes Reconfigure and every other
+	// write to cfg after NewClient.
+	dyn           atomic.Value // *dynCfg
+	reconfigureMu sync.Mutex
 
 	coordinatorsMu sync.Mutex
 	coordinators   map[coordinatorKey]*coordinatorLoad
@@ -90,23 +98,80 @@ type Client struct {
 	metawait             metawait
 	metadone             chan struct{}
 
+	// metaStateMu guards when metadata was last successfully updated and
+	// the error from the most recent update, for State.
+	metaStateMu sync.Mutex
+	metaAt      time.Time
+	metaErr     error
+
 	mappedMetaMu sync.Mutex
 	mappedMeta   map[string]mappedMetadataTopic
 }
 
 func (cl *Client) idempotent() bool { return !cl.cfg.disableIdempotency }
 
+// sinkAndSource contains the sinks and sources for a broker. There is one
+// sink and one source per produce and fetch connection to the broker (see
+// ProduceConnectionsPerBroker and FetchConnectionsPerBroker), and partitions
+// are spread across them by hash.
 type sinkAndSource struct {
-	sink   *sink
-	source *source
+	sinks   []*sink
+	sources []*source
 }
 
-func (cl *Client) allSinksAndSources(fn func(sns sinkAndSource)) {
+func (cl *Client) newSinkAndSource(nodeID int32) sinkAndSource {
+	sns := sinkAndSource{
+		sinks:   make([]*sink, cl.cfg.produceConns),
+		sources: make([]*source, cl.cfg.fetchConns),
+	}
+	for i := range sns.sinks {
+		sns.sinks[i] = cl.newSink(nodeID, i)
+	}
+	for i := range sns.sources {
+		sns.sources[i] = cl.newSource(nodeID, i)
+	}
+	return sns
+}
+
+// partitionIdx returns the index of the sink or source that a partition uses,
+// spreading the partitions of a topic evenly and starting each topic at a
+// different index.
+func partitionIdx(topic string, partition int32, n int) int {
+	if n == 1 {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(topic))
+	return int((h.Sum32() + uint32(partition)) % uint32(n))
+}
+
+func (sns sinkAndSource) sink(topic string, partition int32) *sink {
+	return sns.sinks[partitionIdx(topic, partition, len(sns.sinks))]
+}
+
+func (sns sinkAndSource) source(topic string, partition int32) *source {
+	return sns.sources[partitionIdx(topic, partition, len(sns.sources))]
+}
+
+func (cl *Client) allSinks(fn func(*sink)) {
 	cl.sinksAndSourcesMu.Lock()
 	defer cl.sinksAndSourcesMu.Unlock()
 
 	for _, sns := range cl.sinksAndSources {
-		fn(sns)
+		for _, sink := range sns.sinks {
+			fn(sink)
+		}
+	}
+}
+
+func (cl *Client) allSources(fn func(*source)) {
+	cl.sinksAndSourcesMu.Lock()
+	defer cl.sinksAndSourcesMu.Unlock()
+
+	for _, sns := range cl.sinksAndSources {
+		for _, source := range sns.sources {
+			fn(source)
+		}
 	}
 }
 
@@ -243,14 +308,26 @@ func (cl *Client) OptValues(opt any) []any {
 		return []any{nil}
 	case namefn(RequestTimeoutOverhead):
 		return []any{cfg.requestTimeoutOverhead}
+	case namefn(BrokerCircuitBreaker):
+		return []any{cfg.breakerFailures, cfg.breakerCooldown}
+	case namefn(AdaptiveRequestTimeoutOverhead):
+		return []any{cfg.adaptiveMult, cfg.adaptiveMin}
 	case namefn(ConnIdleTimeout):
 		return []any{cfg.connIdleTimeout}
+	case namefn(RecordTraffic):
+		return []any{cfg.traffic}
+	case namefn(Namespace):
+		return []any{cfg.namespace}
 	case namefn(Dialer):
 		return []any{cfg.dialFn}
 	case namefn(DialTLSConfig):
-		return []any{cfg.dialTLS}
+		return []any{cl.loadDyn().dialTLS}
 	case namefn(DialTLS):
 		return []any{cfg.dialTLS != nil}
+	case namefn(DNSResolver):
+		return []any{cfg.resolver}
+	case namefn(DNSRefreshInterval):
+		return []any{cfg.dnsRefreshInterval}
 	case namefn(SeedBrokers):
 		return []any{cfg.seedBrokers}
 	case namefn(MaxVersions):
@@ -258,13 +335,13 @@ func (cl *Client) OptValues(opt any) []any {
 	case namefn(MinVersions):
 		return []any{cfg.minVersions}
 	case namefn(RetryBackoffFn):
-		return []any{cfg.retryBackoff}
+		return []any{cl.loadDyn().retryBackoff}
 	case namefn(RequestRetries):
-		return []any{cfg.retries}
+		return []any{cfg.retries.load()}
 	case namefn(RetryTimeout):
-		return []any{cfg.retryTimeout(0)}
+		return []any{cl.loadDyn().retryTimeout(0)}
 	case namefn(RetryTimeoutFn):
-		return []any{cfg.retryTimeout}
+		return []any{cl.loadDyn().retryTimeout}
 	case namefn(AllowAutoTopicCreation):
 		return []any{cfg.allowAutoTopicCreation}
 	case namefn(BrokerMaxWriteBytes):
@@ -275,8 +352,10 @@ func (cl *Client) OptValues(opt any) []any {
 		return []any{cfg.metadataMaxAge}
 	case namefn(MetadataMinAge):
 		return []any{cfg.metadataMinAge}
+	case namefn(RebootstrapAfter):
+		return []any{cfg.rebootstrapAfter}
 	case namefn(SASL):
-		return []any{cfg.sasls}
+		return []any{cl.loadDyn().sasls}
 	case namefn(WithHooks):
 		return []any{cfg.hooks}
 	case namefn(ConcurrentTransactionsBackoff):
@@ -286,26 +365,37 @@ func (cl *Client) OptValues(opt any) []any {
 
 	case namefn(DefaultProduceTopic):
 		return []any{cfg.defaultProduceTopic}
+	case namefn(CreateTopicOnProduce), namefn(CreateTopicsOnProduceRegex):
+		regex := name == namefn(CreateTopicsOnProduceRegex)
+		creations := make(map[string]TopicCreation)
+		for _, tc := range cfg.topicCreations {
+			if tc.regex == regex {
+				creations[tc.topic] = tc.creation
+			}
+		}
+		return []any{creations}
 	case namefn(RequiredAcks):
 		return []any{cfg.acks}
 	case namefn(DisableIdempotentWrite):
 		return []any{cfg.disableIdempotency}
 	case namefn(MaxProduceRequestsInflightPerBroker):
 		return []any{cfg.maxProduceInflight}
+	case namefn(ProduceConnectionsPerBroker):
+		return []any{cfg.produceConns}
 	case namefn(ProducerBatchCompression):
-		return []any{cfg.compression}
+		return []any{cl.loadDyn().compression}
 	case namefn(ProducerBatchMaxBytes):
 		return []any{cfg.maxRecordBatchBytes}
 	case namefn(MaxBufferedRecords):
-		return []any{cfg.maxBufferedRecords}
+		return []any{cfg.maxBufferedRecords.load()}
 	case namefn(MaxBufferedBytes):
-		return []any{cfg.maxBufferedBytes}
+		return []any{cfg.maxBufferedBytes.load()}
 	case namefn(RecordPartitioner):
 		return []any{cfg.partitioner}
 	case namefn(ProduceRequestTimeout):
 		return []any{cfg.produceTimeout}
 	case namefn(RecordRetries):
-		return []any{cfg.recordRetries}
+		return []any{cfg.recordRetries.load()}
 	case namefn(UnknownTopicRetries):
 		return []any{cfg.maxUnknownFailures}
 	case namefn(StopProducerOnDataLossDetected):
@@ -313,7 +403,7 @@ func (cl *Client) OptValues(opt any) []any {
 	case namefn(ProducerOnDataLossDetected):
 		return []any{cfg.onDataLoss}
 	case namefn(ProducerLinger):
-		return []any{cfg.linger}
+		return []any{time.Duration(cfg.linger.load())}
 	case namefn(ManualFlushing):
 		return []any{cfg.manualFlushing}
 	case namefn(RecordDeliveryTimeout):
@@ -330,28 +420,56 @@ func (cl *Client) OptValues(opt any) []any {
 		return []any{cfg.partitions}
 	case namefn(ConsumePreferringLagFn):
 		return []any{cfg.preferLagFn}
+	case namefn(ConsumeTopicPriorities):
+		return []any{cfg.topicPriorities}
+	case namefn(ConsumeTopicWeights):
+		return []any{cfg.topicWeights}
+	case namefn(FairPartitionPolling):
+		return []any{cfg.fairPartitions}
+	case namefn(ConsumeReplay):
+		return []any{cfg.replayFrom, cfg.replaySpeed}
 	case namefn(ConsumeRegex):
 		return []any{cfg.regex}
+	case namefn(ConsumeRegexExclude):
+		return []any{cfg.regexExcludes}
+	case namefn(ConsumeRegexFilter):
+		return []any{cfg.regexFilter}
+	case namefn(OnTopicsDiscovered):
+		return []any{cfg.onTopicsDiscovered}
+	case namefn(OnTopicsRemoved):
+		return []any{cfg.onTopicsRemoved}
 	case namefn(ConsumeResetOffset):
 		return []any{cfg.resetOffset}
 	case namefn(ConsumeTopics):
 		return []any{cfg.topics}
 	case namefn(DisableFetchSessions):
 		return []any{cfg.disableFetchSessions}
+	case namefn(FetchConnectionsPerBroker):
+		return []any{cfg.fetchConns}
 	case namefn(FetchIsolationLevel):
 		return []any{cfg.isolationLevel}
 	case namefn(FetchMaxBytes):
-		return []any{int32(cfg.maxBytes)}
+		return []any{cfg.maxBytes.load()}
 	case namefn(FetchMaxPartitionBytes):
-		return []any{int32(cfg.maxPartBytes)}
+		return []any{cfg.maxPartBytes.load()}
 	case namefn(FetchMaxWait):
 		return []any{time.Duration(cfg.maxWait) * time.Millisecond}
 	case namefn(FetchMinBytes):
 		return []any{cfg.minBytes}
 	case namefn(KeepControlRecords):
 		return []any{cfg.keepControl}
+	case namefn(LazyRecordDecoding):
+		return []any{cfg.lazyDecode}
+	case namefn(PooledRecords):
+		return []any{cfg.pooledRecords}
+	case namefn(ConsumeFilter):
+		return []any{cfg.consumeFilter}
+	case namefn(ConsumeDedup):
+		return []any{cfg.dedupIdentity, cfg.dedupWindow}
 	case namefn(MaxConcurrentFetches):
 		return []any{cfg.maxConcurrentFetches}
+	case namefn(FetchDecodeParallelism):
+		return []any{cfg.decodeParallelism}
 	case namefn(Rack):
 		return []any{cfg.rack}
 	case namefn(KeepRetryableFetchErrors):
@@ -394,6 +512,8 @@ func (cl *Client) OptValues(opt any) []any {
 		return []any{cfg.onRevoked}
 	case namefn(RebalanceTimeout):
 		return []any{cfg.rebalanceTimeout}
+	case namefn(CheckpointDedupInCommits):
+		return []any{cfg.dedupCheckpoint}
 	case namefn(RequireStableFetchOffsets):
 		return []any{cfg.requireStable}
 	case namefn(SessionTimeout):
@@ -433,16 +553,23 @@ func NewClient(opts ...Opt) (*Client, error) {
 		}
 	}
 
+	var cl *Client // for the TLS dialer to load the latest TLS config
+
 	if cfg.dialFn == nil {
 		dialer := &net.Dialer{Timeout: cfg.dialTimeout}
 		cfg.dialFn = dialer.DialContext
+		cfg.dialFnOwned = true
 		if cfg.dialTLS != nil {
 			cfg.dialFn = func(ctx context.Context, network, host string) (net.Conn, error) {
-				c := cfg.dialTLS.Clone()
+				c := cl.loadDyn().dialTLS.Clone()
 				if c.ServerName == "" {
-					server, _, err := net.SplitHostPort(host)
-					if err != nil {
-						return nil, fmt.Errorf("unable to split host:port for dialing: %w", err)
+					server, resolved := ctx.Value(dialHostKey{}).(string)
+					if !resolved {
+						var err error
+						server, _, err = net.SplitHostPort(host)
+						if err != nil {
+							return nil, fmt.Errorf("unable to split host:port for dialing: %w", err)
+						}
 					}
 					c.ServerName = server
 				}
@@ -456,7 +583,7 @@ func NewClient(opts ...Opt) (*Client, error) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	cl := &Client{
+	cl = &Client{
 		cfg:       cfg,
 		opts:      opts,
 		ctx:       ctx,
@@ -482,7 +609,6 @@ func NewClient(opts ...Opt) (*Client, error) {
 		bufPool: newBufPool(),
 		prsPool: newPrsPool(),
 
-		compressor:   compressor,
 		decompressor: newDecompressor(),
 
 		coordinators: make(map[coordinatorKey]*coordinatorLoad),
@@ -492,6 +618,18 @@ func NewClient(opts ...Opt) (*Client, error) {
 		blockingMetadataFnCh: make(chan func()),
 		metadone:             make(chan struct{}),
 	}
+	if cfg.pooledRecords {
+		cl.recordPool = newRecordPool()
+	}
+	cl.dyn.Store(&dynCfg{
+		retryBackoff: cfg.retryBackoff,
+		retryTimeout: cfg.retryTimeout,
+		sasls:        cfg.sasls,
+		saslUsers:    new(saslUsers),
+		dialTLS:      cfg.dialTLS,
+		compression:  cfg.compression,
+		compressor:   compressor,
+	})
 
 	// Before we start any goroutines below, we must notify any interested
 	// hooks of our existence.
@@ -508,6 +646,9 @@ func NewClient(opts ...Opt) (*Client, error) {
 	if cfg.id != nil {
 		cl.reqFormatter = kmsg.NewRequestFormatter(kmsg.FormatterClientID(*cfg.id))
 	}
+	if cfg.traffic != nil {
+		cl.traffic = &trafficRecorder{w: cfg.traffic}
+	}
 
 	seedBrokers := make([]*broker, 0, len(seeds))
 	for i, seed := range seeds {
@@ -593,7 +734,8 @@ func (cl *Client) PurgeTopicsFromClient(topics ...string) {
 	if len(topics) == 0 {
 		return
 	}
-	sort.Strings(topics)           // for logging in the functions
+	sort.Strings(topics) // for logging in the functions
+	var removed []string
 	cl.blockingMetadataFn(func() { // make reasoning about concurrency easier
 		var wg sync.WaitGroup
 		wg.Add(2)
@@ -603,7 +745,7 @@ func (cl *Client) PurgeTopicsFromClient(topics ...string) {
 		}()
 		go func() {
 			defer wg.Done()
-			cl.consumer.purgeTopics(topics)
+			removed = cl.consumer.purgeTopics(topics)
 		}()
 		wg.Wait()
 	})
@@ -612,6 +754,7 @@ func (cl *Client) PurgeTopicsFromClient(topics ...string) {
 		delete(cl.mappedMeta, t)
 	}
 	cl.mappedMetaMu.Unlock()
+	cl.consumer.onTopicsChanged(cl.cfg.onTopicsRemoved, removed)
 }
 
 // PurgeTopicsFromProducing internally removes all internal information for
@@ -637,9 +780,11 @@ func (cl *Client) PurgeTopicsFromConsuming(topics ...string) {
 		return
 	}
 	sort.Strings(topics)
+	var removed []string
 	cl.blockingMetadataFn(func() {
-		cl.consumer.purgeTopics(topics)
+		removed = cl.consumer.purgeTopics(topics)
 	})
+	cl.consumer.onTopicsChanged(cl.cfg.onTopicsRemoved, removed)
 }
 
 // Parse broker IP/host and port from a string, using the default Kafka port if
@@ -694,7 +839,12 @@ type connTimeouter struct {
 }
 
 func (c *connTimeouter) timeouts(req kmsg.Request) (r, w time.Duration) {
-	def := c.def
+	return c.timeoutsWith(c.def, req)
+}
+
+// timeoutsWith returns timeouts using the given overhead rather than the
+// configured RequestTimeoutOverhead; see AdaptiveRequestTimeoutOverhead.
+func (c *connTimeouter) timeoutsWith(def time.Duration, req kmsg.Request) (r, w time.Duration) {
 	millis := func(m int32) time.Duration { return time.Duration(m) * time.Millisecond }
 	switch t := req.(type) {
 	default:
@@ -757,6 +907,14 @@ func (cl *Client) broker() *broker {
 	var b *broker
 
 	if len(cl.anyBrokerOrd) > 0 {
+		// We prefer brokers whose circuit breaker is not open. If all
+		// remaining brokers are degraded, we use the next regardless.
+		for i, idx := range cl.anyBrokerOrd {
+			if !cl.brokers[idx].isDegraded() {
+				cl.anyBrokerOrd[0], cl.anyBrokerOrd[i] = cl.anyBrokerOrd[i], cl.anyBrokerOrd[0]
+				break
+			}
+		}
 		b = cl.brokers[cl.anyBrokerOrd[0]]
 		cl.anyBrokerOrd = cl.anyBrokerOrd[1:]
 		return b
@@ -1017,13 +1175,12 @@ func (cl *Client) close(ctx context.Context) (rerr error) {
 
 	sessCloseCtx, sessCloseCancel := context.WithTimeout(ctx, time.Second)
 	var wg sync.WaitGroup
-	cl.allSinksAndSources(func(sns sinkAndSource) {
-		if sns.source.session.id != 0 {
-			sns := sns
+	cl.allSources(func(s *source) {
+		if s.session.id != 0 {
 			wg.Add(1)
 			go func() {
 				defer wg.Done()
-				sns.source.killSessionOnClose(sessCloseCtx)
+				s.killSessionOnClose(sessCloseCtx)
 			}()
 		}
 	})
@@ -1050,8 +1207,12 @@ func (cl *Client) close(ctx context.Context) (rerr error) {
 	<-cl.metadone
 
 	for _, sns := range cl.sinksAndSources {
-		sns.sink.maybeDrain()     // awaken anything in backoff
-		sns.source.maybeConsume() // same
+		for _, sink := range sns.sinks {
+			sink.maybeDrain() // awaken anything in backoff
+		}
+		for _, source := range sns.sources {
+			source.maybeConsume() // same
+		}
 	}
 
 	cl.failBufferedRecords(ErrClientClosed)
@@ -1062,11 +1223,10 @@ func (cl *Client) close(ctx context.Context) (rerr error) {
 	// fetch. PollFetches with `nil` is instant.
 	cl.PollFetches(nil)
 
-	for _, s := range cl.cfg.sasls {
-		if closing, ok := s.(sasl.ClosingMechanism); ok {
-			closing.Close()
-		}
-	}
+	cl.reconfigureMu.Lock()
+	dyn := cl.loadDyn()
+	dyn.saslUsers.retire(dyn.sasls)
+	cl.reconfigureMu.Unlock()
 
 	return rerr
 }
@@ -1149,11 +1309,11 @@ func (cl *Client) retryableBrokerFn(fn func() (*broker, error)) *retryable {
 }
 
 func (cl *Client) shouldRetry(tries int, err error) bool {
-	return (kerr.IsRetriable(err) || isRetryableBrokerErr(err)) && int64(tries) < cl.cfg.retries
+	return (kerr.IsRetriable(err) || isRetryableBrokerErr(err)) && int64(tries) < cl.cfg.retries.load()
 }
 
 func (cl *Client) shouldRetryNext(tries int, err error) bool {
-	return isSkippableBrokerErr(err) && int64(tries) < cl.cfg.retries
+	return isSkippableBrokerErr(err) && int64(tries) < cl.cfg.retries.load()
 }
 
 type retryable struct {
@@ -1192,7 +1352,7 @@ func (d *failDial) isRepeatedDialFail(err error) bool {
 func (r *retryable) Request(ctx context.Context, req kmsg.Request) (kmsg.Response, error) {
 	tries := 0
 	tryStart := time.Now()
-	retryTimeout := r.cl.cfg.retryTimeout(req.Key())
+	retryTimeout := r.cl.loadDyn().retryTimeout(req.Key())
 
 	next, nextErr := r.br()
 start:
@@ -1210,7 +1370,7 @@ start:
 
 	if err != nil || retryErr != nil {
 		if r.limitRetries == 0 || tries < r.limitRetries {
-			backoff := r.cl.cfg.retryBackoff(tries)
+			backoff := r.cl.loadDyn().retryBackoff(tries)
 			if retryTimeout == 0 || time.Now().Add(backoff).Sub(tryStart) <= retryTimeout {
 				// If this broker / request had a retryable error, we can
 				// just retry now. If the error is *not* retryable but
@@ -2000,6 +2160,62 @@ func (cl *Client) UpdateSeedBrokers(addrs ...string) error {
 	return nil
 }
 
+// numKnownBrokers returns how many brokers the client knows of: brokers
+// discovered through metadata if any, otherwise the seed brokers.
+func (cl *Client) numKnownBrokers() int {
+	cl.brokersMu.RLock()
+	defer cl.brokersMu.RUnlock()
+	if len(cl.brokers) > 0 {
+		return len(cl.brokers)
+	}
+	return len(cl.loadSeeds())
+}
+
+// rebootstrap discards all brokers discovered through metadata and replaces
+// the seed brokers with fresh copies, so that the next metadata request dials
+// (and re-resolves) the seed addresses from scratch. This is the KIP-899
+// recovery for when every broker we know of is unreachable.
+func (cl *Client) rebootstrap(failing time.Duration, err error) {
+	cl.brokersMu.Lock()
+	if cl.stopBrokers {
+		cl.brokersMu.Unlock()
+		return
+	}
+
+	oldSeeds := cl.loadSeeds()
+	seeds := make([]*broker, 0, len(oldSeeds))
+	addrs := make([]string, 0, len(oldSeeds))
+	for _, old := range oldSeeds {
+		seeds = append(seeds, cl.newBroker(old.meta.NodeID, old.meta.Host, old.meta.Port, nil))
+		addrs = append(addrs, old.addr)
+	}
+	cl.seeds.Store(seeds)
+
+	oldBrokers := cl.brokers
+	cl.brokers = nil
+	cl.anySeedIdx = 0
+	cl.reinitAnyBrokerOrd()
+	cl.brokersMu.Unlock()
+
+	for _, b := range oldSeeds {
+		b.stopForever()
+	}
+	for _, b := range oldBrokers {
+		b.stopForever()
+	}
+
+	cl.cfg.logger.Log(LogLevelWarn, "metadata requests have been failing to all known brokers, discarding discovered brokers and re-bootstrapping from seeds",
+		"seeds", addrs,
+		"failing_for", failing,
+		"err", err,
+	)
+	cl.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookClientRebootstrap); ok {
+			h.OnClientRebootstrap(addrs, failing, err)
+		}
+	})
+}
+
 // Broker pairs a broker ID with a client to directly issue requests to a
 // specific broker.
 type Broker struct {
@@ -2170,7 +2386,7 @@ func (cl *Client) handleShardedReq(ctx context.Context, req kmsg.Request) ([]Res
 		}
 
 		start        = time.Now()
-		retryTimeout = cl.cfg.retryTimeout(req.Key())
+		retryTimeout = cl.loadDyn().retryTimeout(req.Key())
 
 		wg    sync.WaitGroup
 		issue func(reqTry)
@@ -2266,7 +2482,7 @@ func (cl *Client) handleShardedReq(ctx context.Context, req kmsg.Request) ([]Res
 				// immediately. The request was not even issued. However, as a
 				// safety, we only do this 3 times to avoid some super weird
 				// pathological spin loop.
-				backoff := cl.cfg.retryBackoff(tries)
+				backoff := cl.loadDyn().retryBackoff(tries)
 				if err != nil &&
 					(reshardable && isPinned && errors.Is(err, errBrokerTooOld) && tries <= 3) ||
 					(retryTimeout == 0 || time.Now().Add(backoff).Sub(start) <= retryTimeout) && cl.shouldRetry(tries, err) && cl.waitTries(ctx, backoff) {
@@ -2,11 +2,15 @@ package kgo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net"
 	"reflect"
 	"strconv"
 	"testing"
 	"time"
 
+	"github.com/twmb/franz-go/pkg/kerr"
 	"github.com/twmb/franz-go/pkg/kmsg"
 )
 
@@ -214,3 +218,246 @@ type intSliceHook []int
 func (*intSliceHook) OnNewClient(*Client) {
 	// ignore
 }
+
+type rebootstrapHook struct {
+	seeds []string
+	err   error
+}
+
+func (h *rebootstrapHook) OnClientRebootstrap(seeds []string, _ time.Duration, err error) {
+	h.seeds, h.err = seeds, err
+}
+
+func TestRebootstrap(t *testing.T) {
+	h := new(rebootstrapHook)
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1", "localhost:2"), WithHooks(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	cl.updateBrokers([]kmsg.MetadataResponseBroker{
+		{NodeID: 1, Host: "stale1", Port: 9092},
+		{NodeID: 2, Host: "stale2", Port: 9092},
+	})
+	oldSeeds := cl.loadSeeds()
+
+	rebootstrapErr := errors.New("all brokers down")
+	cl.rebootstrap(time.Minute, rebootstrapErr)
+
+	if exp := []string{"127.0.0.1:1", "localhost:2"}; !reflect.DeepEqual(h.seeds, exp) {
+		t.Errorf("got hook seeds %v != exp %v", h.seeds, exp)
+	}
+	if h.err != rebootstrapErr {
+		t.Errorf("got hook err %v != exp %v", h.err, rebootstrapErr)
+	}
+
+	cl.brokersMu.Lock()
+	nbrokers := len(cl.brokers)
+	cl.brokersMu.Unlock()
+	if nbrokers != 0 {
+		t.Errorf("got %d brokers after rebootstrap, expected 0", nbrokers)
+	}
+
+	newSeeds := cl.loadSeeds()
+	if len(newSeeds) != len(oldSeeds) {
+		t.Fatalf("got %d seeds after rebootstrap != exp %d", len(newSeeds), len(oldSeeds))
+	}
+	for i := range oldSeeds {
+		if !oldSeeds[i].dead.Load() {
+			t.Errorf("old seed %d is not stopped", i)
+		}
+		if newSeeds[i] == oldSeeds[i] || newSeeds[i].addr != oldSeeds[i].addr || newSeeds[i].meta.NodeID != oldSeeds[i].meta.NodeID {
+			t.Errorf("seed %d was not recreated as expected", i)
+		}
+	}
+}
+
+func TestRebootstrapOnlyWhenUnreachable(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	if v := cl.OptValue(RebootstrapAfter); v != time.Duration(0) {
+		t.Errorf("got default rebootstrap after %v, expected disabled", v)
+	}
+
+	for i, test := range []struct {
+		err error
+		exp bool
+	}{
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		{fmt.Errorf("wrapped: %w", errBrokerDegraded), true},
+		{errUnknownBroker, true},
+		{kerr.ClusterAuthorizationFailed, false},
+		{&net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}, false},
+		{context.Canceled, false},
+	} {
+		if got := isUnreachableBrokerErr(test.err); got != test.exp {
+			t.Errorf("#%d (%v): got unreachable %v, expected %v", i, test.err, got, test.exp)
+		}
+	}
+}
+
+type fakeResolver struct {
+	hosts   map[string][]string
+	srvs    map[string][]*net.SRV
+	lookups int
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	r.lookups++
+	if ips, ok := r.hosts[host]; ok {
+		return ips, nil
+	}
+	return nil, errors.New("no such host")
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	if srvs, ok := r.srvs[name]; ok {
+		return name, srvs, nil
+	}
+	return "", nil, errors.New("no such host")
+}
+
+func TestBrokerDialResolved(t *testing.T) {
+	r := &fakeResolver{
+		hosts: map[string][]string{
+			"kafka.example.com": {"10.0.0.1", "10.0.0.2"},
+			"b1.example.com":    {"10.0.1.1"},
+			"b2.example.com":    {"10.0.2.1"},
+		},
+		srvs: map[string][]*net.SRV{
+			"_kafka._tcp.example.com": {
+				{Target: "b1.example.com.", Port: 9093},
+				{Target: "b2.example.com.", Port: 9094},
+			},
+		},
+	}
+
+	var dialed, hosts []string
+	dialFn := func(ctx context.Context, _, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		host, _ := ctx.Value(dialHostKey{}).(string)
+		hosts = append(hosts, host)
+		if addr == "10.0.0.2:9092" || addr == "10.0.2.1:9094" {
+			c1, c2 := net.Pipe()
+			c2.Close()
+			return c1, nil
+		}
+		return nil, errors.New("unreachable")
+	}
+
+	cl, err := NewClient(SeedBrokers("kafka.example.com", "_kafka._tcp.example.com"), DNSResolver(r), Dialer(dialFn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	for _, test := range []struct {
+		seed      int
+		expDialed []string
+		expHosts  []string
+	}{
+		{0, []string{"10.0.0.1:9092", "10.0.0.2:9092"}, []string{"kafka.example.com", "kafka.example.com"}},
+		{1, []string{"10.0.1.1:9093", "10.0.2.1:9094"}, []string{"b1.example.com", "b2.example.com"}},
+	} {
+		dialed, hosts = nil, nil
+		conn, err := cl.loadSeeds()[test.seed].dial(context.Background())
+		if err != nil {
+			t.Fatalf("seed %d: unexpected dial err: %v", test.seed, err)
+		}
+		conn.Close()
+		if !reflect.DeepEqual(dialed, test.expDialed) {
+			t.Errorf("seed %d: got dialed %v != exp %v", test.seed, dialed, test.expDialed)
+		}
+		if !reflect.DeepEqual(hosts, test.expHosts) {
+			t.Errorf("seed %d: got dial hosts %v != exp %v", test.seed, hosts, test.expHosts)
+		}
+	}
+
+	// Dialing again uses the cached resolution; failing every address
+	// clears the cache so that the next dial resolves again.
+	b := cl.loadSeeds()[0]
+	lookups := r.lookups
+	if conn, err := b.dial(context.Background()); err == nil {
+		conn.Close()
+	}
+	if r.lookups != lookups {
+		t.Errorf("got %d lookups after a cached dial, exp %d", r.lookups, lookups)
+	}
+	r.hosts["kafka.example.com"] = []string{"10.0.0.3"}
+	b.resolveMu.Lock()
+	b.resolvedAt = time.Time{}
+	b.resolveMu.Unlock()
+	if _, err := b.dial(context.Background()); err == nil {
+		t.Error("expected dial err after re-resolving to an unreachable address")
+	}
+	b.resolveMu.Lock()
+	nresolved := len(b.resolved)
+	b.resolveMu.Unlock()
+	if nresolved != 0 {
+		t.Errorf("got %d cached addresses after all dials failed, exp 0", nresolved)
+	}
+}
+
+func TestIsSRVName(t *testing.T) {
+	for _, test := range []struct {
+		host string
+		exp  bool
+	}{
+		{"_kafka._tcp.example.com", true},
+		{"kafka.example.com", false},
+		{"_kafka.example.com", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+	} {
+		if got := isSRVName(test.host); got != test.exp {
+			t.Errorf("isSRVName(%q): got %v != exp %v", test.host, got, test.exp)
+		}
+	}
+}
+
+func TestConnectionsPerBroker(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), ProduceConnectionsPerBroker(4), FetchConnectionsPerBroker(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	sns := cl.newSinkAndSource(1)
+	if len(sns.sinks) != 4 || len(sns.sources) != 2 {
+		t.Fatalf("got %d sinks and %d sources, exp 4 and 2", len(sns.sinks), len(sns.sources))
+	}
+	used := make(map[*sink]bool)
+	for p := int32(0); p < 8; p++ {
+		s := sns.sink("foo", p)
+		if s != sns.sink("foo", p) {
+			t.Errorf("partition %d is not pinned to one sink", p)
+		}
+		if s.cxnIdx != partitionIdx("foo", p, 4) {
+			t.Errorf("partition %d sink has cxnIdx %d, exp %d", p, s.cxnIdx, partitionIdx("foo", p, 4))
+		}
+		used[s] = true
+	}
+	if len(used) != 4 {
+		t.Errorf("8 partitions used %d of 4 sinks", len(used))
+	}
+
+	b := cl.newBroker(1, "127.0.0.1", 1, nil)
+	if len(b.cxnProduce) != 4 || len(b.cxnFetch) != 2 || len(b.laneReqs) != 6 {
+		t.Errorf("got %d produce cxns, %d fetch cxns, %d lanes, exp 4, 2, 6", len(b.cxnProduce), len(b.cxnFetch), len(b.laneReqs))
+	}
+	if r := b.reqsFor(&fetchRequest{cxnIdx: 1}); r != &b.laneReqs[5] {
+		t.Error("fetch request on connection 1 did not use the last lane")
+	}
+	if r := b.reqsFor(kmsg.NewPtrMetadataRequest()); r != &b.reqs {
+		t.Error("metadata request did not use the shared queue")
+	}
+	b.stopForever()
+
+	if _, err := NewClient(SeedBrokers("127.0.0.1:1"), ProduceConnectionsPerBroker(0)); err == nil {
+		t.Error("expected error for zero produce connections")
+	}
+}
@@ -259,6 +259,11 @@ type zstdDecoder struct {
 }
 
 func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
+	return d.decompressTo(nil, src, codec)
+}
+
+// decompressTo decompresses src into dst, reusing dst's capacity if possible.
+func (d *decompressor) decompressTo(dst, src []byte, codec byte) ([]byte, error) {
 	switch codecType(codec) {
 	case codecNone:
 		return src, nil
@@ -268,7 +273,7 @@ func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
 		if err := ungz.Reset(bytes.NewReader(src)); err != nil {
 			return nil, err
 		}
-		out := new(bytes.Buffer)
+		out := bytes.NewBuffer(dst[:0])
 		if _, err := io.Copy(out, ungz); err != nil {
 			return nil, err
 		}
@@ -277,12 +282,12 @@ func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
 		if len(src) > 16 && bytes.HasPrefix(src, xerialPfx) {
 			return xerialDecode(src)
 		}
-		return s2.Decode(nil, src)
+		return s2.Decode(dst[:cap(dst)], src)
 	case codecLZ4:
 		unlz4 := d.unlz4Pool.Get().(*lz4.Reader)
 		defer d.unlz4Pool.Put(unlz4)
 		unlz4.Reset(bytes.NewReader(src))
-		out := new(bytes.Buffer)
+		out := bytes.NewBuffer(dst[:0])
 		if _, err := io.Copy(out, unlz4); err != nil {
 			return nil, err
 		}
@@ -290,7 +295,7 @@ func (d *decompressor) decompress(src []byte, codec byte) ([]byte, error) {
 	case codecZstd:
 		unzstd := d.unzstdPool.Get().(*zstdDecoder)
 		defer d.unzstdPool.Put(unzstd)
-		return unzstd.inner.DecodeAll(src, nil)
+		return unzstd.inner.DecodeAll(src, dst[:0])
 	default:
 		return nil, errors.New("unknown compression codec")
 	}
@@ -325,3 +330,185 @@ func xerialDecode(src []byte) ([]byte, error) {
 	}
 	return dst, nil
 }
+
+var errMalformedFraming = errors.New("malformed compression framing")
+
+// checkFraming validates the framing of compressed bytes without
+// decompressing them: the codec must be known, the format's header must be
+// valid, and length prefixed pieces (xerial chunks, lz4 blocks, zstd blocks)
+// must exactly span src. This catches mangled headers and truncated lz4, zstd,
+// and xerial data, but not corruption inside of compressed data, nor
+// truncated gzip or unframed snappy data.
+func checkFraming(src []byte, codec byte) error {
+	switch codecType(codec) {
+	case codecNone:
+		return nil
+	case codecGzip:
+		// ID1, ID2, CM (deflate), and at least a 10 byte header
+		// and 8 byte trailer.
+		if len(src) < 18 || src[0] != 0x1f || src[1] != 0x8b || src[2] != 8 {
+			return errMalformedFraming
+		}
+		return nil
+	case codecSnappy:
+		if len(src) > 16 && bytes.HasPrefix(src, xerialPfx) {
+			for src = src[16:]; len(src) > 0; {
+				if len(src) < 4 {
+					return errMalformedXerial
+				}
+				size := int32(binary.BigEndian.Uint32(src))
+				src = src[4:]
+				if size < 0 || len(src) < int(size) {
+					return errMalformedXerial
+				}
+				if _, err := s2.DecodedLen(src[:size]); err != nil {
+					return err
+				}
+				src = src[size:]
+			}
+			return nil
+		}
+		_, err := s2.DecodedLen(src)
+		return err
+	case codecLZ4:
+		return checkFrames(src, checkLZ4Frame)
+	case codecZstd:
+		return checkFrames(src, checkZstdFrame)
+	default:
+		return errors.New("unknown compression codec")
+	}
+}
+
+// checkFrames checks each concatenated lz4 or zstd frame in src, skipping
+// skippable frames, which the two formats share.
+func checkFrames(src []byte, check func([]byte) ([]byte, bool)) error {
+	if len(src) == 0 {
+		return errMalformedFraming
+	}
+	for len(src) > 0 {
+		if len(src) < 8 {
+			return errMalformedFraming
+		}
+		if binary.LittleEndian.Uint32(src)&0xfffffff0 == 0x184d2a50 {
+			size := uint64(binary.LittleEndian.Uint32(src[4:]))
+			if uint64(len(src)-8) < size {
+				return errMalformedFraming
+			}
+			src = src[8+size:]
+			continue
+		}
+		var ok bool
+		if src, ok = check(src); !ok {
+			return errMalformedFraming
+		}
+	}
+	return nil
+}
+
+// checkLZ4Frame walks the blocks of the lz4 frame at the start of src,
+// returning what follows the frame.
+func checkLZ4Frame(src []byte) ([]byte, bool) {
+	if binary.LittleEndian.Uint32(src) != 0x184d2204 {
+		return nil, false
+	}
+	flg := src[4]
+	if flg>>6 != 1 || flg&0b10 != 0 {
+		return nil, false // unknown version or reserved bit set
+	}
+	var (
+		blockChecksum   = flg&0b1_0000 != 0
+		contentSize     = flg&0b1000 != 0
+		contentChecksum = flg&0b100 != 0
+		dictID          = flg&0b1 != 0
+	)
+	hdr := 7 // magic, FLG, BD, HC
+	if contentSize {
+		hdr += 8
+	}
+	if dictID {
+		hdr += 4
+	}
+	if len(src) < hdr {
+		return nil, false
+	}
+	for src = src[hdr:]; ; {
+		if len(src) < 4 {
+			return nil, false
+		}
+		raw := binary.LittleEndian.Uint32(src)
+		src = src[4:]
+		if raw == 0 {
+			break // end mark
+		}
+		size := uint64(raw & 0x7fffffff) // the high bit is whether the block is uncompressed
+		if blockChecksum {
+			size += 4
+		}
+		if uint64(len(src)) < size {
+			return nil, false
+		}
+		src = src[size:]
+	}
+	if contentChecksum {
+		if len(src) < 4 {
+			return nil, false
+		}
+		src = src[4:]
+	}
+	return src, true
+}
+
+// checkZstdFrame walks the blocks of the zstd frame at the start of src,
+// returning what follows the frame.
+func checkZstdFrame(src []byte) ([]byte, bool) {
+	if binary.LittleEndian.Uint32(src) != 0xfd2fb528 {
+		return nil, false
+	}
+	fhd := src[4]
+	if fhd&0b1000 != 0 {
+		return nil, false // reserved bit set
+	}
+	var (
+		fcsFlag       = fhd >> 6
+		singleSegment = fhd&0b10_0000 != 0
+		checksum      = fhd&0b100 != 0
+		dictIDFlag    = fhd & 0b11
+	)
+	hdr := 5 + [4]int{0, 1, 2, 4}[dictIDFlag] + [4]int{0, 2, 4, 8}[fcsFlag]
+	if !singleSegment {
+		hdr++ // window descriptor
+	} else if fcsFlag == 0 {
+		hdr++ // single segment frames always have a content size
+	}
+	if len(src) < hdr {
+		return nil, false
+	}
+	for src = src[hdr:]; ; {
+		if len(src) < 3 {
+			return nil, false
+		}
+		bh := uint32(src[0]) | uint32(src[1])<<8 | uint32(src[2])<<16
+		src = src[3:]
+		size := int(bh >> 3)
+		switch bh >> 1 & 0b11 {
+		case 1: // RLE: a single byte repeated size times
+			size = 1
+		case 3: // reserved
+			return nil, false
+		}
+		if len(src) < size {
+			return nil, false
+		}
+		src = src[size:]
+		if bh&1 == 1 {
+			break // last block
+		}
+	}
+	if checksum {
+		if len(src) < 4 {
+			return nil, false
+		}
+		src = src[4:]
+	}
+	return src, true
+}
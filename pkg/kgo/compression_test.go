@@ -112,6 +112,50 @@ func BenchmarkCompress(b *testing.B) {
 	}
 }
 
+func TestCheckFraming(t *testing.T) {
+	t.Parallel()
+	in := bytes.Repeat([]byte("foo bar baz "), 50<<10) // multiple lz4 and zstd blocks
+	xerial, _ := base64.StdEncoding.DecodeString("glNOQVBQWQAAAAABAAAAAQAAAA8NMEhlbGxvLCBXb3JsZCE=")
+	for _, test := range []struct {
+		codec      byte
+		compressed []byte
+		truncates  bool // whether truncation is detected
+	}{
+		{1, nil, false},
+		{2, nil, false},
+		{2, xerial, true},
+		{3, nil, true},
+		{4, nil, true},
+	} {
+		compressed := test.compressed
+		if compressed == nil {
+			c, _ := newCompressor(CompressionCodec{codec: codecType(test.codec)})
+			w := sliceWriters.Get().(*sliceWriter)
+			got, _ := c.compress(w, in, 7)
+			compressed = append([]byte(nil), got...)
+			sliceWriters.Put(w)
+		}
+		if err := checkFraming(compressed, test.codec); err != nil {
+			t.Errorf("codec %d: unexpected err %v", test.codec, err)
+		}
+		mangled := append([]byte(nil), compressed...)
+		mangled[0] ^= 0xff
+		if err := checkFraming(mangled, test.codec); err == nil && test.codec != 2 {
+			t.Errorf("codec %d: expected err with a mangled header", test.codec)
+		}
+		if test.truncates {
+			for _, l := range []int{len(compressed) - 1, len(compressed) / 2} {
+				if err := checkFraming(compressed[:l], test.codec); err == nil {
+					t.Errorf("codec %d: expected err truncated to %d of %d bytes", test.codec, l, len(compressed))
+				}
+			}
+		}
+	}
+	if err := checkFraming([]byte("foo"), 5); err == nil {
+		t.Error("expected err for an unknown codec")
+	}
+}
+
 func Test_xerialDecode(t *testing.T) {
 	tests := []struct {
 		name            string
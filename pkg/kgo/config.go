@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"math"
 	"math/rand"
 	"net"
@@ -70,11 +71,22 @@ type cfg struct {
 
 	id                     *string // client ID
 	dialFn                 func(context.Context, string, string) (net.Conn, error)
+	dialFnOwned            bool // dialFn was created in NewClient, rather than with Dialer
 	dialTimeout            time.Duration
 	dialTLS                *tls.Config
+	resolver               Resolver
+	dnsRefreshInterval     time.Duration
 	requestTimeoutOverhead time.Duration
 	connIdleTimeout        time.Duration
 
+	traffic   io.Writer
+	namespace string
+
+	breakerFailures int
+	breakerCooldown time.Duration
+	adaptiveMult    float64
+	adaptiveMin     time.Duration
+
 	softwareName    string // KIP-511
 	softwareVersion string // KIP-511
 
@@ -85,17 +97,20 @@ type cfg struct {
 	minVersions *kversion.Versions
 
 	retryBackoff func(int) time.Duration
-	retries      int64
+	retries      lazyI64
 	retryTimeout func(int16) time.Duration
 
 	maxBrokerWriteBytes int32
 	maxBrokerReadBytes  int32
 
 	allowAutoTopicCreation bool
+	topicCreations         []topicCreation // see CreateTopicOnProduce
 
 	metadataMaxAge time.Duration
 	metadataMinAge time.Duration
 
+	rebootstrapAfter time.Duration // KIP-899
+
 	sasls []sasl.Mechanism
 
 	hooks hooks
@@ -109,16 +124,17 @@ type cfg struct {
 	acks               Acks
 	disableIdempotency bool
 	maxProduceInflight int                // if idempotency is disabled, we allow a configurable max inflight
+	produceConns       int                // number of produce connections per broker
 	compression        []CompressionCodec // order of preference
 
 	defaultProduceTopic string
 	maxRecordBatchBytes int32
-	maxBufferedRecords  int64
-	maxBufferedBytes    int64
+	maxBufferedRecords  lazyI64
+	maxBufferedBytes    lazyI64
 	produceTimeout      time.Duration
-	recordRetries       int64
+	recordRetries       lazyI64
 	maxUnknownFailures  int64
-	linger              time.Duration
+	linger              lazyI64 // time.Duration
 	recordTimeout       time.Duration
 	manualFlushing      bool
 	txnBackoff          time.Duration
@@ -140,10 +156,25 @@ type cfg struct {
 	resetOffset    Offset
 	isolationLevel int8
 	keepControl    bool
+	lazyDecode     bool
+	pooledRecords  bool
+	consumeFilter  func(*Record) bool
+	dedupIdentity  DedupIdentity
+	dedupWindow    int
 	rack           string
 	preferLagFn    PreferLagFn
 
+	topicPriorities map[string]int
+	topicWeights    map[string]int
+	fairPartitions  bool
+
+	replay      bool
+	replayFrom  time.Time
+	replaySpeed float64
+
 	maxConcurrentFetches     int
+	fetchConns               int // number of fetch connections per broker
+	decodeParallelism        int
 	disableFetchSessions     bool
 	keepRetryableFetchErrors bool
 
@@ -151,6 +182,11 @@ type cfg struct {
 	partitions map[string]map[int32]Offset // partitions to directly consume from
 	regex      bool
 
+	regexExcludes      map[string]*regexp.Regexp // topics matching these regular expressions are never consumed via regex
+	regexFilter        func(string) bool
+	onTopicsDiscovered func(*Client, []string)
+	onTopicsRemoved    func(*Client, []string)
+
 	////////////////////////////
 	// CONSUMER GROUP SECTION //
 	////////////////////////////
@@ -164,6 +200,7 @@ type cfg struct {
 	rebalanceTimeout  time.Duration
 	heartbeatInterval time.Duration
 	requireStable     bool
+	dedupCheckpoint   bool
 
 	onAssigned func(context.Context, *Client, map[string][]int32)
 	onRevoked  func(context.Context, *Client, map[string][]int32)
@@ -231,6 +268,10 @@ func (cfg *cfg) validate() error {
 		{name: "transactional id", sp: &cfg.txnID, allowed: 16382},
 
 		{name: "rack", s: cfg.rack, allowed: 512},
+
+		// A namespace is prefixed to topics (max 249 characters),
+		// leaving at least one character for the topic itself.
+		{name: "namespace", s: cfg.namespace, allowed: 248},
 	} {
 		s := limit.s
 		if limit.sp != nil && *limit.sp != nil {
@@ -279,22 +320,47 @@ func (cfg *cfg) validate() error {
 		// 0 <= allowed concurrency
 		{name: "max concurrent fetches", v: int64(cfg.maxConcurrentFetches), allowed: 0, badcmp: i64lt},
 
+		// 1 <= fetch decode parallelism <= 1024
+		{name: "fetch decode parallelism", v: int64(cfg.decodeParallelism), allowed: 1, badcmp: i64lt},
+		{name: "fetch decode parallelism", v: int64(cfg.decodeParallelism), allowed: 1024, badcmp: i64gt},
+
 		// 1s <= request timeout overhead <= 15m
 		{name: "request timeout max overhead", v: int64(cfg.requestTimeoutOverhead), allowed: int64(15 * time.Minute), badcmp: i64gt, durs: true},
 		{name: "request timeout min overhead", v: int64(cfg.requestTimeoutOverhead), allowed: int64(time.Second), badcmp: i64lt, durs: true},
 
+		// 0 (disabled) <= breaker failures; 100ms <= breaker cooldown <= 15m
+		{name: "circuit breaker failures", v: int64(cfg.breakerFailures), allowed: 0, badcmp: i64lt},
+		{name: "circuit breaker min cooldown", v: int64(cfg.breakerCooldown), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
+		{name: "circuit breaker max cooldown", v: int64(cfg.breakerCooldown), allowed: int64(15 * time.Minute), badcmp: i64gt, durs: true},
+
+		// 100ms <= adaptive timeout overhead min <= request timeout overhead
+		{name: "adaptive request timeout min overhead", v: int64(cfg.adaptiveMin), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
+		{v: int64(cfg.adaptiveMin), allowed: int64(cfg.requestTimeoutOverhead), badcmp: i64gt, fmt: "adaptive request timeout min overhead %v is erroneously larger than the request timeout overhead %v", durs: true},
+
 		// 1s <= conn idle <= 15m
 		{name: "conn min idle timeout", v: int64(cfg.connIdleTimeout), allowed: int64(time.Second), badcmp: i64lt, durs: true},
 		{name: "conn max idle timeout", v: int64(cfg.connIdleTimeout), allowed: int64(15 * time.Minute), badcmp: i64gt, durs: true},
 
+		// 0 (resolve every dial) <= dns refresh
+		{name: "dns refresh interval", v: int64(cfg.dnsRefreshInterval), allowed: 0, badcmp: i64lt, durs: true},
+
 		// 10ms <= metadata <= 1hr
 		{name: "metadata max age", v: int64(cfg.metadataMaxAge), allowed: int64(time.Hour), badcmp: i64gt, durs: true},
 		{name: "metadata min age", v: int64(cfg.metadataMinAge), allowed: int64(10 * time.Millisecond), badcmp: i64lt, durs: true},
 		{v: int64(cfg.metadataMaxAge), allowed: int64(cfg.metadataMinAge), badcmp: i64lt, fmt: "metadata max age %v is erroneously less than metadata min age %v", durs: true},
 
+		// 0 (disabled) <= rebootstrap
+		{name: "rebootstrap after", v: int64(cfg.rebootstrapAfter), allowed: 0, badcmp: i64lt, durs: true},
+
+		// 1 <= connections per broker <= 64
+		{name: "produce connections per broker", v: int64(cfg.produceConns), allowed: 1, badcmp: i64lt},
+		{name: "produce connections per broker", v: int64(cfg.produceConns), allowed: 64, badcmp: i64gt},
+		{name: "fetch connections per broker", v: int64(cfg.fetchConns), allowed: 1, badcmp: i64lt},
+		{name: "fetch connections per broker", v: int64(cfg.fetchConns), allowed: 64, badcmp: i64gt},
+
 		// Some random producer settings.
-		{name: "max buffered records", v: cfg.maxBufferedRecords, allowed: 1, badcmp: i64lt},
-		{name: "max buffered bytes", v: cfg.maxBufferedBytes, allowed: 0, badcmp: i64lt},
+		{name: "max buffered records", v: int64(cfg.maxBufferedRecords), allowed: 1, badcmp: i64lt},
+		{name: "max buffered bytes", v: int64(cfg.maxBufferedBytes), allowed: 0, badcmp: i64lt},
 		{name: "linger", v: int64(cfg.linger), allowed: int64(time.Minute), badcmp: i64gt, durs: true},
 		{name: "produce timeout", v: int64(cfg.produceTimeout), allowed: int64(100 * time.Millisecond), badcmp: i64lt, durs: true},
 		{name: "record timeout", v: int64(cfg.recordTimeout), allowed: int64(time.Second), badcmp: func(l, r int64) (bool, string) {
@@ -334,7 +400,43 @@ func (cfg *cfg) validate() error {
 		}
 	}
 
-	if cfg.dialFn != nil {
+	for topic, weight := range cfg.topicWeights {
+		if weight < 1 {
+			return fmt.Errorf("topic %q weight %d is less than allowed 1", topic, weight)
+		}
+	}
+
+	if cfg.dedupIdentity != nil && (cfg.dedupWindow < 1 || cfg.dedupWindow > 1<<20) {
+		return fmt.Errorf("dedup window %d is not between allowed 1 and %d", cfg.dedupWindow, 1<<20)
+	}
+	if cfg.dedupCheckpoint && cfg.dedupIdentity == nil {
+		return errors.New("invalid CheckpointDedupInCommits option without ConsumeDedup")
+	}
+	if cfg.dedupCheckpoint && len(cfg.group) == 0 {
+		return errors.New("invalid CheckpointDedupInCommits option when a group was not specified")
+	}
+
+	if cfg.replay && !(cfg.replaySpeed > 0) {
+		return fmt.Errorf("replay speed %v is not larger than 0", cfg.replaySpeed)
+	}
+
+	if cfg.adaptiveMult != 0 && cfg.adaptiveMult < 1 {
+		return fmt.Errorf("adaptive request timeout multiplier %v is less than allowed 1", cfg.adaptiveMult)
+	}
+
+	for i := range cfg.topicCreations {
+		tc := &cfg.topicCreations[i]
+		if !tc.regex {
+			continue
+		}
+		compiled, err := regexp.Compile(tc.topic)
+		if err != nil {
+			return fmt.Errorf("invalid topic creation regular expression %q", tc.topic)
+		}
+		tc.re = compiled
+	}
+
+	if cfg.dialFn != nil && !cfg.dialFnOwned {
 		if cfg.dialTLS != nil {
 			return errors.New("cannot set both Dialer and DialTLSConfig")
 		}
@@ -357,6 +459,15 @@ func (cfg *cfg) validate() error {
 			}
 			cfg.topics[re] = compiled
 		}
+		for re := range cfg.regexExcludes {
+			compiled, err := regexp.Compile(re)
+			if err != nil {
+				return fmt.Errorf("invalid exclusion regular expression %q", re)
+			}
+			cfg.regexExcludes[re] = compiled
+		}
+	} else if len(cfg.regexExcludes) > 0 || cfg.regexFilter != nil {
+		return errors.New("invalid regex exclusion or filter option when not consuming as regex")
 	}
 
 	if cfg.topics != nil && cfg.partitions != nil {
@@ -438,7 +549,10 @@ func defaultCfg() cfg {
 		id: &defaultID,
 
 		dialTimeout:            10 * time.Second,
+		dnsRefreshInterval:     time.Minute,
 		requestTimeoutOverhead: 10 * time.Second,
+		breakerCooldown:        10 * time.Second,
+		adaptiveMin:            time.Second,
 		connIdleTimeout:        20 * time.Second,
 
 		softwareName:    "kgo",
@@ -494,6 +608,7 @@ func defaultCfg() cfg {
 		txnTimeout:          40 * time.Second,
 		acks:                AllISRAcks(),
 		maxProduceInflight:  1,
+		produceConns:        1,
 		compression:         []CompressionCodec{SnappyCompression(), NoCompression()},
 		maxRecordBatchBytes: 1000012, // Kafka max.message.bytes default is 1000012
 		maxBufferedRecords:  10000,
@@ -515,6 +630,8 @@ func defaultCfg() cfg {
 		isolationLevel: 0,
 
 		maxConcurrentFetches: 0, // unbounded default
+		fetchConns:           1,
+		decodeParallelism:    1,
 
 		///////////
 		// group //
@@ -589,6 +706,72 @@ func RequestTimeoutOverhead(overhead time.Duration) Opt {
 	return clientOpt{func(cfg *cfg) { cfg.requestTimeoutOverhead = overhead }}
 }
 
+// BrokerCircuitBreaker enables a circuit breaker for every broker, which opens
+// after the given number of consecutive failed requests to a broker and stays
+// open for cooldown (by default, the breaker is disabled and the cooldown is
+// 10s). A request fails if the connection cannot be opened, or if writing the
+// request or reading the response fails or times out; error codes within
+// responses do not count as failures. Requests that are canceled by their
+// context do not count.
+//
+// While a broker's breaker is open, the broker is considered degraded:
+//
+//   - requests that must go to that broker fail immediately with a retryable
+//     error rather than piling up behind a slow broker, and are retried with
+//     the client's normal retry backoff
+//   - requests that can go to any broker (metadata requests and coordinator
+//     lookups) prefer brokers that are not degraded
+//   - fetches that would move to a degraded preferred replica (see Rack)
+//     stay on the partition leader
+//
+// Once per cooldown, one request is allowed through to a degraded broker. Any
+// successful response closes the breaker, while a failure restarts the
+// cooldown. Whether a broker is degraded is available in Client.State.
+func BrokerCircuitBreaker(failures int, cooldown time.Duration) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.breakerFailures, cfg.breakerCooldown = failures, cooldown }}
+}
+
+// AdaptiveRequestTimeoutOverhead adapts the request timeout overhead for every
+// broker to the latency of that broker, rather than always using
+// RequestTimeoutOverhead. By default, this is disabled.
+//
+// The client tracks the latency of recent requests to each broker that do not
+// wait within the broker (that is, everything but produce, fetch, join, sync,
+// and requests with timeout fields). Once enough requests have been observed,
+// the overhead for a broker is the 99th percentile latency times multiplier,
+// bounded below by min (which must be at least 100ms) and above by
+// RequestTimeoutOverhead. Using a multiplier of 0 disables adapting; otherwise,
+// the multiplier must be at least 1.
+//
+// Adapting timeouts allows requests to a broker that has turned slow (but not
+// dead) to time out quickly, rather than waiting for the full
+// RequestTimeoutOverhead. This pairs well with BrokerCircuitBreaker. As with
+// RequestTimeoutOverhead, hitting the timeout kills the connection.
+func AdaptiveRequestTimeoutOverhead(multiplier float64, minOverhead time.Duration) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.adaptiveMult, cfg.adaptiveMin = multiplier, minOverhead }}
+}
+
+// Namespace transparently prefixes every topic, group, and transactional ID in
+// requests with prefix, and strips the prefix from responses. This is meant
+// for clusters that are shared between tenants by prefixing names: tenants use
+// logical names everywhere in the client (producing, consuming, groups,
+// transactions, and any request issued with Request, including through kadm),
+// while brokers see the prefixed names.
+//
+// Responses that list topics, groups, or transactions across the cluster
+// (metadata requests for all topics, ListGroups, and ListTransactions) are
+// filtered to names within the namespace. This means consuming with
+// ConsumeRegex only ever matches topics within the namespace.
+//
+// Topics are only rewritten in requests and responses that name topics; the
+// topic names within group member metadata are logical names, which is fine
+// so long as every member of a group uses the same namespace. Requests that
+// the client does not know how to namespace (such as ACL requests and
+// reassignment requests) are issued unchanged.
+func Namespace(prefix string) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.namespace = prefix }}
+}
+
 // ConnIdleTimeout is a rough amount of time to allow connections to idle
 // before they are closed, overriding the default 20.
 //
@@ -646,6 +829,47 @@ func DialTLSConfig(c *tls.Config) Opt {
 	return clientOpt{func(cfg *cfg) { cfg.dialTLS = c }}
 }
 
+// Resolver looks up DNS records for broker hosts. A *net.Resolver satisfies
+// this interface.
+type Resolver interface {
+	// LookupHost returns the addresses for the given host.
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	// LookupSRV looks up SRV records. The client always passes an empty
+	// service and proto and a fully formed name (_service._proto.name).
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+// DNSResolver sets the resolver to use for looking up broker hosts, opting
+// into the client resolving hosts itself rather than leaving resolution to
+// the dialer.
+//
+// When resolving itself, the client tries every address a host resolves to,
+// in order, until one connection succeeds. This corresponds to Kafka's
+// client.dns.lookup=use_all_dns_ips (KIP-302). The dial function is then
+// called with an ip:port address rather than the original host:port. If you
+// use DialTLSConfig, the TLS ServerName continues to be set from the
+// original host. If you use a custom Dialer that depends on the host (i.e.,
+// for TLS verification), you must set the server name yourself.
+//
+// Seed brokers of the form _service._proto.name (e.g.
+// _kafka._tcp.example.com) are looked up as DNS SRV records, with each
+// target tried in priority and weight order. SRV seeds are always resolved by
+// the client, using net.DefaultResolver if this option is not set.
+//
+// Resolved addresses are cached per broker for the DNSRefreshInterval, and
+// are resolved again immediately if dialing every address fails.
+func DNSResolver(r Resolver) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.resolver = r }}
+}
+
+// DNSRefreshInterval sets how long resolved broker addresses are cached
+// before being resolved again, overriding the default of 1m. Setting this to
+// 0 resolves a host on every dial. This option only applies when the client
+// resolves hosts itself; see DNSResolver for more details.
+func DNSRefreshInterval(interval time.Duration) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.dnsRefreshInterval = interval }}
+}
+
 // DialTLS opts into dialing brokers with TLS. This is a shortcut for
 // DialTLSConfig with an empty config. See DialTLSConfig for more details.
 func DialTLS() Opt {
@@ -655,7 +879,9 @@ func DialTLS() Opt {
 // SeedBrokers sets the seed brokers for the client to use, overriding the
 // default 127.0.0.1:9092.
 //
-// Any seeds that are missing a port use the default Kafka port 9092.
+// Any seeds that are missing a port use the default Kafka port 9092. Seeds of
+// the form _service._proto.name are looked up as DNS SRV records when dialing,
+// and any port on such a seed is ignored; see DNSResolver for more details.
 func SeedBrokers(seeds ...string) Opt {
 	return clientOpt{func(cfg *cfg) { cfg.seedBrokers = append(cfg.seedBrokers[:0], seeds...) }}
 }
@@ -705,7 +931,7 @@ func RetryBackoffFn(backoff func(int) time.Duration) Opt {
 // This option does not apply to produce requests; to limit produce request
 // retries / record retries, see RecordRetries.
 func RequestRetries(n int) Opt {
-	return clientOpt{func(cfg *cfg) { cfg.retries = int64(n) }}
+	return clientOpt{func(cfg *cfg) { cfg.retries = lazyI64(n) }}
 }
 
 // RetryTimeout sets the upper limit on how long we allow a request to be
@@ -802,6 +1028,25 @@ func MetadataMinAge(age time.Duration) Opt {
 	return clientOpt{func(cfg *cfg) { cfg.metadataMinAge = age }}
 }
 
+// RebootstrapAfter opts into re-bootstrapping: if the client cannot reach any
+// broker it knows of for the given duration, the client discards all
+// discovered brokers and re-bootstraps from the seed brokers. By default,
+// re-bootstrapping is disabled. Errors from brokers that the client did reach
+// (such as authorization errors) do not count towards re-bootstrapping.
+//
+// Re-bootstrapping is useful if every broker in a cluster changes address at
+// once, such as when a cluster is replaced behind the same DNS name. Without
+// re-bootstrapping, the client would keep trying the stale broker addresses
+// it learned from metadata. When re-bootstrapping, the seed brokers are
+// recreated, meaning their hostnames are resolved again on the next dial.
+// Each re-bootstrap is reported to any HookClientRebootstrap hook.
+//
+// This corresponds to Kafka's metadata.recovery.strategy=rebootstrap and
+// metadata.recovery.rebootstrap.trigger.ms (KIP-899).
+func RebootstrapAfter(timeout time.Duration) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.rebootstrapAfter = timeout }}
+}
+
 // SASL appends sasl authentication options to use for all connections.
 //
 // SASL is tried in order; if the broker supports the first mechanism, all
@@ -863,6 +1108,82 @@ func DefaultProduceTopic(t string) ProducerOpt {
 	return producerOpt{func(cfg *cfg) { cfg.defaultProduceTopic = t }}
 }
 
+// TopicCreation configures how the client creates a topic that is unknown
+// when producing; see CreateTopicOnProduce.
+type TopicCreation struct {
+	// Partitions is the number of partitions to create the topic with.
+	// Zero or -1 uses the broker default.
+	Partitions int32
+
+	// ReplicationFactor is the replication factor to create the topic
+	// with. Zero or -1 uses the broker default.
+	ReplicationFactor int16
+
+	// Configs are topic configs to create the topic with, such as
+	// "cleanup.policy" or "retention.ms".
+	Configs map[string]string
+}
+
+type topicCreation struct {
+	topic    string // an exact topic, or a regular expression if regex is true
+	regex    bool
+	re       *regexp.Regexp
+	creation TopicCreation
+}
+
+// CreateTopicOnProduce sets the client to create a topic itself when
+// producing to the topic fails with UNKNOWN_TOPIC_OR_PARTITION, rather than
+// relying on broker side auto creation (see AllowAutoTopicCreation), which
+// always uses the broker's default partitions and configs. This option can
+// be used multiple times to configure different topics.
+//
+// The topic is created once per time that the client learns the topic is
+// unknown. Only one creation is ever in flight for a topic per client; if
+// another client creates the topic first, the creation fails with
+// TOPIC_ALREADY_EXISTS, which is treated as success. Once the topic is
+// created, the client reloads metadata and produces to the topic as normal.
+// Records produced to the topic while it is created are buffered, and
+// UNKNOWN_TOPIC_OR_PARTITION errors while the creation is in flight do not
+// count toward UnknownTopicRetries; errors after the creation finishes or
+// fails do. A creation that fails with a retryable error is retried on the
+// next metadata load, while any other error (such as
+// TOPIC_AUTHORIZATION_FAILED) fails the buffered records. Every creation
+// attempt is passed to HookProduceTopicCreated.
+//
+// Creating topics requires the client to be authorized to create topics.
+func CreateTopicOnProduce(topic string, creation TopicCreation) ProducerOpt {
+	return producerOpt{func(cfg *cfg) {
+		cfg.topicCreations = append(cfg.topicCreations, topicCreation{topic: topic, creation: creation})
+	}}
+}
+
+// CreateTopicsOnProduceRegex is CreateTopicOnProduce for every topic that
+// matches the regular expression. A topic configured exactly with
+// CreateTopicOnProduce takes precedence over any regular expression, and
+// otherwise the first matching regular expression (in the order the options
+// are given) is used.
+func CreateTopicsOnProduceRegex(regex string, creation TopicCreation) ProducerOpt {
+	return producerOpt{func(cfg *cfg) {
+		cfg.topicCreations = append(cfg.topicCreations, topicCreation{topic: regex, regex: true, creation: creation})
+	}}
+}
+
+// topicCreation returns how to create a topic that is unknown when
+// producing, if the topic is configured to be created.
+func (cfg *cfg) topicCreation(topic string) (TopicCreation, bool) {
+	for _, tc := range cfg.topicCreations {
+		if !tc.regex && tc.topic == topic {
+			return tc.creation, true
+		}
+	}
+	for _, tc := range cfg.topicCreations {
+		if tc.regex && tc.re.MatchString(topic) {
+			return tc.creation, true
+		}
+	}
+	return TopicCreation{}, false
+}
+
 // Acks represents the number of acks a broker leader must have before
 // a produce request is considered complete.
 //
@@ -919,6 +1240,21 @@ func MaxProduceRequestsInflightPerBroker(n int) ProducerOpt {
 	return producerOpt{func(cfg *cfg) { cfg.maxProduceInflight = n }}
 }
 
+// ProduceConnectionsPerBroker sets the number of connections to open to each
+// broker for producing, overriding the default of 1. This must be between 1
+// and 64.
+//
+// A single TCP connection (especially with TLS) can limit throughput on high
+// bandwidth links. With more than one connection, partitions are spread
+// across the connections to each broker by hash, and every connection has its
+// own produce requests in flight and its own writer. A partition is always
+// produced on the same connection, so per-partition ordering and idempotent
+// sequence numbers are unaffected, and MaxProduceRequestsInflightPerBroker
+// applies per connection.
+func ProduceConnectionsPerBroker(n int) ProducerOpt {
+	return producerOpt{func(cfg *cfg) { cfg.produceConns = n }}
+}
+
 // ProducerBatchCompression sets the compression codec to use for producing
 // records.
 //
@@ -955,7 +1291,7 @@ func ProducerBatchMaxBytes(v int32) ProducerOpt {
 // blocking produces until records are finished if this limit is reached.
 // This overrides the default of 10,000.
 func MaxBufferedRecords(n int) ProducerOpt {
-	return producerOpt{func(cfg *cfg) { cfg.maxBufferedRecords = int64(n) }}
+	return producerOpt{func(cfg *cfg) { cfg.maxBufferedRecords = lazyI64(n) }}
 }
 
 // MaxBufferedBytes sets the max amount of bytes that the client will buffer
@@ -972,7 +1308,7 @@ func MaxBufferedRecords(n int) ProducerOpt {
 //
 // Note that this limit applies after [MaxBufferedRecords].
 func MaxBufferedBytes(n int) ProducerOpt {
-	return producerOpt{func(cfg *cfg) { cfg.maxBufferedBytes = int64(n) }}
+	return producerOpt{func(cfg *cfg) { cfg.maxBufferedBytes = lazyI64(n) }}
 }
 
 // RecordPartitioner uses the given partitioner to partition records, overriding
@@ -1013,7 +1349,7 @@ func ProduceRequestTimeout(limit time.Duration) ProducerOpt {
 // This option is different from RequestRetries to allow finer grained control
 // of when to fail when producing records.
 func RecordRetries(n int) ProducerOpt {
-	return producerOpt{func(cfg *cfg) { cfg.recordRetries = int64(n) }}
+	return producerOpt{func(cfg *cfg) { cfg.recordRetries = lazyI64(n) }}
 }
 
 // UnknownTopicRetries sets the number of times a record can fail with
@@ -1068,7 +1404,7 @@ func ProducerOnDataLossDetected(fn func(string, int32)) ProducerOpt {
 // to linger in this case and inefficient because the client will have many
 // timers running (and stopping and restarting) unnecessarily.
 func ProducerLinger(linger time.Duration) ProducerOpt {
-	return producerOpt{func(cfg *cfg) { cfg.linger = linger }}
+	return producerOpt{func(cfg *cfg) { cfg.linger = lazyI64(linger) }}
 }
 
 // ManualFlushing disables auto-flushing when producing. While you can still
@@ -1229,6 +1565,21 @@ func MaxConcurrentFetches(n int) ConsumerOpt {
 	return consumerOpt{func(cfg *cfg) { cfg.maxConcurrentFetches = n }}
 }
 
+// FetchDecodeParallelism sets how many goroutines can decompress and decode
+// the partitions of a single fetch response at once, overriding the default
+// of 1 (partitions are processed one after another on the goroutine reading
+// the response).
+//
+// Each partition is still processed by a single goroutine, so this helps when
+// a fetch response contains large (particularly zstd or gzip compressed)
+// batches across many partitions. Records within a partition remain in
+// order, and partitions are returned in the order the broker responded with.
+// Hooks called while processing partitions, such as HookFetchBatchRead, may
+// be called concurrently.
+func FetchDecodeParallelism(n int) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.decodeParallelism = n }}
+}
+
 // ConsumeResetOffset sets the offset to start consuming from, or if
 // OffsetOutOfRange is seen while fetching, to restart consuming from. The
 // default is NewOffset().AtStart(), i.e., the earliest offset.
@@ -1307,6 +1658,107 @@ func KeepControlRecords() ConsumerOpt {
 	return consumerOpt{func(cfg *cfg) { cfg.keepControl = true }}
 }
 
+// LazyRecordDecoding sets the client to keep fetched record batches
+// compressed until records are iterated, overriding the default that
+// decompresses and decodes every record as soon as a fetch response is
+// received. This lowers peak memory and allocations when consuming large
+// fetches, especially for consumers that only process some records.
+//
+// With this option, the Records field of each FetchPartition is empty. Records
+// must be read through EachRecord or RecordIter on FetchPartition,
+// FetchTopic, or Fetches, each of which decodes one batch at a time as it is
+// reached. Records are decoded every time they are iterated, so iterate once
+// if possible.
+//
+// When a fetch is received, the compression framing of each batch is checked
+// (without decompressing) before the batch can be committed; a batch with
+// invalid framing is handled the same as when not lazily decoding. A batch
+// whose compressed data is corrupt despite a valid CRC and framing is only
+// detected when iterated, and has no records.
+//
+// Because records are not decoded when buffered, a few things change:
+//
+//   - HookFetchRecordBuffered and HookFetchRecordUnbuffered are not called.
+//   - FetchBatchMetrics.UncompressedBytes is the compressed size of a batch.
+//   - BufferedFetchBytes counts compressed bytes, and record counts (including
+//     BufferedFetchRecords and NumRecords) count every record in a batch,
+//     even records before the fetch offset that are skipped when decoding.
+//   - PollRecords returns whole batches, and may return more than the
+//     requested maximum number of records.
+//
+// Transaction markers and aborted transactions are still processed when a
+// fetch is received. Old message set formats (Kafka < 0.11) are always
+// decoded immediately.
+func LazyRecordDecoding() ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.lazyDecode = true }}
+}
+
+// PooledRecords sets the client to allocate fetched records, and the buffers
+// that compressed batches are decompressed into, from pools, overriding the
+// default of allocating new records and buffers for every fetch. This lowers
+// allocations and GC time for high throughput consumers.
+//
+// Pooled memory is only reused once the application returns it, with
+// Fetches.Recycle or Record.Release. The keys, values, and headers of records
+// from compressed batches point into a shared decompression buffer, which is
+// reused once every record from the batch is released. After releasing a
+// record, neither the record nor its key, value, or headers can be used; copy
+// anything that needs to be kept (including records that are produced
+// elsewhere). Records that are never released are garbage collected as
+// normal.
+//
+// Building with the kgo_debug tag enables checks that catch the use of
+// released records: releasing a record twice or committing a released record
+// panics, and the memory of released records is overwritten rather than
+// reused.
+func PooledRecords() ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.pooledRecords = true }}
+}
+
+// ConsumeFilter sets a function that is called for every fetched record as
+// batches are decoded; records for which fn returns false are dropped. Dropped
+// records are never buffered, are not counted in BufferedFetchRecords, and
+// are not returned from polling, but consuming advances past them as if they
+// were polled: offsets for dropped records are committed the same as offsets
+// for polled records. Because of this, polling may return partitions that
+// have no records.
+//
+// The function can be called concurrently for records in different
+// partitions (see FetchDecodeParallelism) and must be fast. The record must
+// not be kept after the function returns false. If consuming with
+// LazyRecordDecoding, the function is called as records are iterated, and
+// dropped records are counted when buffered.
+func ConsumeFilter(fn func(*Record) bool) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.consumeFilter = fn }}
+}
+
+// ConsumeDedup drops records that were already consumed, overriding the
+// default of returning every record. Duplicates are common in at-least-once
+// pipelines: non-idempotent producers that retry can write a record twice,
+// and upstream consumers that rebalance can process a record twice.
+//
+// Every record is identified with the identity function (see DedupByKey,
+// DedupByHeader, and DedupByProducerSequence), and the identities of the most
+// recent window records per partition are tracked. A record is dropped if its
+// identity was seen at a lower offset in the same partition. Consuming the
+// same offset again (after a rebalance or a seek) does not drop the record.
+// Identities are tracked as 64 bit hashes, so there is a tiny chance that
+// distinct records are considered duplicates. The window must be between 1
+// and 1048576.
+//
+// Dropped records are handled as if they were filtered with ConsumeFilter:
+// they are never buffered, and their offsets are committed the same as
+// offsets for polled records. Deduplication runs after any ConsumeFilter, so
+// filtered records are not tracked.
+//
+// Tracked identities are kept in memory for the lifetime of the client. When
+// group consuming, CheckpointDedupInCommits can be used to save identities
+// alongside committed offsets, such that the member that is assigned a
+// partition next continues deduplicating where the prior member left off.
+func ConsumeDedup(identity DedupIdentity, window int) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.dedupIdentity, cfg.dedupWindow = identity, window }}
+}
+
 // ConsumeTopics adds topics to use for consuming.
 //
 // By default, consuming will start at the beginning of partitions. To change
@@ -1347,6 +1799,77 @@ func ConsumeRegex() ConsumerOpt {
 	return consumerOpt{func(cfg *cfg) { cfg.regex = true }}
 }
 
+// ConsumeRegexExclude adds regular expressions for topics to never consume
+// when consuming via regex, even if the topics match a regular expression
+// passed to ConsumeTopics. This can be used to keep internal or temporary
+// topics from being consumed. This option is only valid with ConsumeRegex.
+//
+// As with ConsumeRegex, every topic is evaluated only once ever: a topic
+// that is excluded is permanently excluded, unless it is purged (see
+// PurgeTopicsFromConsuming) and evaluated again.
+func ConsumeRegexExclude(regexes ...string) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) {
+		if cfg.regexExcludes == nil {
+			cfg.regexExcludes = make(map[string]*regexp.Regexp)
+		}
+		for _, re := range regexes {
+			cfg.regexExcludes[re] = nil
+		}
+	}}
+}
+
+// ConsumeRegexFilter sets a function that is called for every topic that
+// matches a regular expression and is not excluded when consuming via regex;
+// topics for which fn returns false are not consumed. This option is only
+// valid with ConsumeRegex.
+//
+// The function is called once per topic, the first time the topic is seen in
+// a metadata response (or again after the topic is purged), while the client
+// is evaluating new topics. The function must not block, and must not call
+// back into the client.
+func ConsumeRegexFilter(fn func(topic string) bool) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.regexFilter = fn }}
+}
+
+// OnTopicsDiscovered sets a function to be called when consuming via regex
+// begins consuming new topics that match the regular expressions. The topics
+// are sorted. Topics that have no partitions yet, and internal topics (which
+// are never consumed via regex), are not passed until they are consumed.
+//
+// The function is called serially, after the client has begun consuming the
+// topics, and can call back into the client (for example, to purge topics).
+// Consuming further new topics is blocked while the function runs.
+func OnTopicsDiscovered(onDiscovered func(*Client, []string)) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.onTopicsDiscovered = onDiscovered }}
+}
+
+// OnTopicsRemoved sets a function to be called when topics that were being
+// consumed via regex are removed from consuming. Topics are removed when
+// they are purged with PurgeTopicsFromConsuming or PurgeTopicsFromClient, or
+// automatically if they are missing from metadata responses for longer than
+// ConsiderMissingTopicDeletedAfter. The topics are sorted.
+//
+// The function is called after the topics are purged, and can call back into
+// the client. If a removed topic is seen again, it is evaluated against the
+// regular expressions again and may be passed to OnTopicsDiscovered.
+func OnTopicsRemoved(onRemoved func(*Client, []string)) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.onTopicsRemoved = onRemoved }}
+}
+
+// FetchConnectionsPerBroker sets the number of connections to open to each
+// broker for fetching, overriding the default of 1. This must be between 1
+// and 64.
+//
+// With more than one connection, partitions are spread across the
+// connections to each broker by hash, and each connection issues its own
+// fetch requests (with its own fetch session). MaxConcurrentFetches limits
+// fetches across all connections, and FetchMaxBytes applies per fetch request,
+// so the total amount buffered can be up to FetchMaxBytes times the number of
+// connections for every broker.
+func FetchConnectionsPerBroker(n int) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.fetchConns = n }}
+}
+
 // DisableFetchSessions sets the client to not use fetch sessions (Kafka 1.0+).
 //
 // A "fetch session" is is a way to reduce bandwidth for fetch requests &
@@ -1397,6 +1920,84 @@ func ConsumePreferringLagFn(fn PreferLagFn) ConsumerOpt {
 	return consumerOpt{func(cfg *cfg) { cfg.preferLagFn = fn }}
 }
 
+// ConsumeTopicPriorities sets per-topic priorities, overriding the default
+// of every topic having priority 0. Higher priorities are more important.
+//
+// Topics with a higher priority are placed first in fetch requests, ahead of
+// any ordering from ConsumePreferringLagFn, so that Kafka fills the response
+// with their records before the FetchMaxBytes limit is hit. When polling,
+// records from higher priority topics are returned first across every
+// buffered fetch. If polling with a limit (PollRecords), higher priority
+// topics are fully drained before any records from lower priority topics
+// are returned, meaning a busy low priority topic cannot starve a high
+// priority topic.
+//
+// Fetches returned from polling are grouped by priority, with one Fetch per
+// priority, highest first.
+func ConsumeTopicPriorities(priorities map[string]int) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.topicPriorities = priorities }}
+}
+
+// ConsumeTopicWeights sets per-topic weights, overriding the default of every
+// topic having weight 1. Weights must be at least 1.
+//
+// When polling with a limit (PollRecords), the limit is shared across topics
+// of the same priority proportionally to their weights: a topic with weight 3
+// is given up to three times as many records as a topic with weight 1. Any
+// share that a topic cannot use because it has fewer records buffered is
+// given to the other topics. Weights have no effect when polling without a
+// limit, since everything buffered is returned.
+func ConsumeTopicWeights(weights map[string]int) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.topicWeights = weights }}
+}
+
+// FairPartitionPolling shares the record limit in PollRecords evenly across
+// buffered partitions of a topic, overriding the default of draining
+// partitions one at a time in the order they were fetched. If the limit is
+// smaller than the number of partitions, the partitions that are given
+// records rotate with every poll. Fetch requests already rotate the order
+// that partitions are requested in (see ConsumePreferringLagFn); with this
+// option, that rotation is also applied to partitions within each topic.
+func FairPartitionPolling() ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) { cfg.fairPartitions = true }}
+}
+
+// ConsumeReplay switches the consumer to replay records at the pace they were
+// originally produced, or at speed times that pace: a speed of 1 replays at
+// the original pace, while a speed of 10 replays ten times faster. This is
+// useful for load tests and simulations.
+//
+// Replaying starts at the first poll. Polling releases buffered records only
+// once they are due: a record is due once the time since replaying started,
+// multiplied by speed, reaches the record's timestamp relative to from.
+// Records are released in timestamp order across partitions, meaning every
+// record returned from one poll has a timestamp at or before every record
+// still buffered. Within a single poll, records are grouped by partition as
+// usual; use PollRecords with a small limit for finer interleaving. If no
+// records are due, polling with a context waits until the next record is due.
+//
+// If from is non-zero, this also sets the reset offset to
+// NewOffset().AfterMilli(from.UnixMilli()), meaning partitions without
+// committed offsets start consuming at from. A ConsumeResetOffset option
+// after this option overrides that. If from is zero, replaying is anchored
+// at the timestamp of the earliest record buffered at the first poll.
+//
+// Records that are not yet due are held in the client's fetch buffers, and a
+// broker's buffer is not fetched into again until it is drained. Replaying
+// is meant for pacing, not throughput. Replaying takes precedence over the
+// limits of ConsumeTopicWeights and FairPartitionPolling.
+//
+// Replaying can be paused and resumed with PauseReplay and ResumeReplay, and
+// the speed can be changed with SetReplaySpeed.
+func ConsumeReplay(from time.Time, speed float64) ConsumerOpt {
+	return consumerOpt{func(cfg *cfg) {
+		cfg.replay, cfg.replayFrom, cfg.replaySpeed = true, from, speed
+		if !from.IsZero() {
+			cfg.resetOffset = NewOffset().AfterMilli(from.UnixMilli())
+		}
+	}}
+}
+
 // KeepRetryableFetchErrors switches the client to always return any retryable
 // broker error when fetching, rather than stripping them. By default, the
 // client strips retryable errors from fetch responses; these are usually
@@ -1507,6 +2108,27 @@ func RequireStableFetchOffsets() GroupOpt {
 	return groupOpt{func(cfg *cfg) { cfg.requireStable = true }}
 }
 
+// CheckpointDedupInCommits saves the identities tracked with ConsumeDedup in
+// the metadata of offset commits, and restores them when offsets are fetched
+// after partitions are assigned. This allows deduplicating records across
+// rebalances and restarts: the member that is assigned a partition next
+// drops duplicates of records that were consumed before the commit.
+//
+// Only the most recent identities that fit in ~3KiB of metadata are saved
+// per partition (a bit over 200 identities), because brokers reject commit
+// metadata that is larger than offset.metadata.max.bytes (4KiB by default).
+//
+// This overwrites the commit metadata that the client otherwise writes: by
+// default, the metadata of every commit is the committing member's ID, which
+// tools use to see which member committed an offset. With this option, the
+// metadata is instead a "kgo.dedup.1:" prefixed encoding of the identities,
+// and the member ID is not recorded anywhere in the commit.
+//
+// Requires ConsumeDedup.
+func CheckpointDedupInCommits() GroupOpt {
+	return groupOpt{func(cfg *cfg) { cfg.dedupCheckpoint = true }}
+}
+
 // BlockRebalanceOnPoll switches the client to block rebalances whenever you
 // poll until you explicitly call AllowRebalance. This option also ensures that
 // any OnPartitions{Assigned,Revoked,Lost} callbacks are only called when you
@@ -222,6 +222,10 @@ type consumer struct {
 	sourcesReadyCond        *sync.Cond
 	sourcesReadyForDraining []*source
 	fakeReadyForDraining    []Fetch
+	pollRobin               int // rotates ties when polling with priorities, weights, or fair partitions; guarded by sourcesReadyMu
+
+	replay *replayer   // non-nil if replaying; see ConsumeReplay
+	dedup  *dedupState // non-nil if deduplicating; see ConsumeDedup
 
 	pollWaitMu    sync.Mutex
 	pollWaitC     *sync.Cond
@@ -324,6 +328,12 @@ func (c *consumer) init(cl *Client) {
 	c.paused.Store(make(pausedTopics))
 	c.sourcesReadyCond = sync.NewCond(&c.sourcesReadyMu)
 	c.pollWaitC = sync.NewCond(&c.pollWaitMu)
+	if cl.cfg.replay {
+		c.replay = newReplayer(&cl.cfg)
+	}
+	if cl.cfg.dedupIdentity != nil {
+		c.dedup = newDedupState(&cl.cfg)
+	}
 
 	if len(cl.cfg.topics) > 0 || len(cl.cfg.partitions) > 0 {
 		defer cl.triggerUpdateMetadataNow("querying metadata for consumer initialization") // we definitely want to trigger a metadata update
@@ -347,6 +357,7 @@ func (c *consumer) addSourceReadyForDraining(source *source) {
 	c.sourcesReadyForDraining = append(c.sourcesReadyForDraining, source)
 	c.sourcesReadyMu.Unlock()
 	c.sourcesReadyCond.Broadcast()
+	c.replay.wakeup()
 }
 
 // addFakeReadyForDraining saves a fake fetch that has important partition
@@ -363,6 +374,7 @@ func (c *consumer) addFakeReadyForDraining(topic string, partition int32, err er
 	}}})
 	c.sourcesReadyMu.Unlock()
 	c.sourcesReadyCond.Broadcast()
+	c.replay.wakeup()
 }
 
 // NewErrFetch returns a fake fetch containing a single empty topic with a
@@ -440,7 +452,10 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 		}
 	}
 
-	var fetches Fetches
+	var (
+		fetches    Fetches
+		replayWait time.Duration
+	)
 	fill := func() {
 		if c.cl.cfg.blockRebalanceOnPoll {
 			c.waitAndAddPoller()
@@ -469,15 +484,19 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 		defer c.mu.Unlock()
 
 		c.sourcesReadyMu.Lock()
-		if maxPollRecords < 0 {
+		if c.replay != nil {
+			fetches, replayWait = c.takeReplay(paused, maxPollRecords)
+		} else if maxPollRecords < 0 {
 			for _, ready := range c.sourcesReadyForDraining {
 				fetches = append(fetches, ready.takeBuffered(paused))
 			}
 			c.sourcesReadyForDraining = nil
+		} else if c.cl.cfg.prioritizedPolling() {
+			fetches = c.takePrioritized(paused, maxPollRecords)
 		} else {
 			for len(c.sourcesReadyForDraining) > 0 && maxPollRecords > 0 {
 				source := c.sourcesReadyForDraining[0]
-				fetch, taken, drained := source.takeNBuffered(paused, maxPollRecords)
+				fetch, taken, drained := source.takeNBuffered(paused, maxPollRecords, nil)
 				if drained {
 					c.sourcesReadyForDraining = c.sourcesReadyForDraining[1:]
 				}
@@ -485,6 +504,7 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 				fetches = append(fetches, fetch)
 			}
 		}
+		fetches = c.cl.cfg.prioritizeFetches(fetches)
 
 		realFetches := fetches
 
@@ -518,6 +538,18 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 		return fetches
 	}
 
+	// When replaying, buffered records may not yet be due. We wait until
+	// the next record is due or until something changes, and try again.
+	for c.replay != nil {
+		if err := c.replay.wait(cl.ctx, ctx, replayWait); err != nil {
+			return NewErrFetch(err)
+		}
+		fill()
+		if len(fetches) > 0 {
+			return fetches
+		}
+	}
+
 	done := make(chan struct{})
 	quit := false
 	go func() {
@@ -551,6 +583,210 @@ func (cl *Client) PollRecords(ctx context.Context, maxPollRecords int) Fetches {
 	return fetches
 }
 
+func (cfg *cfg) prioritizedPolling() bool {
+	return len(cfg.topicPriorities) > 0 || len(cfg.topicWeights) > 0 || cfg.fairPartitions
+}
+
+// takePrioritized takes up to n records across every source ready for
+// draining. The limit is given to topics in priority order, shared across
+// topics of the same priority by weight, and shared across partitions of a
+// topic if polling fairly (otherwise, partitions are filled in order).
+//
+// This must be called with sourcesReadyMu held.
+func (c *consumer) takePrioritized(paused pausedTopics, n int) Fetches {
+	cfg := &c.cl.cfg
+	c.pollRobin++
+
+	type buffered struct {
+		partitions []int32
+		have       []int
+	}
+	var torder []string
+	topics := make(map[string]*buffered)
+	for _, s := range c.sourcesReadyForDraining {
+		for _, t := range s.buffered.fetch.Topics {
+			for _, p := range t.Partitions {
+				if paused.has(t.Topic, p.Partition) {
+					continue
+				}
+				b := topics[t.Topic]
+				if b == nil {
+					b = new(buffered)
+					topics[t.Topic] = b
+					torder = append(torder, t.Topic)
+				}
+				b.partitions = append(b.partitions, p.Partition)
+				b.have = append(b.have, p.numRecords())
+			}
+		}
+	}
+	sort.SliceStable(torder, func(i, j int) bool {
+		return cfg.topicPriorities[torder[i]] > cfg.topicPriorities[torder[j]]
+	})
+
+	allot := make(map[string]map[int32]int, len(torder))
+	left := n
+	for i := 0; i < len(torder) && left > 0; {
+		prio := cfg.topicPriorities[torder[i]]
+		j := i
+		for j < len(torder) && cfg.topicPriorities[torder[j]] == prio {
+			j++
+		}
+		level := torder[i:j]
+		i = j
+
+		weights := make([]int, len(level))
+		have := make([]int, len(level))
+		for k, t := range level {
+			weights[k] = 1
+			if w, ok := cfg.topicWeights[t]; ok {
+				weights[k] = w
+			}
+			for _, h := range topics[t].have {
+				have[k] += h
+			}
+		}
+
+		for k, give := range shareRecords(left, weights, have, c.pollRobin) {
+			left -= give
+			b := topics[level[k]]
+			var gives []int
+			if cfg.fairPartitions {
+				ones := make([]int, len(b.partitions))
+				for i := range ones {
+					ones[i] = 1
+				}
+				gives = shareRecords(give, ones, b.have, c.pollRobin)
+			} else {
+				gives = make([]int, len(b.partitions))
+				for i, h := range b.have {
+					if h > give {
+						h = give
+					}
+					gives[i] = h
+					give -= h
+				}
+			}
+			tallot := make(map[int32]int, len(gives))
+			for i, give := range gives {
+				tallot[b.partitions[i]] = give
+			}
+			allot[level[k]] = tallot
+		}
+	}
+
+	return c.takeLimited(paused, n, func(t string, p int32) int { return allot[t][p] })
+}
+
+// takeLimited takes up to n records across every source ready for draining,
+// limiting the records taken per partition (see takeNBuffered). Sources that
+// are completely drained are no longer ready for draining.
+//
+// This must be called with sourcesReadyMu held.
+func (c *consumer) takeLimited(paused pausedTopics, n int, limit func(string, int32) int) Fetches {
+	var fetches Fetches
+	keep := c.sourcesReadyForDraining[:0]
+	for _, s := range c.sourcesReadyForDraining {
+		if n <= 0 {
+			keep = append(keep, s)
+			continue
+		}
+		fetch, taken, drained := s.takeNBuffered(paused, n, limit)
+		n -= taken
+		if !drained {
+			keep = append(keep, s)
+		}
+		if len(fetch.Topics) > 0 {
+			fetches = append(fetches, fetch)
+		}
+	}
+	c.sourcesReadyForDraining = keep
+	return fetches
+}
+
+// shareRecords shares n across len(weights) participants proportionally to
+// their weights, never giving any participant more than it has. Any share a
+// participant cannot use is given to the others. If n is too small to give
+// every participant something, the participants that are given one record
+// start at the rotate'th participant.
+func shareRecords(n int, weights, have []int, rotate int) []int {
+	gives := make([]int, len(weights))
+	for n > 0 {
+		var total int
+		for i, w := range weights {
+			if gives[i] < have[i] {
+				total += w
+			}
+		}
+		if total == 0 {
+			break
+		}
+
+		var gave int
+		for i, w := range weights {
+			if more := have[i] - gives[i]; more > 0 {
+				share := n * w / total
+				if share > more {
+					share = more
+				}
+				gives[i] += share
+				gave += share
+			}
+		}
+		n -= gave
+
+		if gave == 0 {
+			for i := 0; i < len(weights) && n > 0; i++ {
+				idx := (i + rotate) % len(weights)
+				if gives[idx] < have[idx] {
+					gives[idx]++
+					n--
+				}
+			}
+		}
+	}
+	return gives
+}
+
+// prioritizeFetches regroups fetches into one fetch per topic priority,
+// highest priority first, if any topic priorities are configured.
+func (cfg *cfg) prioritizeFetches(fs Fetches) Fetches {
+	if len(cfg.topicPriorities) == 0 || len(fs) == 0 {
+		return fs
+	}
+	var prios []int
+	byPrio := make(map[int]*Fetch)
+	for _, f := range fs {
+		for _, t := range f.Topics {
+			prio := cfg.topicPriorities[t.Topic]
+			pf := byPrio[prio]
+			if pf == nil {
+				pf = new(Fetch)
+				byPrio[prio] = pf
+				prios = append(prios, prio)
+			}
+			merged := false
+			for i := range pf.Topics {
+				if pf.Topics[i].Topic == t.Topic {
+					pf.Topics[i].Partitions = append(pf.Topics[i].Partitions, t.Partitions...)
+					merged = true
+					break
+				}
+			}
+			if !merged {
+				t.Partitions = t.Partitions[:len(t.Partitions):len(t.Partitions)] // appending to a merged topic must not clobber the source's slice
+				pf.Topics = append(pf.Topics, t)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(prios)))
+	prioritized := make(Fetches, 0, len(prios))
+	for _, prio := range prios {
+		prioritized = append(prioritized, *byPrio[prio])
+	}
+	return prioritized
+}
+
 // AllowRebalance allows a consumer group to rebalance if it was blocked by you
 // polling records in tandem with the BlockRebalanceOnPoll option.
 //
@@ -568,6 +804,8 @@ func (cl *Client) AllowRebalance() {
 // and the max partition bytes that a fetch request will ask for each
 // partition.
 func (cl *Client) UpdateFetchMaxBytes(maxBytes, maxPartBytes int32) {
+	cl.reconfigureMu.Lock()
+	defer cl.reconfigureMu.Unlock()
 	cl.cfg.maxBytes.store(maxBytes)
 	cl.cfg.maxPartBytes.store(maxPartBytes)
 }
@@ -622,8 +860,8 @@ func (cl *Client) PauseFetchPartitions(topicPartitions map[string][]int32) map[s
 // paused. Resuming topics that are not currently paused is a per-topic no-op.
 // See the documentation on PauseTfetchTopics for more details.
 func (cl *Client) ResumeFetchTopics(topics ...string) {
-	defer cl.allSinksAndSources(func(sns sinkAndSource) {
-		sns.source.maybeConsume()
+	defer cl.allSources(func(s *source) {
+		s.maybeConsume()
 	})
 
 	c := &cl.consumer
@@ -640,8 +878,8 @@ func (cl *Client) ResumeFetchTopics(topics ...string) {
 // per-topic no-op. See the documentation on PauseFetchPartitions for more
 // details.
 func (cl *Client) ResumeFetchPartitions(topicPartitions map[string][]int32) {
-	defer cl.allSinksAndSources(func(sns sinkAndSource) {
-		sns.source.maybeConsume()
+	defer cl.allSources(func(s *source) {
+		s.maybeConsume()
 	})
 
 	c := &cl.consumer
@@ -706,12 +944,50 @@ func (cl *Client) setOffsets(setOffsets map[string]map[int32]EpochOffset, log bo
 	}
 }
 
+// regexWants evaluates a topic that has not yet been seen when consuming via
+// regex: the topic is wanted if it matches any regular expression, does not
+// match any exclusion, and is not filtered.
+func (cfg *cfg) regexWants(topic string, rns *reNews) bool {
+	for rawRe, re := range cfg.topics {
+		if !re.MatchString(topic) {
+			continue
+		}
+		for _, exclude := range cfg.regexExcludes {
+			if exclude.MatchString(topic) {
+				rns.skip(topic)
+				return false
+			}
+		}
+		if cfg.regexFilter != nil && !cfg.regexFilter(topic) {
+			rns.skip(topic)
+			return false
+		}
+		rns.add(rawRe, topic)
+		return true
+	}
+	rns.skip(topic)
+	return false
+}
+
+// onTopicsChanged calls fn, which is either OnTopicsDiscovered or
+// OnTopicsRemoved, if there are any topics.
+func (c *consumer) onTopicsChanged(fn func(*Client, []string), topics []string) {
+	if fn == nil || len(topics) == 0 {
+		return
+	}
+	sort.Strings(topics)
+	fn(c.cl, topics)
+}
+
 // This is guaranteed to be called in a blocking metadata fn, which ensures
 // that metadata does not load the tps we are changing. Basically, we ensure
 // everything w.r.t. consuming is at a stand still.
-func (c *consumer) purgeTopics(topics []string) {
+//
+// This returns the purged topics that were being consumed via regex, which
+// the caller passes to OnTopicsRemoved outside of the metadata fn.
+func (c *consumer) purgeTopics(topics []string) (removed []string) {
 	if c.g == nil && c.d == nil {
-		return
+		return nil
 	}
 
 	purgeAssignments := make(map[string]map[int32]Offset, len(topics))
@@ -732,6 +1008,9 @@ func (c *consumer) purgeTopics(topics []string) {
 		defer c.g.mu.Unlock()
 		c.assignPartitions(purgeAssignments, assignPurgeMatching, c.g.tps, fmt.Sprintf("purge of %v requested", topics))
 		for _, topic := range topics {
+			if _, using := c.g.using[topic]; using && c.cl.cfg.regex {
+				removed = append(removed, topic)
+			}
 			delete(c.g.using, topic)
 			delete(c.g.reSeen, topic)
 		}
@@ -739,11 +1018,15 @@ func (c *consumer) purgeTopics(topics []string) {
 	} else {
 		c.assignPartitions(purgeAssignments, assignPurgeMatching, c.d.tps, fmt.Sprintf("purge of %v requested", topics))
 		for _, topic := range topics {
+			if _, using := c.d.using[topic]; using && c.cl.cfg.regex {
+				removed = append(removed, topic)
+			}
 			delete(c.d.using, topic)
 			delete(c.d.reSeen, topic)
 			delete(c.d.m, topic)
 		}
 	}
+	return removed
 }
 
 // AddConsumeTopics adds new topics to be consumed. This function is a no-op if
@@ -1195,6 +1478,11 @@ func (c *consumer) doOnMetadataUpdate() {
 	// block below.
 	if c.outstandingMetadataUpdates.maybeBegin() {
 		doUpdate := func() {
+			// We call OnTopicsDiscovered after unlocking below, so
+			// that the user can modify consuming in the callback.
+			var rns reNews
+			defer func() { c.onTopicsChanged(c.cl.cfg.onTopicsDiscovered, rns.discovered) }()
+
 			// We forbid reassignments while we do a quick check for
 			// new assignments--for the direct consumer particularly,
 			// this prevents TOCTOU, and guards against a concurrent
@@ -1204,11 +1492,11 @@ func (c *consumer) doOnMetadataUpdate() {
 
 			switch {
 			case c.d != nil:
-				if new := c.d.findNewAssignments(); len(new) > 0 {
+				if new := c.d.findNewAssignments(&rns); len(new) > 0 {
 					c.assignPartitions(new, assignWithoutInvalidating, c.d.tps, "new assignments from direct consumer")
 				}
 			case c.g != nil:
-				c.g.findNewAssignments()
+				c.g.findNewAssignments(&rns)
 			}
 
 			go c.loadSession().doOnMetadataUpdate()
@@ -1631,8 +1919,8 @@ func (c *consumer) stopSession() (listOrEpochLoads, *topicsPartitions) {
 	// our num-fetches manager without worrying about a source trying to
 	// register itself.
 
-	c.cl.allSinksAndSources(func(sns sinkAndSource) {
-		sns.source.session.reset()
+	c.cl.allSources(func(s *source) {
+		s.session.reset()
 	})
 
 	// At this point, if we begin fetching anew, then the sources will not
@@ -1675,8 +1963,8 @@ func (c *consumer) startNewSession(tps *topicsPartitions) *consumerSession {
 
 	c.sessionChangeMu.Unlock()
 
-	c.cl.allSinksAndSources(func(sns sinkAndSource) {
-		sns.source.maybeConsume()
+	c.cl.allSources(func(s *source) {
+		s.maybeConsume()
 	})
 
 	// At this point, any source that was not consuming becauase it saw the
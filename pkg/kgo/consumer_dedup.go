@@ -0,0 +1,237 @@
+package kgo
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// DedupIdentity returns the identity of a record for consumer side
+// deduplication (see ConsumeDedup), and false if the record has no identity
+// and should never be dropped as a duplicate. The function is called
+// concurrently for records in different partitions. The returned slice is
+// hashed immediately and not retained.
+type DedupIdentity func(*Record) ([]byte, bool)
+
+// DedupByKey returns a DedupIdentity that identifies records by their keys.
+// Records with nil keys are not deduplicated.
+func DedupByKey() DedupIdentity {
+	return func(r *Record) ([]byte, bool) {
+		return r.Key, r.Key != nil
+	}
+}
+
+// DedupByHeader returns a DedupIdentity that identifies records by the value
+// of the first header with the given key. Records without the header are not
+// deduplicated.
+func DedupByHeader(key string) DedupIdentity {
+	return func(r *Record) ([]byte, bool) {
+		for _, h := range r.Headers {
+			if h.Key == key {
+				return h.Value, true
+			}
+		}
+		return nil, false
+	}
+}
+
+// DedupByProducerSequence returns a DedupIdentity that identifies records by
+// the producer ID, producer epoch, and sequence number that an idempotent
+// producer wrote the record with. Brokers normally reject these duplicates,
+// so this only drops records that were written more than once within one
+// producer session, which can happen if a broker loses the producer's
+// idempotency state while the producer is retrying (for example, because
+// the producer ID expired or after an unclean leader election), or if a
+// pipeline copies batches with their producer fields intact.
+//
+// Every new producer session writes with a new producer ID or epoch, so this
+// does not drop records that are produced again after a producer restarts
+// or bumps its epoch; deduplicating those requires an identity that the
+// application assigns, with DedupByKey or DedupByHeader. Records from
+// producers that are not idempotent are not deduplicated.
+func DedupByProducerSequence() DedupIdentity {
+	return func(r *Record) ([]byte, bool) {
+		if r.ProducerID < 0 || r.sequence < 0 {
+			return nil, false
+		}
+		id := make([]byte, 0, 14)
+		id = binary.BigEndian.AppendUint64(id, uint64(r.ProducerID))
+		id = binary.BigEndian.AppendUint16(id, uint16(r.ProducerEpoch))
+		id = binary.BigEndian.AppendUint32(id, uint32(r.sequence))
+		return id, true
+	}
+}
+
+// dedupMetadataPrefix prefixes offset commit metadata that contains a
+// checkpointed dedup window.
+const dedupMetadataPrefix = "kgo.dedup.1:"
+
+// dedupCheckpointMaxBytes bounds the encoded entries checkpointed per
+// partition. Brokers reject commit metadata over offset.metadata.max.bytes,
+// which defaults to 4096; base64 expands 3 bytes to 4.
+const dedupCheckpointMaxBytes = 3000
+
+// dedupState tracks a bounded window of record identities per partition.
+type dedupState struct {
+	identity DedupIdentity
+	size     int
+
+	mu      sync.Mutex
+	windows map[string]map[int32]*dedupWindow
+}
+
+func newDedupState(cfg *cfg) *dedupState {
+	return &dedupState{
+		identity: cfg.dedupIdentity,
+		size:     cfg.dedupWindow,
+		windows:  make(map[string]map[int32]*dedupWindow),
+	}
+}
+
+// window returns the window for a partition, creating it if necessary. The
+// window is kept for the lifetime of the client, which allows deduplicating
+// records that are consumed again after a partition moves away and back.
+func (d *dedupState) window(topic string, partition int32) *dedupWindow {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	t := d.windows[topic]
+	if t == nil {
+		t = make(map[int32]*dedupWindow)
+		d.windows[topic] = t
+	}
+	w := t[partition]
+	if w == nil {
+		w = &dedupWindow{
+			identity: d.identity,
+			max:      d.size,
+			seen:     make(map[uint64]int64),
+		}
+		t[partition] = w
+	}
+	return w
+}
+
+// dedupWindow tracks the identities of the most recent records in a
+// partition, and the lowest offset each identity was seen at.
+type dedupWindow struct {
+	identity DedupIdentity
+	max      int
+
+	mu   sync.Mutex
+	seen map[uint64]int64
+	ring []uint64 // identities in the order they were added, for eviction
+	at   int      // once the ring is full, the oldest identity
+}
+
+func dedupHash(id []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(id)
+	return h.Sum64()
+}
+
+// keep returns false if the record is a duplicate: its identity was seen at
+// a lower offset. A record that is consumed again at the same offset (after
+// a rebalance or a seek) is not a duplicate.
+func (w *dedupWindow) keep(r *Record) bool {
+	id, ok := w.identity(r)
+	if !ok {
+		return true
+	}
+	h := dedupHash(id)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.addLocked(h, r.Offset)
+	return w.seen[h] >= r.Offset
+}
+
+// addLocked tracks that an identity was seen at offset, keeping the lowest
+// offset if the identity is already tracked.
+func (w *dedupWindow) addLocked(h uint64, offset int64) {
+	if seenAt, ok := w.seen[h]; ok {
+		if offset < seenAt {
+			w.seen[h] = offset
+		}
+		return
+	}
+	if len(w.ring) < w.max {
+		w.ring = append(w.ring, h)
+	} else {
+		delete(w.seen, w.ring[w.at])
+		w.ring[w.at] = h
+		w.at = (w.at + 1) % w.max
+	}
+	w.seen[h] = offset
+}
+
+// checkpoint encodes the most recent identities in the window as offset
+// commit metadata, relative to the offset being committed.
+func (w *dedupWindow) checkpoint(committed int64) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// We encode newest first, so that if we hit our size limit, we have
+	// kept the most recent identities.
+	var raw []byte
+	for i := 0; i < len(w.ring) && len(raw) < dedupCheckpointMaxBytes; i++ {
+		idx := w.at - 1 - i
+		if len(w.ring) < w.max {
+			idx = len(w.ring) - 1 - i
+		}
+		if idx < 0 {
+			idx += len(w.ring)
+		}
+		h := w.ring[idx]
+		raw = binary.BigEndian.AppendUint64(raw, h)
+		raw = binary.AppendVarint(raw, w.seen[h]-committed)
+	}
+	return dedupMetadataPrefix + base64.RawStdEncoding.EncodeToString(raw)
+}
+
+// restore adds identities from offset commit metadata that was written by
+// checkpoint. Metadata that is not a checkpoint is ignored.
+func (w *dedupWindow) restore(committed int64, metadata string) {
+	if !strings.HasPrefix(metadata, dedupMetadataPrefix) {
+		return
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(metadata[len(dedupMetadataPrefix):])
+	if err != nil {
+		return
+	}
+	type entry struct {
+		h      uint64
+		offset int64
+	}
+	var entries []entry
+	for len(raw) >= 8 {
+		h := binary.BigEndian.Uint64(raw)
+		delta, n := binary.Varint(raw[8:])
+		if n <= 0 {
+			return
+		}
+		raw = raw[8+n:]
+		entries = append(entries, entry{h, committed + delta})
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i := len(entries) - 1; i >= 0; i-- { // oldest first, so that the newest are evicted last
+		w.addLocked(entries[i].h, entries[i].offset)
+	}
+}
+
+// cursorFilter returns the record filter for a partition, combining
+// ConsumeFilter and ConsumeDedup.
+func (c *consumer) cursorFilter(topic string, partition int32) func(*Record) bool {
+	filter := c.cl.cfg.consumeFilter
+	if c.dedup == nil {
+		return filter
+	}
+	w := c.dedup.window(topic, partition)
+	if filter == nil {
+		return w.keep
+	}
+	return func(r *Record) bool { return filter(r) && w.keep(r) }
+}
@@ -0,0 +1,93 @@
+package kgo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestDedupWindow(t *testing.T) {
+	t.Parallel()
+
+	d := newDedupState(&cfg{dedupIdentity: DedupByKey(), dedupWindow: 2})
+	w := d.window("foo", 0)
+	if d.window("foo", 0) != w {
+		t.Fatal("expected the same window for the same partition")
+	}
+
+	rec := func(key string, offset int64) *Record {
+		r := &Record{Offset: offset}
+		if key != "" {
+			r.Key = []byte(key)
+		}
+		return r
+	}
+	for i, test := range []struct {
+		r    *Record
+		keep bool
+	}{
+		{rec("a", 0), true},
+		{rec("a", 0), true},  // consumed again at the same offset
+		{rec("a", 1), false}, // duplicate
+		{rec("", 2), true},   // no identity
+		{rec("", 3), true},
+		{rec("b", 4), true},
+		{rec("c", 5), true},  // evicts a
+		{rec("a", 6), true},  // a was evicted; evicts b
+		{rec("c", 7), false}, // c is still in the window
+	} {
+		if got := w.keep(test.r); got != test.keep {
+			t.Errorf("#%d: got keep %v, expected %v", i, got, test.keep)
+		}
+	}
+
+	// A checkpoint restores into a new window, relative to the committed
+	// offset.
+	restored := newDedupState(&cfg{dedupIdentity: DedupByKey(), dedupWindow: 2}).window("foo", 0)
+	restored.restore(8, w.checkpoint(8))
+	restored.restore(8, "member-id") // not a checkpoint, ignored
+	if !reflect.DeepEqual(restored.seen, w.seen) {
+		t.Errorf("got restored %v, expected %v", restored.seen, w.seen)
+	}
+	if restored.keep(rec("a", 9)) || !restored.keep(rec("c", 5)) {
+		t.Error("restored window did not deduplicate as expected")
+	}
+}
+
+func TestConsumeDedup(t *testing.T) {
+	t.Parallel()
+
+	cl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		ConsumeDedup(func(r *Record) ([]byte, bool) { return []byte{r.Value[0] % 3}, true }, 10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	rp := &kmsg.FetchResponseTopicPartition{
+		Partition:     0,
+		HighWatermark: 10,
+		RecordBatches: append(fetchedBatch(t, 0, 5, false), fetchedBatch(t, 5, 5, true)...),
+	}
+	o := &cursorOffsetNext{
+		cursorOffset: cursorOffset{offset: 0, lastConsumedEpoch: -1},
+		from:         &cursor{topic: "foo", filter: cl.consumer.cursorFilter("foo", 0)},
+	}
+	fp := o.processRespPartition(&broker{}, rp, newDecompressor(), nil, nil, false)
+
+	var got []string
+	fp.EachRecord(func(r *Record) { got = append(got, string(r.Value)) })
+	if exp := []string{"0", "1", "2"}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("got values %v, expected %v", got, exp)
+	}
+	if offset, _, _ := fp.lastConsumed(); offset != 9 {
+		t.Errorf("got last consumed offset %d, expected 9", offset)
+	}
+
+	if _, err := NewClient(SeedBrokers("127.0.0.1:1"), CheckpointDedupInCommits()); err == nil {
+		t.Error("expected error checkpointing dedup without ConsumeDedup")
+	}
+}
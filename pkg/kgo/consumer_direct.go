@@ -62,10 +62,11 @@ func (*directConsumer) getSetAssigns(setOffsets map[string]map[int32]EpochOffset
 
 // findNewAssignments returns new partitions to consume at given offsets
 // based off the current topics.
-func (d *directConsumer) findNewAssignments() map[string]map[int32]Offset {
+//
+// Topics newly consumed via regex are added to rns.
+func (d *directConsumer) findNewAssignments(rns *reNews) map[string]map[int32]Offset {
 	topics := d.tps.load()
 
-	var rns reNews
 	if d.cfg.regex {
 		defer rns.log(d.cfg)
 	}
@@ -76,15 +77,7 @@ func (d *directConsumer) findNewAssignments() map[string]map[int32]Offset {
 		if d.cfg.regex {
 			want, seen := d.reSeen[topic]
 			if !seen {
-				for rawRe, re := range d.cfg.topics {
-					if want = re.MatchString(topic); want {
-						rns.add(rawRe, topic)
-						break
-					}
-				}
-				if !want {
-					rns.skip(topic)
-				}
+				want = d.cfg.regexWants(topic, rns)
 				d.reSeen[topic] = want
 			}
 			useTopic = want
@@ -107,6 +100,9 @@ func (d *directConsumer) findNewAssignments() map[string]map[int32]Offset {
 				toUseTopic[int32(partition)] = d.cfg.resetOffset
 			}
 			toUse[topic] = toUseTopic
+			if _, using := d.using[topic]; d.cfg.regex && !using {
+				rns.discover(topic)
+			}
 		}
 
 		// Lastly, if this topic has some specific partitions pinned,
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
 	"sort"
 	"sync/atomic"
 	"testing"
@@ -534,3 +535,57 @@ func TestIssue648(t *testing.T) {
 		t.Errorf("did not see ErrUnknownTopicOrPartition")
 	}
 }
+
+func TestRegexExcludeFilterAndCallbacks(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewClient(SeedBrokers("127.0.0.1:1"), ConsumeTopics("foo"), ConsumeRegexExclude("bar")); err == nil {
+		t.Error("expected error using regex exclusions without consuming as regex")
+	}
+
+	removedC := make(chan []string, 1)
+	cl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		ConsumeTopics("foo.*"),
+		ConsumeRegex(),
+		ConsumeRegexExclude(`^foo\.tmp`),
+		ConsumeRegexFilter(func(topic string) bool { return topic != "foo.filtered" }),
+		OnTopicsRemoved(func(_ *Client, topics []string) { removedC <- topics }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	var rns reNews
+	for topic, exp := range map[string]bool{
+		"foo.a":        true,
+		"foo.tmp.a":    false,
+		"foo.filtered": false,
+		"bar":          false,
+	} {
+		if got := cl.cfg.regexWants(topic, &rns); got != exp {
+			t.Errorf("topic %s: got wanted %v, expected %v", topic, got, exp)
+		}
+	}
+
+	// Purging a topic that was consumed via regex calls OnTopicsRemoved.
+	d := cl.consumer.d
+	cl.consumer.mu.Lock()
+	d.using.add("foo.a", 0)
+	d.reSeen["foo.a"] = true
+	cl.consumer.mu.Unlock()
+	cl.PurgeTopicsFromConsuming("foo.a", "foo.unused")
+
+	select {
+	case removed := <-removedC:
+		if exp := []string{"foo.a"}; !reflect.DeepEqual(removed, exp) {
+			t.Errorf("got removed topics %v, expected %v", removed, exp)
+		}
+	default:
+		t.Error("OnTopicsRemoved was not called")
+	}
+	if _, seen := d.reSeen["foo.a"]; seen {
+		t.Error("purged topic is still marked as seen")
+	}
+}
@@ -102,6 +102,13 @@ type groupConsumer struct {
 	// IllegalGeneration errors while cooperative consuming.
 	noCommitDuringJoinAndSync sync.RWMutex
 
+	// The following are set at the start of join&sync and are only read
+	// in the manage loop (or in the offset fetch that the manage loop
+	// waits on) for HookGroupRebalance.
+	rebalanceStart    time.Time
+	rebalanceWhy      string
+	rebalanceProtocol string
+
 	//////////////
 	// mu block //
 	//////////////
@@ -468,7 +475,7 @@ func (g *groupConsumer) manage() {
 		// Waiting for the backoff is a good time to update our
 		// metadata; maybe the error is from stale metadata.
 		consecutiveErrors++
-		backoff := g.cfg.retryBackoff(consecutiveErrors)
+		backoff := g.cl.loadDyn().retryBackoff(consecutiveErrors)
 		g.cfg.logger.Log(LogLevelError, "join and sync loop errored",
 			"group", g.cfg.group,
 			"err", err,
@@ -838,6 +845,10 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() (string, error) {
 	g.lastAssigned = g.nowAssigned.clone() // now that we are done with our last assignment, update it per the new assignment
 
 	g.cfg.logger.Log(LogLevelInfo, "new group session begun", "group", g.cfg.group, "added", mtps(added), "lost", mtps(lost))
+
+	// If cooperative, added may be expanded below; we keep what is newly
+	// assigned for HookGroupRebalance.
+	assigned, assignStart := added, time.Now()
 	s.prerevoke(g, lost) // for cooperative consumers
 
 	// Since we have joined the group, we immediately begin heartbeating.
@@ -891,14 +902,32 @@ func (g *groupConsumer) setupAssignedAndHeartbeat() (string, error) {
 	}
 
 	<-s.assignDone
+	g.onRebalance(GroupRebalanceMetrics{
+		Stage:         GroupRebalanceAssigned,
+		Assigned:      assigned,
+		Revoked:       lost,
+		StageDuration: time.Since(assignStart),
+	})
 
 	if len(added) > 0 {
 		go func() {
 			defer close(fetchDone)
 			defer close(fetchErrCh)
-			fetchErrCh <- g.fetchOffsets(ctx, added)
+			fetchStart := time.Now()
+			err := g.fetchOffsets(ctx, added)
+			if err == nil {
+				g.onRebalance(GroupRebalanceMetrics{
+					Stage:         GroupRebalanceOffsetsFetched,
+					Assigned:      assigned,
+					Revoked:       lost,
+					StageDuration: time.Since(fetchStart),
+				})
+				g.onRebalance(GroupRebalanceMetrics{Stage: GroupRebalanceComplete, Assigned: assigned, Revoked: lost})
+			}
+			fetchErrCh <- err
 		}()
 	} else {
+		g.onRebalance(GroupRebalanceMetrics{Stage: GroupRebalanceComplete, Revoked: lost})
 		close(fetchDone)
 		close(fetchErrCh)
 	}
@@ -1071,6 +1100,22 @@ func (cl *Client) ForceRebalance() {
 	}
 }
 
+// onRebalance fills in the group's current state and calls any
+// HookGroupRebalance hooks.
+func (g *groupConsumer) onRebalance(m GroupRebalanceMetrics) {
+	m.Group = g.cfg.group
+	m.MemberID, m.Generation = g.memberGen.load()
+	m.Protocol = g.rebalanceProtocol
+	m.Leader = g.leader.Load()
+	m.Reason = g.rebalanceWhy
+	m.RebalanceDuration = time.Since(g.rebalanceStart)
+	g.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookGroupRebalance); ok {
+			h.OnGroupRebalance(m)
+		}
+	})
+}
+
 // rejoin is called after a cooperative member revokes what it lost at the
 // beginning of a session, or if we are leader and detect new partitions to
 // consume.
@@ -1092,6 +1137,10 @@ func (g *groupConsumer) joinAndSync(joinWhy string) error {
 	g.cfg.logger.Log(LogLevelInfo, "joining group", "group", g.cfg.group)
 	g.leader.Store(false)
 	g.getAndResetExternalRejoin()
+	g.rebalanceStart = time.Now()
+	g.rebalanceWhy = joinWhy
+	g.rebalanceProtocol = ""
+	g.onRebalance(GroupRebalanceMetrics{Stage: GroupRebalanceStart})
 	defer func() {
 		// If we are not leader, we clear any tracking of external
 		// topics from when we were previously leader, since tracking
@@ -1132,6 +1181,7 @@ start:
 	// and then our final commit will receive either REBALANCE_IN_PROGRESS
 	// or ILLEGAL_GENERATION.
 
+	joinStart := time.Now()
 	go func() {
 		defer close(joined)
 		joinResp, err = joinReq.RequestWith(g.cl.ctx, g.cl)
@@ -1154,6 +1204,8 @@ start:
 		g.cfg.logger.Log(LogLevelWarn, "join group failed", "group", g.cfg.group, "err", err)
 		return err
 	}
+	g.rebalanceProtocol = protocol
+	g.onRebalance(GroupRebalanceMetrics{Stage: GroupRebalanceJoined, StageDuration: time.Since(joinStart)})
 
 	syncReq := kmsg.NewPtrSyncGroupRequest()
 	syncReq.Group = g.cfg.group
@@ -1172,6 +1224,7 @@ start:
 	)
 
 	g.cfg.logger.Log(LogLevelInfo, "syncing", "group", g.cfg.group, "protocol_type", g.cfg.protocol, "protocol", protocol)
+	syncStart := time.Now()
 	go func() {
 		defer close(synced)
 		syncResp, err = syncReq.RequestWith(g.cl.ctx, g.cl)
@@ -1194,6 +1247,7 @@ start:
 		g.cfg.logger.Log(LogLevelWarn, "sync group failed", "group", g.cfg.group, "err", err)
 		return err
 	}
+	g.onRebalance(GroupRebalanceMetrics{Stage: GroupRebalanceSynced, StageDuration: time.Since(syncStart)})
 
 	// KIP-814 fixes one limitation with KIP-345, but has another
 	// fundamental limitation. When an instance ID leader restarts, its
@@ -1625,6 +1679,8 @@ start:
 			}
 			if rPartition.Offset == -1 {
 				offset = g.cfg.resetOffset
+			} else if g.cfg.dedupCheckpoint && rPartition.Metadata != nil {
+				g.cl.consumer.dedup.window(rTopic.Topic, rPartition.Partition).restore(rPartition.Offset, *rPartition.Metadata)
 			}
 			topicOffsets[rPartition.Partition] = offset
 		}
@@ -1706,7 +1762,9 @@ start:
 //
 // This does not rejoin if the leader notices a partition is lost, which is
 // finicky.
-func (g *groupConsumer) findNewAssignments() {
+//
+// Topics newly consumed via regex are added to rns.
+func (g *groupConsumer) findNewAssignments(rns *reNews) {
 	topics := g.tps.load()
 
 	type change struct {
@@ -1714,7 +1772,6 @@ func (g *groupConsumer) findNewAssignments() {
 		delta int
 	}
 
-	var rns reNews
 	if g.cfg.regex {
 		defer rns.log(&g.cl.cfg)
 	}
@@ -1743,15 +1800,7 @@ func (g *groupConsumer) findNewAssignments() {
 		if g.cfg.regex {
 			want, seen := g.reSeen[topic]
 			if !seen {
-				for rawRe, re := range g.cfg.topics {
-					if want = re.MatchString(topic); want {
-						rns.add(rawRe, topic)
-						break
-					}
-				}
-				if !want {
-					rns.skip(topic)
-				}
+				want = g.cfg.regexWants(topic, rns)
 				g.reSeen[topic] = want
 			}
 			useTopic = want
@@ -1767,6 +1816,9 @@ func (g *groupConsumer) findNewAssignments() {
 			}
 			toChange[topic] = change{isNew: true, delta: numPartitions}
 			numNewTopics++
+			if g.cfg.regex {
+				rns.discover(topic)
+			}
 		}
 	}
 
@@ -1858,10 +1910,10 @@ func (g *groupConsumer) updateUncommitted(fetches Fetches) {
 			}
 			var topicOffsets map[int32]uncommit
 			for _, partition := range topic.Partitions {
-				if len(partition.Records) == 0 {
+				if !partition.hasProgress() {
 					continue
 				}
-				final := partition.Records[len(partition.Records)-1]
+				finalOffset, finalEpoch, _ := partition.lastConsumed()
 
 				if topicOffsets == nil {
 					if g.uncommitted == nil {
@@ -1877,16 +1929,16 @@ func (g *groupConsumer) updateUncommitted(fetches Fetches) {
 				// Our new head points just past the final consumed offset,
 				// that is, if we rejoin, this is the offset to begin at.
 				set := EpochOffset{
-					final.LeaderEpoch, // -1 if old message / unknown
-					final.Offset + 1,
+					finalEpoch, // -1 if old message / unknown
+					finalOffset + 1,
 				}
 				prior := topicOffsets[partition.Partition]
 
 				if debug {
 					if setHead {
-						fmt.Fprintf(&b, "%d{%d=>%d r%d}, ", partition.Partition, prior.head.Offset, set.Offset, len(partition.Records))
+						fmt.Fprintf(&b, "%d{%d=>%d r%d}, ", partition.Partition, prior.head.Offset, set.Offset, partition.numRecords())
 					} else {
-						fmt.Fprintf(&b, "%d{%d=>%d=>%d r%d}, ", partition.Partition, prior.head.Offset, prior.dirty.Offset, set.Offset, len(partition.Records))
+						fmt.Fprintf(&b, "%d{%d=>%d=>%d r%d}, ", partition.Partition, prior.head.Offset, prior.dirty.Offset, set.Offset, partition.numRecords())
 					}
 				}
 
@@ -2351,6 +2403,7 @@ func (cl *Client) CommitRecords(ctx context.Context, rs ...*Record) error {
 	// offset, if any records map to the same topic / partition.
 	offsets := make(map[string]map[int32]EpochOffset)
 	for _, r := range rs {
+		r.checkNotReleased()
 		toffsets := offsets[r.Topic]
 		if toffsets == nil {
 			toffsets = make(map[int32]EpochOffset)
@@ -2417,6 +2470,7 @@ func (cl *Client) MarkCommitRecords(rs ...*Record) {
 	var curTopic string
 	var curPartitions map[int32]uncommit
 	for _, r := range rs {
+		r.checkNotReleased()
 		if curPartitions == nil || r.Topic != curTopic {
 			curPartitions = g.uncommitted[r.Topic]
 			if curPartitions == nil {
@@ -2855,6 +2909,10 @@ func (g *groupConsumer) commit(
 				reqPartition.Offset = eo.Offset
 				reqPartition.LeaderEpoch = eo.Epoch // KIP-320
 				reqPartition.Metadata = &req.MemberID
+				if g.cfg.dedupCheckpoint {
+					checkpoint := g.cl.consumer.dedup.window(topic, partition).checkpoint(eo.Offset)
+					reqPartition.Metadata = &checkpoint
+				}
 				reqTopic.Partitions = append(reqTopic.Partitions, reqPartition)
 			}
 			req.Topics = append(req.Topics, reqTopic)
@@ -2878,8 +2936,13 @@ func (g *groupConsumer) commit(
 }
 
 type reNews struct {
-	added   map[string][]string
-	skipped []string
+	added      map[string][]string
+	skipped    []string
+	discovered []string // topics matching regex that are newly consumed
+}
+
+func (r *reNews) discover(topic string) {
+	r.discovered = append(r.discovered, topic)
 }
 
 func (r *reNews) add(re, match string) {
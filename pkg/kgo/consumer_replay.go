@@ -0,0 +1,283 @@
+package kgo
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// replayer paces the records that are released from polling by their
+// timestamps; see ConsumeReplay.
+type replayer struct {
+	mu sync.Mutex
+
+	from     int64     // record timestamp (millis) that anchor corresponds to; -1 if starting at the first buffered record
+	anchor   time.Time // wall time that replaying started, or was last re-anchored; zero until replaying starts
+	speed    float64   // multiplier of the original pace
+	pausedAt time.Time // non-zero if paused
+
+	wake chan struct{}
+}
+
+func newReplayer(cfg *cfg) *replayer {
+	r := &replayer{
+		from:  -1,
+		speed: cfg.replaySpeed,
+		wake:  make(chan struct{}, 1),
+	}
+	if !cfg.replayFrom.IsZero() {
+		r.from = cfg.replayFrom.UnixMilli()
+	}
+	return r
+}
+
+// wakeup wakes a poll that is waiting for the next record to be due, such
+// that the poll checks again. This is safe to call on a nil replayer.
+func (r *replayer) wakeup() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// at returns the wall time that the replay position is evaluated at, which
+// is frozen while paused. This must be called with mu held.
+func (r *replayer) at(now time.Time) time.Time {
+	if !r.pausedAt.IsZero() && r.pausedAt.Before(now) {
+		now = r.pausedAt
+	}
+	if now.Before(r.anchor) {
+		now = r.anchor
+	}
+	return now
+}
+
+// positionLocked returns the record timestamp (millis) that replaying has
+// reached at the wall time at. This must be called with mu held after
+// replaying has started.
+func (r *replayer) positionLocked(at time.Time) int64 {
+	elapsed := float64(at.Sub(r.anchor)) / float64(time.Millisecond)
+	return r.from + int64(elapsed*r.speed)
+}
+
+// position returns the record timestamp (millis) that replaying has reached,
+// and how long it takes at the current speed to reach the given timestamp.
+// If replaying has not yet started, it starts now, at the earliest buffered
+// timestamp if no start time was configured. If nothing has ever been
+// buffered, this returns false. The duration is negative if paused.
+func (r *replayer) position(now time.Time, earliest int64) (int64, func(int64) time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.anchor.IsZero() {
+		if r.from < 0 {
+			if earliest < 0 {
+				return 0, nil, false
+			}
+			r.from = earliest
+		}
+		r.anchor = now
+	}
+
+	pos := r.positionLocked(r.at(now))
+	speed, paused := r.speed, !r.pausedAt.IsZero()
+	until := func(ts int64) time.Duration {
+		if paused {
+			return -1
+		}
+		return time.Duration(float64(ts-pos) / speed * float64(time.Millisecond))
+	}
+	return pos, until, true
+}
+
+// wait waits until d elapses (or forever if d is negative), until something
+// changes that may make more records due, or until either context is done.
+func (r *replayer) wait(clctx, ctx context.Context, d time.Duration) error {
+	var timeout <-chan time.Time
+	if d >= 0 {
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-clctx.Done():
+		return ErrClientClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.wake:
+	case <-timeout:
+	}
+	return nil
+}
+
+// takeReplay takes up to n records (or everything if n is negative) that are
+// due across every source ready for draining, taking records in timestamp
+// order across partitions. This also returns how long to wait until the next
+// buffered record is due, which is negative if nothing is buffered or if
+// replaying is paused.
+//
+// This must be called with sourcesReadyMu held.
+func (c *consumer) takeReplay(paused pausedTopics, n int) (Fetches, time.Duration) {
+	if n < 0 {
+		n = math.MaxInt32
+	}
+
+	// Each head tracks the next untaken record (or lazy batch, which is
+	// taken whole) in a buffered partition.
+	type head struct {
+		topic string
+		p     *FetchPartition
+		at    int
+		take  int
+	}
+	next := func(h *head) (ts int64, nrecs int, ok bool) {
+		if h.at < len(h.p.Records) {
+			return h.p.Records[h.at].Timestamp.UnixMilli(), 1, true
+		}
+		if h.at < len(h.p.batches) {
+			b := &h.p.batches[h.at]
+			_, _, last := b.last()
+			return last.UnixMilli(), b.numRecords(), true
+		}
+		return 0, 0, false
+	}
+
+	var heads []*head
+	earliest := int64(-1)
+	for _, s := range c.sourcesReadyForDraining {
+		for i := range s.buffered.fetch.Topics {
+			t := &s.buffered.fetch.Topics[i]
+			for j := range t.Partitions {
+				p := &t.Partitions[j]
+				if paused.has(t.Topic, p.Partition) {
+					continue
+				}
+				h := &head{topic: t.Topic, p: p}
+				if ts, _, ok := next(h); ok {
+					if earliest < 0 || ts < earliest {
+						earliest = ts
+					}
+					heads = append(heads, h)
+				}
+			}
+		}
+	}
+
+	wait := time.Duration(-1)
+	pos, until, started := c.replay.position(time.Now(), earliest)
+	for started && n > 0 {
+		var (
+			min   *head
+			minTs int64
+			nrecs int
+		)
+		for _, h := range heads {
+			if ts, hn, ok := next(h); ok && (min == nil || ts < minTs) {
+				min, minTs, nrecs = h, ts, hn
+			}
+		}
+		if min == nil {
+			break
+		}
+		if minTs > pos {
+			wait = until(minTs)
+			break
+		}
+		min.take += nrecs
+		min.at++
+		n -= nrecs
+	}
+
+	if len(c.sourcesReadyForDraining) == 0 {
+		return nil, wait
+	}
+	allot := make(map[string]map[int32]int)
+	for _, h := range heads {
+		if h.take == 0 {
+			continue
+		}
+		tallot := allot[h.topic]
+		if tallot == nil {
+			tallot = make(map[int32]int)
+			allot[h.topic] = tallot
+		}
+		tallot[h.p.Partition] = h.take
+	}
+	return c.takeLimited(paused, math.MaxInt32, func(t string, p int32) int { return allot[t][p] }), wait
+}
+
+// PauseReplay pauses replaying records (see ConsumeReplay): polling does not
+// release any further records until ResumeReplay is called. This is a no-op
+// if the client is not replaying or if replaying is already paused.
+func (cl *Client) PauseReplay() {
+	r := cl.consumer.replay
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pausedAt.IsZero() {
+		r.pausedAt = time.Now()
+	}
+}
+
+// ResumeReplay resumes replaying records after PauseReplay, continuing from
+// the record timestamp that replaying was paused at. This is a no-op if the
+// client is not replaying or if replaying is not paused.
+func (cl *Client) ResumeReplay() {
+	r := cl.consumer.replay
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pausedAt.IsZero() {
+		return
+	}
+	if !r.anchor.IsZero() {
+		now := time.Now()
+		r.anchor = r.anchor.Add(now.Sub(r.at(now)))
+	}
+	r.pausedAt = time.Time{}
+	r.wakeup()
+}
+
+// SetReplaySpeed changes the speed that records are replayed at (see
+// ConsumeReplay), continuing from the record timestamp that replaying has
+// reached. This is a no-op if the client is not replaying or if the speed is
+// not larger than zero.
+func (cl *Client) SetReplaySpeed(speed float64) {
+	r := cl.consumer.replay
+	if r == nil || !(speed > 0) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.anchor.IsZero() {
+		at := r.at(time.Now())
+		r.from, r.anchor = r.positionLocked(at), at
+	}
+	r.speed = speed
+	r.wakeup()
+}
+
+// ReplayPosition returns the record timestamp that replaying has reached
+// (see ConsumeReplay): every buffered record with a timestamp at or before
+// the position is released from polling. This returns false if the client is
+// not replaying or if replaying has not yet started.
+func (cl *Client) ReplayPosition() (time.Time, bool) {
+	r := cl.consumer.replay
+	if r == nil {
+		return time.Time{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.anchor.IsZero() {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(r.positionLocked(r.at(time.Now()))), true
+}
@@ -2,11 +2,15 @@ package kgo
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
+
+	"github.com/twmb/franz-go/pkg/kerr"
 )
 
 func isRetryableBrokerErr(err error) bool {
@@ -61,6 +65,11 @@ func isRetryableBrokerErr(err error) bool {
 	if errors.Is(err, errChosenBrokerDead) {
 		return true
 	}
+	// The broker's circuit breaker is open; the request can be retried
+	// after backoff, or on another broker.
+	if errors.Is(err, errBrokerDegraded) {
+		return true
+	}
 	// A broker kept giving us short sasl lifetimes, so we killed the
 	// connection ourselves. We can retry on a new connection.
 	if errors.Is(err, errSaslReauthLoop) {
@@ -98,6 +107,17 @@ func isAnyDialErr(err error) bool {
 	return errors.As(err, &ne) && ne.Op == "dial"
 }
 
+// isUnreachableBrokerErr returns whether err indicates that a request could
+// not reach a broker at all, rather than a reached broker failing it.
+func isUnreachableBrokerErr(err error) bool {
+	var dns *net.DNSError
+	return isAnyDialErr(err) ||
+		errors.As(err, &dns) ||
+		errors.Is(err, errUnknownBroker) ||
+		errors.Is(err, errChosenBrokerDead) ||
+		errors.Is(err, errBrokerDegraded)
+}
+
 func isContextErr(err error) bool {
 	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
 }
@@ -134,6 +154,10 @@ var (
 	// stopped due to a concurrent metadata response.
 	errChosenBrokerDead = errors.New("the internal broker struct chosen to issue this request has died--either the broker id is migrating or no longer exists")
 
+	// A temporary error returned when a broker's circuit breaker is open;
+	// see BrokerCircuitBreaker.
+	errBrokerDegraded = errors.New("the broker is degraded (circuit breaker open) and is failing requests fast until it recovers")
+
 	// If a broker repeatedly gives us tiny sasl lifetimes, we fail a
 	// request after a few tries to forcefully kill the connection and
 	// restart a new connection ourselves.
@@ -308,3 +332,135 @@ func (e *ErrGroupSession) Error() string {
 }
 
 func (e *ErrGroupSession) Unwrap() error { return e.err }
+
+// errSASLMechanism is returned when a SASL mechanism itself fails while
+// authenticating, rather than the broker rejecting the authentication.
+type errSASLMechanism struct {
+	mechanism string
+	err       error
+}
+
+func (e *errSASLMechanism) Error() string {
+	return fmt.Sprintf("sasl mechanism %s failed: %v", e.mechanism, e.err)
+}
+
+func (e *errSASLMechanism) Unwrap() error { return e.err }
+
+// ClassifiedError wraps an error with its category and the category's
+// suggested action; see ClassifyError. The wrapped error is returned from
+// Unwrap, so errors.Is and errors.As continue to work against it.
+type ClassifiedError struct {
+	// Category is the category of Err.
+	Category kerr.Category
+	// Action is the suggested action for Category.
+	Action kerr.Action
+	// Err is the classified error.
+	Err error
+}
+
+func (e *ClassifiedError) Error() string { return e.Err.Error() }
+
+// Unwrap returns the classified error.
+func (e *ClassifiedError) Unwrap() error { return e.Err }
+
+// ClassifyError classifies an error returned from the client into a category
+// with a suggested action, returning nil if err is nil. The error chain is
+// searched, so errors that wrap classifiable errors are classified the same
+// as the errors they wrap. Kafka errors are classified with kerr.Classify,
+// and errors from the client itself are classified as follows:
+//
+//   - ErrDataLoss is DataLoss.
+//   - ErrFirstReadEOF is Auth if SASL is likely missing, and Config if TLS is
+//     likely misconfigured.
+//   - SASL mechanism failures and TLS certificate verification failures are
+//     Auth.
+//   - Dial errors are Config if the broker host does not resolve, and are
+//     otherwise Transient, as are other retryable connection errors.
+//   - ErrRecordTimeout, ErrRecordRetries, and ErrMaxBuffered are Transient:
+//     the records can be produced again.
+//   - ErrGroupSession is classified by the error it wraps, or is Transient if
+//     that error is unknown: the client rejoins the group on the next poll.
+//   - Misusing the client (for example, beginning a transaction with a
+//     client that is not transactional) is Config.
+//
+// Anything else, including ErrClientClosed and context errors, is Unknown.
+func ClassifyError(err error) *ClassifiedError {
+	if err == nil {
+		return nil
+	}
+	c := classifyErr(err)
+	return &ClassifiedError{
+		Category: c,
+		Action:   c.Action(),
+		Err:      err,
+	}
+}
+
+func classifyErr(err error) kerr.Category {
+	var (
+		classified *ClassifiedError
+		dataLoss   *ErrDataLoss
+		firstRead  *ErrFirstReadEOF
+		saslMech   *errSASLMechanism
+		session    *ErrGroupSession
+		dns        *net.DNSError
+	)
+	if errors.As(err, &classified) {
+		return classified.Category
+	}
+	if isContextErr(err) || errors.Is(err, ErrClientClosed) {
+		return kerr.Unknown
+	}
+	switch {
+	case errors.As(err, &dataLoss):
+		return kerr.DataLoss
+	case errors.As(err, &firstRead):
+		if firstRead.kind == firstReadTLS {
+			return kerr.Config
+		}
+		return kerr.Auth
+	case errors.As(err, &saslMech), isCertificateErr(err):
+		return kerr.Auth
+	}
+	if c := kerr.Classify(err); c != kerr.Unknown {
+		return c
+	}
+	switch {
+	case errors.As(err, &session):
+		if c := classifyErr(session.err); c != kerr.Unknown {
+			return c
+		}
+		return kerr.Transient
+	case errors.As(err, &dns) && dns.IsNotFound:
+		return kerr.Config
+	case isAnyDialErr(err),
+		isRetryableBrokerErr(err),
+		errors.Is(err, ErrRecordTimeout),
+		errors.Is(err, ErrRecordRetries),
+		errors.Is(err, ErrMaxBuffered):
+		return kerr.Transient
+	case errors.Is(err, errNotGroup),
+		errors.Is(err, errNotTransactional),
+		errors.Is(err, errNotInTransaction),
+		errors.Is(err, errNoTopic),
+		errors.Is(err, errBrokerTooOld),
+		errors.Is(err, errUnknownRequestKey):
+		return kerr.Config
+	}
+	return kerr.Unknown
+}
+
+// isCertificateErr returns whether err is a TLS certificate verification
+// failure.
+func isCertificateErr(err error) bool {
+	var (
+		verify    *tls.CertificateVerificationError
+		authority x509.UnknownAuthorityError
+		hostname  x509.HostnameError
+		invalid   x509.CertificateInvalidError
+	)
+	return errors.As(err, &verify) ||
+		errors.As(err, &authority) ||
+		errors.As(err, &hostname) ||
+		errors.As(err, &invalid)
+}
@@ -0,0 +1,54 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+)
+
+func TestClassifyError(t *testing.T) {
+	t.Parallel()
+
+	if ClassifyError(nil) != nil {
+		t.Error("expected nil classifying a nil error")
+	}
+
+	dialErr := func(err error) error { return &net.OpError{Op: "dial", Net: "tcp", Err: err} }
+	for i, test := range []struct {
+		err error
+		exp kerr.Category
+	}{
+		{&ErrDataLoss{Topic: "foo"}, kerr.DataLoss},
+		{&ErrFirstReadEOF{kind: firstReadSASL, err: io.EOF}, kerr.Auth},
+		{&ErrFirstReadEOF{kind: firstReadTLS, err: io.EOF}, kerr.Config},
+		{&errSASLMechanism{"PLAIN", errors.New("bad token")}, kerr.Auth},
+		{&ErrGroupSession{kerr.FencedInstanceID}, kerr.Fenced},
+		{&ErrGroupSession{errors.New("unknown")}, kerr.Transient},
+		{fmt.Errorf("wrapped: %w", kerr.ProducerFenced), kerr.Fenced},
+		{dialErr(&net.DNSError{Err: "no such host", IsNotFound: true}), kerr.Config},
+		{dialErr(errors.New("connection refused")), kerr.Transient},
+		{ErrRecordTimeout, kerr.Transient},
+		{errNotTransactional, kerr.Config},
+		{ErrClientClosed, kerr.Unknown},
+		{context.Canceled, kerr.Unknown},
+		{fmt.Errorf("wrapped: %w", ClassifyError(kerr.MessageTooLarge)), kerr.Config},
+	} {
+		ce := ClassifyError(test.err)
+		if ce.Category != test.exp || ce.Action != test.exp.Action() {
+			t.Errorf("#%d (%v): got %s/%s, expected %s/%s", i, test.err, ce.Category, ce.Action, test.exp, test.exp.Action())
+		}
+		if !errors.Is(ce, test.err) {
+			t.Errorf("#%d: classified error does not wrap the original error", i)
+		}
+	}
+
+	var dl *ErrDataLoss
+	if ce := ClassifyError(fmt.Errorf("wrapped: %w", &ErrDataLoss{Topic: "foo"})); !errors.As(ce, &dl) || dl.Topic != "foo" {
+		t.Error("expected errors.As to find the wrapped ErrDataLoss")
+	}
+}
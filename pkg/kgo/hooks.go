@@ -49,6 +49,27 @@ type HookClientClosed interface {
 	OnClientClosed(*Client)
 }
 
+// HookClientRebootstrap is called when the client discards all brokers it
+// learned from metadata and re-bootstraps from its seed brokers, which happens
+// once no broker could be reached for the RebootstrapAfter timeout.
+type HookClientRebootstrap interface {
+	// OnClientRebootstrap is passed the seed broker addresses the client
+	// is re-bootstrapping from, how long brokers had been unreachable,
+	// and the most recent metadata error.
+	OnClientRebootstrap(seeds []string, failingFor time.Duration, err error)
+}
+
+// HookCredentialsRotated is called when the client's TLS config or SASL
+// mechanisms are changed with Reconfigure, or when reloading credentials in
+// WatchFiles fails.
+type HookCredentialsRotated interface {
+	// OnCredentialsRotated is passed the options that changed
+	// ("DialTLSConfig", "SASL", or both), or, if reloading failed, the
+	// reload error and no changed options. The client continues to use
+	// its prior credentials if reloading failed.
+	OnCredentialsRotated(changed []string, err error)
+}
+
 //////////////////
 // BROKER HOOKS //
 //////////////////
@@ -203,6 +224,104 @@ type HookGroupManageError interface {
 	OnGroupManageError(error)
 }
 
+// GroupRebalanceStage is a stage of a group rebalance; see HookGroupRebalance.
+type GroupRebalanceStage int8
+
+const (
+	// GroupRebalanceStart is when the client begins joining the group,
+	// either for the first time or to rebalance.
+	GroupRebalanceStart GroupRebalanceStage = iota
+	// GroupRebalanceJoined is when the client has successfully joined the
+	// group and knows its member ID, generation, and the group protocol.
+	GroupRebalanceJoined
+	// GroupRebalanceSynced is when the client has successfully synced and
+	// received its assignment from the group leader.
+	GroupRebalanceSynced
+	// GroupRebalanceAssigned is when the new assignment has been applied:
+	// OnPartitionsRevoked (for cooperative consumers) and
+	// OnPartitionsAssigned have returned.
+	GroupRebalanceAssigned
+	// GroupRebalanceOffsetsFetched is when the committed offsets for newly
+	// assigned partitions have been fetched. This stage is skipped if no
+	// partitions were newly assigned.
+	GroupRebalanceOffsetsFetched
+	// GroupRebalanceComplete is when the rebalance is done and the client
+	// can begin consuming all newly assigned partitions.
+	GroupRebalanceComplete
+)
+
+func (s GroupRebalanceStage) String() string {
+	switch s {
+	case GroupRebalanceStart:
+		return "start"
+	case GroupRebalanceJoined:
+		return "joined"
+	case GroupRebalanceSynced:
+		return "synced"
+	case GroupRebalanceAssigned:
+		return "assigned"
+	case GroupRebalanceOffsetsFetched:
+		return "offsets_fetched"
+	case GroupRebalanceComplete:
+		return "complete"
+	default:
+		return "unknown"
+	}
+}
+
+// GroupRebalanceMetrics describes the state of a group rebalance as it
+// reaches a stage.
+type GroupRebalanceMetrics struct {
+	// Stage is the stage the rebalance reached.
+	Stage GroupRebalanceStage
+
+	// Group is the group being rebalanced.
+	Group string
+	// MemberID and Generation are the member ID and generation of this
+	// client in the group. These are the prior member ID and generation
+	// in the start stage, and are the new member ID and generation from
+	// the joined stage onward.
+	MemberID   string
+	Generation int32
+	// Protocol is the group balancer protocol chosen for this rebalance.
+	// This is empty in the start stage.
+	Protocol string
+	// Leader is whether this client is the group leader, and is only
+	// known from the joined stage onward.
+	Leader bool
+	// Reason is why the client is joining the group.
+	Reason string
+
+	// Assigned contains partitions newly assigned to this client, and
+	// Revoked contains partitions that are no longer assigned to this
+	// client. These are only set from the assigned stage onward.
+	Assigned map[string][]int32
+	Revoked  map[string][]int32
+
+	// StageDuration is how long this stage took: how long the join or
+	// sync request took, how long the OnPartitions callbacks took to
+	// return, or how long offset fetching took. This is zero for the
+	// start and complete stages.
+	StageDuration time.Duration
+	// RebalanceDuration is how long it has been since the rebalance
+	// started. In the complete stage, this is the time from beginning to
+	// join the group until the client can consume its new assignment.
+	RebalanceDuration time.Duration
+}
+
+// HookGroupRebalance is called as a group rebalance progresses through each
+// GroupRebalanceStage. A rebalance that errors does not complete; the error
+// is passed to HookGroupManageError and a new rebalance starts after
+// backing off.
+type HookGroupRebalance interface {
+	// OnGroupRebalance is passed metrics for the stage the rebalance
+	// reached. This is called serially within the group management
+	// goroutine (the offsets fetched and complete stages may be called
+	// while heartbeating), and should not block for long. The maps in the
+	// metrics must not be modified.
+	OnGroupRebalance(GroupRebalanceMetrics)
+}
+
 ///////////////////////////////
 // PRODUCE & CONSUME BATCHES //
 ///////////////////////////////
@@ -249,6 +368,17 @@ type HookProduceBatchWritten interface {
 	OnProduceBatchWritten(meta BrokerMetadata, topic string, partition int32, metrics ProduceBatchMetrics)
 }
 
+// HookProduceTopicCreated is called after the client attempts to create a
+// topic that was unknown when producing; see CreateTopicOnProduce.
+type HookProduceTopicCreated interface {
+	// OnProduceTopicCreated is passed the topic, the partitions and
+	// replication factor that were requested (-1 meaning the broker
+	// default), how long the request took, and any error. If another
+	// client created the topic first, the error is kerr.TopicAlreadyExists
+	// and producing continues as if the topic was created.
+	OnProduceTopicCreated(topic string, partitions int32, replicationFactor int16, dur time.Duration, err error)
+}
+
 // FetchBatchMetrics tracks information about fetches of batches.
 type FetchBatchMetrics struct {
 	// NumRecords is the number of records that were fetched in this batch.
@@ -400,6 +530,9 @@ func implementsAnyHook(h Hook) bool {
 	switch h.(type) {
 	case HookNewClient,
 		HookClientClosed,
+		HookClientRebootstrap,
+		HookCredentialsRotated,
+		HookMetadataUpdated,
 		HookBrokerConnect,
 		HookBrokerDisconnect,
 		HookBrokerWrite,
@@ -407,7 +540,9 @@ func implementsAnyHook(h Hook) bool {
 		HookBrokerE2E,
 		HookBrokerThrottle,
 		HookGroupManageError,
+		HookGroupRebalance,
 		HookProduceBatchWritten,
+		HookProduceTopicCreated,
 		HookFetchBatchRead,
 		HookProduceRecordBuffered,
 		HookProduceRecordPartitioned,
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 )
 
 // LogLevel designates which level the logger should log at.
@@ -97,6 +99,189 @@ func (b *basicLogger) Log(level LogLevel, msg string, keyvals ...any) {
 	b.dst.Write(buf.inner)
 }
 
+// LogLimits configures RateLimitedLogger.
+type LogLimits struct {
+	// Interval is the period that messages are limited over, and how
+	// often a summary of suppressed messages is logged. If non-positive,
+	// this defaults to 1s.
+	Interval time.Duration
+
+	// Burst is how many similar messages are logged per interval before
+	// further similar messages are suppressed. If non-positive, this
+	// defaults to 10.
+	Burst int
+
+	// Thereafter, if positive, samples suppressed messages: every
+	// Thereafter'th message after Burst is logged. By default, every
+	// message after Burst is suppressed until the next interval.
+	Thereafter int
+
+	// Key returns the key that messages are limited by: messages with the
+	// same key are similar. If Key returns an empty string, the message is
+	// never limited. By default, messages are similar if they have the
+	// same level and message, regardless of their key value pairs, such
+	// that the same warning for many brokers or partitions is limited
+	// together.
+	Key func(level LogLevel, msg string, keyvals ...any) string
+}
+
+// RateLimitedLogger returns a logger that wraps inner and limits how often
+// similar messages are logged; see LogLimits for how messages are limited.
+// This can be used to wrap any Logger, including the loggers in the franz-go
+// plugins.
+//
+// When messages are suppressed in an interval, a summary is logged at the
+// level of the suppressed messages once the interval ends:
+//
+//	N similar messages suppressed; suppressed_msg: msg, interval: 1s
+//
+// If a custom Key is used, the summary also contains the key.
+func RateLimitedLogger(inner Logger, limits LogLimits) Logger {
+	l := &limitedLogger{
+		inner:      inner,
+		interval:   limits.Interval,
+		burst:      limits.Burst,
+		thereafter: limits.Thereafter,
+		key:        limits.Key,
+		keys:       make(map[string]*limitedKey),
+	}
+	if l.interval <= 0 {
+		l.interval = time.Second
+	}
+	if l.burst <= 0 {
+		l.burst = 10
+	}
+	if l.key == nil {
+		l.key = func(level LogLevel, msg string, _ ...any) string {
+			return level.String() + " " + msg
+		}
+	}
+	return l
+}
+
+// limitedLoggerMaxIdleKeys is how many keys we track before sweeping keys
+// whose intervals have ended.
+const limitedLoggerMaxIdleKeys = 1024
+
+type limitedLogger struct {
+	inner      Logger
+	interval   time.Duration
+	burst      int
+	thereafter int
+	key        func(LogLevel, string, ...any) string
+
+	mu   sync.Mutex
+	keys map[string]*limitedKey
+}
+
+// limitedKey tracks similar messages in the current interval.
+type limitedKey struct {
+	level LogLevel
+	msg   string // the first message logged for this key in the interval
+
+	start      time.Time
+	n          int  // messages seen this interval
+	suppressed int  // messages suppressed this interval and not yet summarized
+	armed      bool // whether a summary is scheduled for the end of the interval
+}
+
+func (l *limitedLogger) Level() LogLevel { return l.inner.Level() }
+
+func (l *limitedLogger) Log(level LogLevel, msg string, keyvals ...any) {
+	if l.inner.Level() < level {
+		return
+	}
+	key := l.key(level, msg, keyvals...)
+	if key == "" {
+		l.inner.Log(level, msg, keyvals...)
+		return
+	}
+
+	var (
+		now = time.Now()
+
+		pendingLevel LogLevel
+		pendingMsg   string
+		pending      int
+	)
+	l.mu.Lock()
+	k := l.keys[key]
+	if k == nil {
+		if len(l.keys) >= limitedLoggerMaxIdleKeys {
+			l.sweepLocked(now)
+		}
+		k = &limitedKey{level: level, msg: msg, start: now}
+		l.keys[key] = k
+	} else if now.Sub(k.start) >= l.interval {
+		// Our summary may not yet have fired; if so, we summarize
+		// now and the timer will find nothing to summarize.
+		pendingLevel, pendingMsg, pending = k.level, k.msg, k.suppressed
+		k.level, k.msg, k.start, k.n, k.suppressed = level, msg, now, 0, 0
+	}
+
+	k.n++
+	allow := k.n <= l.burst || l.thereafter > 0 && (k.n-l.burst)%l.thereafter == 0
+	if !allow {
+		k.suppressed++
+		if !k.armed {
+			k.armed = true
+			time.AfterFunc(k.start.Add(l.interval).Sub(now), func() { l.flush(key) })
+		}
+	}
+	l.mu.Unlock()
+
+	if pending > 0 {
+		l.summarize(key, pendingLevel, pendingMsg, pending)
+	}
+	if allow {
+		l.inner.Log(level, msg, keyvals...)
+	}
+}
+
+// flush logs the summary for a key at the end of its interval.
+func (l *limitedLogger) flush(key string) {
+	l.mu.Lock()
+	k := l.keys[key]
+	if k == nil {
+		l.mu.Unlock()
+		return
+	}
+	// If a message reset the interval after it ended but before we
+	// fired, we wait for the new interval to end.
+	if remaining := time.Until(k.start.Add(l.interval)); remaining > 0 {
+		time.AfterFunc(remaining, func() { l.flush(key) })
+		l.mu.Unlock()
+		return
+	}
+	k.armed = false
+	level, msg, suppressed := k.level, k.msg, k.suppressed
+	k.suppressed = 0
+	l.mu.Unlock()
+
+	if suppressed > 0 {
+		l.summarize(key, level, msg, suppressed)
+	}
+}
+
+func (l *limitedLogger) summarize(key string, level LogLevel, msg string, suppressed int) {
+	summary := fmt.Sprintf("%d similar messages suppressed", suppressed)
+	keyvals := []any{"suppressed_msg", msg, "interval", l.interval}
+	if defaultKey := level.String() + " " + msg; key != defaultKey {
+		keyvals = append(keyvals, "key", key)
+	}
+	l.inner.Log(level, summary, keyvals...)
+}
+
+// sweepLocked removes keys whose intervals have ended and that have nothing
+// left to summarize, bounding memory if a custom Key returns many keys.
+func (l *limitedLogger) sweepLocked(now time.Time) {
+	for key, k := range l.keys {
+		if !k.armed && k.suppressed == 0 && now.Sub(k.start) >= l.interval {
+			delete(l.keys, key)
+		}
+	}
+}
+
 // nopLogger, the default logger, drops everything.
 type nopLogger struct{}
 
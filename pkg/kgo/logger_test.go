@@ -0,0 +1,74 @@
+package kgo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type captureLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (*captureLogger) Level() LogLevel { return LogLevelInfo }
+func (c *captureLogger) Log(level LogLevel, msg string, keyvals ...any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.msgs = append(c.msgs, fmt.Sprintf("[%s] %s %v", level, msg, keyvals))
+}
+
+func (c *captureLogger) take() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	msgs := c.msgs
+	c.msgs = nil
+	return msgs
+}
+
+func TestRateLimitedLogger(t *testing.T) {
+	t.Parallel()
+
+	inner := new(captureLogger)
+	l := RateLimitedLogger(inner, LogLimits{
+		Interval:   100 * time.Millisecond,
+		Burst:      2,
+		Thereafter: 4,
+	})
+
+	for i := 0; i < 10; i++ {
+		l.Log(LogLevelWarn, "broker down", "partition", i)
+	}
+	l.Log(LogLevelError, "broker down")             // different level, not similar
+	l.Log(LogLevelDebug, "broker down", "debug", 0) // above the inner level
+
+	exp := []string{
+		"[WARN] broker down [partition 0]",
+		"[WARN] broker down [partition 1]",
+		"[WARN] broker down [partition 5]", // sampled: the 6th
+		"[WARN] broker down [partition 9]", // sampled: the 10th
+		"[ERROR] broker down []",
+	}
+	if got := inner.take(); !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got %v, expected %v", got, exp)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var got []string
+	for len(got) == 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		got = inner.take()
+	}
+	if exp := []string{"[WARN] 6 similar messages suppressed [suppressed_msg broker down interval 100ms]"}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("got summary %v, expected %v", got, exp)
+	}
+
+	// After the interval, messages are logged again.
+	l.Log(LogLevelWarn, "broker down", "partition", 10)
+	if got := inner.take(); len(got) != 1 || !strings.Contains(got[0], "partition 10") {
+		t.Errorf("got %v after the interval, expected the message to be logged", got)
+	}
+}
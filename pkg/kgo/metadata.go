@@ -41,6 +41,7 @@ func (cl *Client) ForceMetadataRefresh() {
 
 // PartitionLeader returns the given topic partition's leader, leader epoch and
 // load error. This returns -1, -1, nil if the partition has not been loaded.
+// To be notified when leaders change rather than polling, see WatchMetadata.
 func (cl *Client) PartitionLeader(topic string, partition int32) (leader, leaderEpoch int32, err error) {
 	if partition < 0 {
 		return -1, -1, errors.New("invalid negative partition")
@@ -164,6 +165,10 @@ func (cl *Client) updateMetadataLoop() {
 	defer close(cl.metadone)
 	var consecutiveErrors int
 	var lastAt time.Time
+	var (
+		unreachableSince time.Time // first unreachable error in a row, for rebootstrapping
+		unreachableTries int
+	)
 
 	ticker := time.NewTicker(cl.cfg.metadataMaxAge)
 	defer ticker.Stop()
@@ -225,6 +230,11 @@ loop:
 		}
 
 		retryWhy, err := cl.updateMetadata()
+		cl.metaStateMu.Lock()
+		if cl.metaErr = err; err == nil {
+			cl.metaAt = time.Now()
+		}
+		cl.metaStateMu.Unlock()
 		if retryWhy != nil || err != nil {
 			// If err is non-nil, the metadata request failed
 			// itself and already retried 3x; we do not loop more.
@@ -269,11 +279,33 @@ loop:
 			cl.consumer.doOnMetadataUpdate()
 			lastAt = time.Now()
 			consecutiveErrors = 0
+			unreachableSince, unreachableTries = time.Time{}, 0
 			continue
 		}
 
+		// If we have been unable to reach any broker for long enough,
+		// every broker we know of may have moved; we go back to our
+		// seeds. We only do this once we have failed to reach at least
+		// as many brokers as we know of: metadata requests cycle
+		// through brokers, so every broker has been tried. An error
+		// from a broker we did reach resets our tracking.
+		if cl.cfg.rebootstrapAfter > 0 {
+			switch {
+			case !isUnreachableBrokerErr(err):
+				unreachableSince, unreachableTries = time.Time{}, 0
+			case unreachableSince.IsZero():
+				unreachableSince, unreachableTries = time.Now(), 1
+			default:
+				unreachableTries++
+				if failing := time.Since(unreachableSince); failing >= cl.cfg.rebootstrapAfter && unreachableTries >= cl.numKnownBrokers() {
+					cl.rebootstrap(failing, err)
+					unreachableSince, unreachableTries = time.Now(), 0
+				}
+			}
+		}
+
 		consecutiveErrors++
-		after := time.NewTimer(cl.cfg.retryBackoff(consecutiveErrors))
+		after := time.NewTimer(cl.loadDyn().retryBackoff(consecutiveErrors))
 	backoff:
 		select {
 		case <-cl.ctx.Done():
@@ -340,6 +372,7 @@ func (cl *Client) updateMetadata() (retryWhy multiUpdateWhy, err error) {
 		return nil, err
 	}
 	groupExternal.updateLatest(latest)
+	cl.notifyMetadata(latest)
 
 	// If we are consuming with regex and fetched all topics, the metadata
 	// may have returned topics the consumer is not yet tracking. We ensure
@@ -502,7 +535,7 @@ func (mp metadataPartition) newPartition(cl *Client, isProduce bool) *topicParti
 			maxRecordBatchBytes: cl.maxRecordBatchBytesForTopic(mp.topic),
 			recBufsIdx:          -1,
 			failing:             mp.loadErr != 0,
-			sink:                mp.sns.sink,
+			sink:                mp.sns.sink(mp.topic, mp.partition),
 			topicPartitionData:  td,
 		}
 	} else {
@@ -511,14 +544,15 @@ func (mp metadataPartition) newPartition(cl *Client, isProduce bool) *topicParti
 			topicID:            mp.topicID,
 			partition:          mp.partition,
 			keepControl:        cl.cfg.keepControl,
+			filter:             cl.consumer.cursorFilter(mp.topic, mp.partition),
 			cursorsIdx:         -1,
-			source:             mp.sns.source,
 			topicPartitionData: td,
 			cursorOffset: cursorOffset{
 				offset:            -1, // required to not consume until needed
 				lastConsumedEpoch: -1, // required sentinel
 			},
 		}
+		p.cursor.setSource(mp.sns.source(mp.topic, mp.partition))
 	}
 	return p
 }
@@ -607,10 +641,7 @@ func (cl *Client) fetchTopicMetadata(all bool, reqTopics []string) (map[string]*
 			cl.sinksAndSourcesMu.Lock()
 			sns, exists := cl.sinksAndSources[mp.leader]
 			if !exists {
-				sns = sinkAndSource{
-					sink:   cl.newSink(mp.leader),
-					source: cl.newSource(mp.leader),
-				}
+				sns = cl.newSinkAndSource(mp.leader)
 				cl.sinksAndSources[mp.leader] = sns
 			}
 			for _, replica := range partMeta.Replicas {
@@ -618,10 +649,7 @@ func (cl *Client) fetchTopicMetadata(all bool, reqTopics []string) (map[string]*
 					continue
 				}
 				if _, exists = cl.sinksAndSources[replica]; !exists {
-					cl.sinksAndSources[replica] = sinkAndSource{
-						sink:   cl.newSink(replica),
-						source: cl.newSource(replica),
-					}
+					cl.sinksAndSources[replica] = cl.newSinkAndSource(replica)
 				}
 			}
 			cl.sinksAndSourcesMu.Unlock()
@@ -0,0 +1,289 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+)
+
+// MetadataDiff is the difference between two metadata updates for the topics
+// the client is tracking (producing to, consuming, or, when consuming with
+// regex, all topics) and for the brokers in the cluster.
+type MetadataDiff struct {
+	// AddedTopics are topics that now exist or that the client started
+	// tracking.
+	AddedTopics []string
+	// RemovedTopics are topics that no longer exist or that the client
+	// stopped tracking.
+	RemovedTopics []string
+
+	// PartitionCounts contains topics whose partition count changed. Added
+	// topics are included with a prior count of 0, and removed topics are
+	// included with a count of 0.
+	PartitionCounts []PartitionCountChange
+
+	// Leaders contains partitions whose leader or leader epoch changed.
+	// All partitions of added topics and new partitions are included with
+	// a prior leader and epoch of -1. Partitions that fail to load (for
+	// example, because the leader is unavailable) have a leader of -1.
+	Leaders []LeaderChange
+
+	// AddedBrokers are brokers that joined the cluster, and RemovedBrokers
+	// are brokers that left. A broker whose host, port, or rack changes is
+	// in both.
+	AddedBrokers   []BrokerMetadata
+	RemovedBrokers []BrokerMetadata
+}
+
+// PartitionCountChange is a change in the number of partitions in a topic.
+type PartitionCountChange struct {
+	Topic string
+	Prior int
+	Now   int
+}
+
+// LeaderChange is a change in the leader or leader epoch of a partition.
+type LeaderChange struct {
+	Topic            string
+	Partition        int32
+	PriorLeader      int32
+	PriorLeaderEpoch int32
+	Leader           int32
+	LeaderEpoch      int32
+}
+
+// Empty returns whether nothing changed.
+func (d *MetadataDiff) Empty() bool {
+	return len(d.AddedTopics) == 0 &&
+		len(d.RemovedTopics) == 0 &&
+		len(d.PartitionCounts) == 0 &&
+		len(d.Leaders) == 0 &&
+		len(d.AddedBrokers) == 0 &&
+		len(d.RemovedBrokers) == 0
+}
+
+// HookMetadataUpdated is called after a metadata update that changed any
+// tracked topic, partition, or broker.
+type HookMetadataUpdated interface {
+	// OnMetadataUpdated is passed the changes from the prior metadata
+	// update. This is called serially in the metadata update loop, and
+	// should not block for long.
+	OnMetadataUpdated(MetadataDiff)
+}
+
+type metaLeader struct{ leader, epoch int32 }
+
+// metaSnapshot is the state of the last metadata update, which we diff new
+// updates against.
+type metaSnapshot struct {
+	topics  map[string][]metaLeader
+	brokers map[int32]BrokerMetadata
+}
+
+type metaWatch struct {
+	mu       sync.Mutex
+	snap     metaSnapshot
+	watchers map[*metaWatcher]struct{}
+}
+
+type metaWatcher struct {
+	queue  []MetadataDiff
+	signal chan struct{}
+}
+
+// WatchMetadata returns a channel that receives a MetadataDiff every time a
+// metadata update changes a tracked topic, partition, or broker; see
+// HookMetadataUpdated. The first diff received is the current metadata state
+// diffed against nothing, which can be used to seed partition aware caches.
+// If no metadata has been loaded yet, the first diff is received after the
+// first metadata update.
+//
+// Diffs are queued for slow receivers and are never dropped or merged. The
+// channel is closed when ctx is canceled or the client is closed.
+func (cl *Client) WatchMetadata(ctx context.Context) <-chan MetadataDiff {
+	w := &metaWatcher{signal: make(chan struct{}, 1)}
+	m := &cl.metaWatch
+	m.mu.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[*metaWatcher]struct{})
+	}
+	m.watchers[w] = struct{}{}
+	if initial := diffMetadata(metaSnapshot{}, m.snap); !initial.Empty() {
+		w.queue = append(w.queue, initial)
+		w.signal <- struct{}{}
+	}
+	m.mu.Unlock()
+
+	ch := make(chan MetadataDiff)
+	go func() {
+		defer close(ch)
+		defer func() {
+			m.mu.Lock()
+			delete(m.watchers, w)
+			m.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-cl.ctx.Done():
+				return
+			case <-w.signal:
+			}
+			for {
+				m.mu.Lock()
+				if len(w.queue) == 0 {
+					m.mu.Unlock()
+					break
+				}
+				d := w.queue[0]
+				w.queue = w.queue[1:]
+				m.mu.Unlock()
+
+				select {
+				case ch <- d:
+				case <-ctx.Done():
+					return
+				case <-cl.ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// notifyMetadata diffs the latest metadata against our last snapshot and
+// notifies hooks and watchers of any change. This is only called from
+// updateMetadata, which is only called serially in the metadata loop.
+func (cl *Client) notifyMetadata(latest map[string]*metadataTopic) {
+	m := &cl.metaWatch
+	now := metaSnapshot{
+		topics:  make(map[string][]metaLeader, len(latest)),
+		brokers: make(map[int32]BrokerMetadata),
+	}
+
+	m.mu.Lock()
+	prior := m.snap
+
+	for topic, mt := range latest {
+		if mt.loadErr != nil {
+			// A topic that failed to load with a non-deletion
+			// error keeps its prior leaders.
+			if ls, ok := prior.topics[topic]; ok && !errors.Is(mt.loadErr, kerr.UnknownTopicOrPartition) {
+				now.topics[topic] = ls
+			}
+			continue
+		}
+		ls := make([]metaLeader, len(mt.partitions))
+		for i, mp := range mt.partitions {
+			ls[i] = metaLeader{mp.leader, mp.leaderEpoch}
+			if mp.loadErr != 0 {
+				ls[i] = metaLeader{-1, -1}
+			}
+		}
+		now.topics[topic] = ls
+	}
+
+	cl.brokersMu.RLock()
+	for _, b := range cl.brokers {
+		now.brokers[b.meta.NodeID] = b.meta
+	}
+	cl.brokersMu.RUnlock()
+
+	m.snap = now
+	d := diffMetadata(prior, now)
+	if d.Empty() {
+		m.mu.Unlock()
+		return
+	}
+	for w := range m.watchers {
+		w.queue = append(w.queue, d)
+		select {
+		case w.signal <- struct{}{}:
+		default:
+		}
+	}
+	m.mu.Unlock()
+
+	cl.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookMetadataUpdated); ok {
+			h.OnMetadataUpdated(d)
+		}
+	})
+}
+
+func diffMetadata(prior, now metaSnapshot) MetadataDiff {
+	var d MetadataDiff
+	for topic, ls := range now.topics {
+		pls, existed := prior.topics[topic]
+		if !existed {
+			d.AddedTopics = append(d.AddedTopics, topic)
+		}
+		if len(pls) != len(ls) {
+			d.PartitionCounts = append(d.PartitionCounts, PartitionCountChange{topic, len(pls), len(ls)})
+		}
+		for p, l := range ls {
+			pl := metaLeader{-1, -1}
+			if p < len(pls) {
+				pl = pls[p]
+			}
+			if pl != l || p >= len(pls) {
+				d.Leaders = append(d.Leaders, LeaderChange{
+					Topic:            topic,
+					Partition:        int32(p),
+					PriorLeader:      pl.leader,
+					PriorLeaderEpoch: pl.epoch,
+					Leader:           l.leader,
+					LeaderEpoch:      l.epoch,
+				})
+			}
+		}
+	}
+	for topic, pls := range prior.topics {
+		if _, exists := now.topics[topic]; !exists {
+			d.RemovedTopics = append(d.RemovedTopics, topic)
+			d.PartitionCounts = append(d.PartitionCounts, PartitionCountChange{topic, len(pls), 0})
+		}
+	}
+
+	for id, b := range now.brokers {
+		pb, existed := prior.brokers[id]
+		if !existed || !pb.same(b) {
+			d.AddedBrokers = append(d.AddedBrokers, b)
+			if existed {
+				d.RemovedBrokers = append(d.RemovedBrokers, pb)
+			}
+		}
+	}
+	for id, pb := range prior.brokers {
+		if _, exists := now.brokers[id]; !exists {
+			d.RemovedBrokers = append(d.RemovedBrokers, pb)
+		}
+	}
+
+	sort.Strings(d.AddedTopics)
+	sort.Strings(d.RemovedTopics)
+	sort.Slice(d.PartitionCounts, func(i, j int) bool { return d.PartitionCounts[i].Topic < d.PartitionCounts[j].Topic })
+	sort.Slice(d.Leaders, func(i, j int) bool {
+		l, r := d.Leaders[i], d.Leaders[j]
+		return l.Topic < r.Topic || l.Topic == r.Topic && l.Partition < r.Partition
+	})
+	sort.Slice(d.AddedBrokers, func(i, j int) bool { return d.AddedBrokers[i].NodeID < d.AddedBrokers[j].NodeID })
+	sort.Slice(d.RemovedBrokers, func(i, j int) bool { return d.RemovedBrokers[i].NodeID < d.RemovedBrokers[j].NodeID })
+	return d
+}
+
+func (me BrokerMetadata) same(other BrokerMetadata) bool {
+	var lr, rr string
+	if me.Rack != nil {
+		lr = *me.Rack
+	}
+	if other.Rack != nil {
+		rr = *other.Rack
+	}
+	return me.NodeID == other.NodeID && me.Host == other.Host && me.Port == other.Port && lr == rr
+}
@@ -0,0 +1,88 @@
+package kgo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+)
+
+type metaHook struct{ diffs chan MetadataDiff }
+
+func (h *metaHook) OnMetadataUpdated(d MetadataDiff) { h.diffs <- d }
+
+func TestNotifyMetadata(t *testing.T) {
+	h := &metaHook{make(chan MetadataDiff, 10)}
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), WithHooks(h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	topic := func(name string, leaders ...int32) *metadataTopic {
+		mt := &metadataTopic{topic: name}
+		for p, l := range leaders {
+			mt.partitions = append(mt.partitions, metadataPartition{topic: name, partition: int32(p), leader: l, leaderEpoch: 1})
+		}
+		return mt
+	}
+
+	cl.notifyMetadata(map[string]*metadataTopic{
+		"foo": topic("foo", 1, 2),
+		"bar": topic("bar", 1),
+	})
+	d := <-h.diffs
+	if !reflect.DeepEqual(d.AddedTopics, []string{"bar", "foo"}) || len(d.Leaders) != 3 || len(d.PartitionCounts) != 2 {
+		t.Errorf("unexpected initial diff %+v", d)
+	}
+
+	// A watcher starting now first receives the current state.
+	ctx, cancel := context.WithCancel(context.Background())
+	watch := cl.WatchMetadata(ctx)
+	if initial := <-watch; !reflect.DeepEqual(initial.AddedTopics, []string{"bar", "foo"}) {
+		t.Errorf("unexpected initial watched diff %+v", initial)
+	}
+
+	// Nothing changes: no hook call.
+	cl.notifyMetadata(map[string]*metadataTopic{
+		"foo": topic("foo", 1, 2),
+		"bar": topic("bar", 1),
+	})
+
+	// foo gains a partition and moves partition 0's leader, bar is deleted.
+	cl.notifyMetadata(map[string]*metadataTopic{
+		"foo": topic("foo", 3, 2, 1),
+		"bar": {topic: "bar", loadErr: kerr.UnknownTopicOrPartition},
+	})
+	exp := MetadataDiff{
+		RemovedTopics: []string{"bar"},
+		PartitionCounts: []PartitionCountChange{
+			{"bar", 1, 0},
+			{"foo", 2, 3},
+		},
+		Leaders: []LeaderChange{
+			{"foo", 0, 1, 1, 3, 1},
+			{"foo", 2, -1, -1, 1, 1},
+		},
+	}
+	for _, got := range []MetadataDiff{<-h.diffs, <-watch} {
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("got diff %+v, exp %+v", got, exp)
+		}
+	}
+
+	cancel()
+	select {
+	case _, ok := <-watch:
+		if ok {
+			t.Error("unexpected diff after canceling the watch")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("watch channel not closed after canceling")
+	}
+	if len(h.diffs) != 0 {
+		t.Errorf("unexpected extra hook calls: %d", len(h.diffs))
+	}
+}
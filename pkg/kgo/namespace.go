@@ -0,0 +1,338 @@
+package kgo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// nsReq returns a copy of a request with the client's namespace (see
+// Namespace) prefixed to every topic, group, and transactional ID. The
+// caller's request is never modified: it may be retried, and once a request
+// issued with Client.Request returns (including if its context is canceled),
+// the caller owns it again while we may still be writing it. Requests the
+// client does not know how to namespace, including our internal produce and
+// fetch requests that prefix names as they are encoded, are returned as is.
+func (cl *Client) nsReq(req kmsg.Request) (kmsg.Request, error) {
+	ns := cl.cfg.namespace
+	if ns == "" {
+		return req, nil
+	}
+	switch req.(type) {
+	case *produceRequest, *fetchRequest:
+		return req, nil
+	}
+	orig := req
+	if req = kmsg.RequestForKey(orig.Key()); req == nil {
+		return orig, nil
+	}
+	req.SetVersion(orig.GetVersion())
+	if err := req.ReadFrom(orig.AppendTo(nil)); err != nil {
+		return nil, fmt.Errorf("unable to copy %s request to namespace it: %w", kmsg.NameForKey(orig.Key()), err)
+	}
+
+	f := func(s *string) { *s = ns + *s }
+	fp := func(s *string) {
+		if s != nil {
+			f(s)
+		}
+	}
+	fs := func(ss []string) {
+		for i := range ss {
+			f(&ss[i])
+		}
+	}
+
+	switch r := req.(type) {
+	case *kmsg.ProduceRequest:
+		fp(r.TransactionID)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.FetchRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+		for i := range r.ForgottenTopics {
+			f(&r.ForgottenTopics[i].Topic)
+		}
+	case *kmsg.ListOffsetsRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.MetadataRequest:
+		for i := range r.Topics {
+			fp(r.Topics[i].Topic)
+		}
+	case *kmsg.OffsetCommitRequest:
+		f(&r.Group)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.OffsetFetchRequest:
+		f(&r.Group)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+		for i := range r.Groups {
+			g := &r.Groups[i]
+			f(&g.Group)
+			for j := range g.Topics {
+				f(&g.Topics[j].Topic)
+			}
+		}
+	case *kmsg.FindCoordinatorRequest:
+		if r.CoordinatorType == 0 || r.CoordinatorType == 1 { // group or txn
+			f(&r.CoordinatorKey)
+			fs(r.CoordinatorKeys)
+		}
+	case *kmsg.JoinGroupRequest:
+		f(&r.Group)
+	case *kmsg.HeartbeatRequest:
+		f(&r.Group)
+	case *kmsg.LeaveGroupRequest:
+		f(&r.Group)
+	case *kmsg.SyncGroupRequest:
+		f(&r.Group)
+	case *kmsg.DescribeGroupsRequest:
+		fs(r.Groups)
+	case *kmsg.CreateTopicsRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteTopicsRequest:
+		fs(r.TopicNames)
+		for i := range r.Topics {
+			fp(r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteRecordsRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.InitProducerIDRequest:
+		fp(r.TransactionalID)
+	case *kmsg.OffsetForLeaderEpochRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.AddPartitionsToTxnRequest:
+		f(&r.TransactionalID)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+		for i := range r.Transactions {
+			t := &r.Transactions[i]
+			f(&t.TransactionalID)
+			for j := range t.Topics {
+				f(&t.Topics[j].Topic)
+			}
+		}
+	case *kmsg.AddOffsetsToTxnRequest:
+		f(&r.TransactionalID)
+		f(&r.Group)
+	case *kmsg.EndTxnRequest:
+		f(&r.TransactionalID)
+	case *kmsg.TxnOffsetCommitRequest:
+		f(&r.TransactionalID)
+		f(&r.Group)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeConfigsRequest:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.AlterConfigsRequest:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.IncrementalAlterConfigsRequest:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.CreatePartitionsRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteGroupsRequest:
+		fs(r.Groups)
+	case *kmsg.OffsetDeleteRequest:
+		f(&r.Group)
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeProducersRequest:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeTransactionsRequest:
+		fs(r.TransactionalIDs)
+	default:
+		return orig, nil
+	}
+	return req, nil
+}
+
+// nsResp strips the client's namespace from every topic, group, and
+// transactional ID in a response. Responses that list topics, groups, or
+// transactions across the cluster are filtered to only those within the
+// namespace.
+func (cl *Client) nsResp(resp kmsg.Response) {
+	ns := cl.cfg.namespace
+	if ns == "" {
+		return
+	}
+	f := func(s *string) { *s = strings.TrimPrefix(*s, ns) }
+	fp := func(s *string) {
+		if s != nil {
+			f(s)
+		}
+	}
+	in := func(s string) bool { return strings.HasPrefix(s, ns) }
+
+	switch r := resp.(type) {
+	case *kmsg.ProduceResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.FetchResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.ListOffsetsResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.MetadataResponse:
+		keep := r.Topics[:0]
+		for _, t := range r.Topics {
+			if t.Topic != nil && !in(*t.Topic) {
+				continue // another namespace's topic, or outside any namespace
+			}
+			fp(t.Topic)
+			keep = append(keep, t)
+		}
+		r.Topics = keep
+	case *kmsg.OffsetCommitResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.OffsetFetchResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+		for i := range r.Groups {
+			g := &r.Groups[i]
+			f(&g.Group)
+			for j := range g.Topics {
+				f(&g.Topics[j].Topic)
+			}
+		}
+	case *kmsg.FindCoordinatorResponse:
+		for i := range r.Coordinators {
+			f(&r.Coordinators[i].Key)
+		}
+	case *kmsg.DescribeGroupsResponse:
+		for i := range r.Groups {
+			f(&r.Groups[i].Group)
+		}
+	case *kmsg.ListGroupsResponse:
+		keep := r.Groups[:0]
+		for _, g := range r.Groups {
+			if in(g.Group) {
+				f(&g.Group)
+				keep = append(keep, g)
+			}
+		}
+		r.Groups = keep
+	case *kmsg.CreateTopicsResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteTopicsResponse:
+		for i := range r.Topics {
+			fp(r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteRecordsResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.OffsetForLeaderEpochResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.AddPartitionsToTxnResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+		for i := range r.Transactions {
+			t := &r.Transactions[i]
+			f(&t.TransactionalID)
+			for j := range t.Topics {
+				f(&t.Topics[j].Topic)
+			}
+		}
+	case *kmsg.TxnOffsetCommitResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeConfigsResponse:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.AlterConfigsResponse:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.IncrementalAlterConfigsResponse:
+		for i := range r.Resources {
+			if rr := &r.Resources[i]; rr.ResourceType == kmsg.ConfigResourceTypeTopic {
+				f(&rr.ResourceName)
+			}
+		}
+	case *kmsg.CreatePartitionsResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DeleteGroupsResponse:
+		for i := range r.Groups {
+			f(&r.Groups[i].Group)
+		}
+	case *kmsg.OffsetDeleteResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeProducersResponse:
+		for i := range r.Topics {
+			f(&r.Topics[i].Topic)
+		}
+	case *kmsg.DescribeTransactionsResponse:
+		for i := range r.TransactionStates {
+			t := &r.TransactionStates[i]
+			f(&t.TransactionalID)
+			for j := range t.Topics {
+				f(&t.Topics[j].Topic)
+			}
+		}
+	case *kmsg.ListTransactionsResponse:
+		keep := r.TransactionStates[:0]
+		for _, t := range r.TransactionStates {
+			if in(t.TransactionalID) {
+				f(&t.TransactionalID)
+				keep = append(keep, t)
+			}
+		}
+		r.TransactionStates = keep
+	}
+}
@@ -0,0 +1,89 @@
+package kgo
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestNamespace(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), Namespace("tenant."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	req := kmsg.NewPtrTxnOffsetCommitRequest()
+	req.TransactionalID = "txn"
+	req.Group = "group"
+	rt := kmsg.NewTxnOffsetCommitRequestTopic()
+	rt.Topic = "tenant.foo" // a logical name that happens to look prefixed
+	req.Topics = append(req.Topics, rt)
+
+	kreq, err := cl.nsReq(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nsReq := kreq.(*kmsg.TxnOffsetCommitRequest)
+	if nsReq.TransactionalID != "tenant.txn" || nsReq.Group != "tenant.group" || nsReq.Topics[0].Topic != "tenant.tenant.foo" {
+		t.Errorf("request not namespaced: %+v", nsReq)
+	}
+	if req.TransactionalID != "txn" || req.Group != "group" || req.Topics[0].Topic != "tenant.foo" {
+		t.Errorf("caller's request modified: %+v", req)
+	}
+
+	// Requests we do not namespace, and our internal requests that
+	// namespace themselves, are not copied.
+	acls := kmsg.NewPtrDescribeACLsRequest()
+	if kreq, _ := cl.nsReq(acls); kreq != acls {
+		t.Error("ACL request unexpectedly copied")
+	}
+	p := &produceRequest{version: 7, namespace: "tenant."}
+	if kreq, _ := cl.nsReq(p); kreq != p {
+		t.Error("internal produce request unexpectedly copied")
+	}
+
+	meta := kmsg.NewPtrMetadataResponse()
+	for _, topic := range []string{"tenant.foo", "other.bar", "baz"} {
+		mt := kmsg.NewMetadataResponseTopic()
+		mt.Topic = kmsg.StringPtr(topic)
+		meta.Topics = append(meta.Topics, mt)
+	}
+	cl.nsResp(meta)
+	var topics []string
+	for _, mt := range meta.Topics {
+		topics = append(topics, *mt.Topic)
+	}
+	if exp := []string{"foo"}; !reflect.DeepEqual(topics, exp) {
+		t.Errorf("got metadata topics %v, exp %v", topics, exp)
+	}
+
+	groups := kmsg.NewPtrListGroupsResponse()
+	for _, group := range []string{"tenant.g", "g"} {
+		lg := kmsg.NewListGroupsResponseGroup()
+		lg.Group = group
+		groups.Groups = append(groups.Groups, lg)
+	}
+	cl.nsResp(groups)
+	if len(groups.Groups) != 1 || groups.Groups[0].Group != "g" {
+		t.Errorf("got groups %v, exp only g", groups.Groups)
+	}
+
+	// Our internal produce request prefixes its own topics and
+	// transactional ID.
+	p = &produceRequest{
+		version:   7,
+		txnID:     kmsg.StringPtr("txn"),
+		namespace: "tenant.",
+		batches:   seqRecBatches{"foo": {}},
+	}
+	var decoded kmsg.ProduceRequest
+	decoded.Version = 7
+	if err := decoded.ReadFrom(p.AppendTo(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if *decoded.TransactionID != "tenant.txn" || len(decoded.Topics) != 1 || decoded.Topics[0].Topic != "tenant.foo" {
+		t.Errorf("produce request not namespaced: %+v", decoded)
+	}
+}
@@ -261,6 +261,64 @@ func (cl *Client) ProduceSync(ctx context.Context, rs ...*Record) ProduceResults
 	return results
 }
 
+// ProduceBatch produces every record in rs, calling promise once with rs and
+// the first error encountered (if any) after every record is done. This is
+// the same as calling Produce for each record in order, with two differences:
+// records are used in place, so one slice of records can be reused rather
+// than allocating a Record per message, and there is one promise for the
+// whole batch rather than one per record. See the Produce documentation for
+// an in depth description of how producing works.
+//
+// ProduceBatch does not buffer records any differently than Produce: each
+// record is individually partitioned, counted against MaxBufferedRecords and
+// MaxBufferedBytes, and buffered, and ProduceBatch can block partway through
+// rs wherever Produce would block. Records are grouped into record batches per
+// partition as usual, regardless of how they were passed to ProduceBatch.
+//
+// The caller owns rs and the buffers of each record's key, value, and headers,
+// and must not modify them until the promise is called. Successfully produced
+// records have their attributes, offset, and partition set before the promise
+// is called. If promise is nil, the records are produced with no
+// notification.
+func (cl *Client) ProduceBatch(
+	ctx context.Context,
+	rs []Record,
+	promise func([]Record, error),
+) {
+	if len(rs) == 0 {
+		if promise != nil {
+			promise(rs, nil)
+		}
+		return
+	}
+	var recPromise func(*Record, error)
+	if promise != nil {
+		b := &produceBatchPromise{rs: rs, left: len(rs), fn: promise}
+		recPromise = b.promise
+	}
+	for i := range rs {
+		cl.produce(ctx, &rs[i], recPromise, true)
+	}
+}
+
+// produceBatchPromise tracks the records of a ProduceBatch. Record promises
+// are called serially, so this needs no locking.
+type produceBatchPromise struct {
+	rs   []Record
+	left int
+	err  error
+	fn   func([]Record, error)
+}
+
+func (b *produceBatchPromise) promise(_ *Record, err error) {
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	if b.left--; b.left == 0 {
+		b.fn(b.rs, b.err)
+	}
+}
+
 // FirstErrPromise is a helper type to capture only the first failing error
 // when producing a batch of records with this type's Promise function.
 //
@@ -401,15 +459,16 @@ func (cl *Client) produce(
 		userSize     = r.userSize()
 		bufRecs      = p.bufferedRecords.Add(1)
 		bufBytes     = p.bufferedBytes.Add(userSize)
-		overMaxRecs  = bufRecs > cl.cfg.maxBufferedRecords
+		maxBufBytes  = cl.cfg.maxBufferedBytes.load()
+		overMaxRecs  = bufRecs > cl.cfg.maxBufferedRecords.load()
 		overMaxBytes bool
 	)
-	if cl.cfg.maxBufferedBytes > 0 {
-		if userSize > cl.cfg.maxBufferedBytes {
+	if maxBufBytes > 0 {
+		if userSize > maxBufBytes {
 			p.promiseRecord(promisedRec{ctx, promise, r}, kerr.MessageTooLarge)
 			return
 		}
-		overMaxBytes = bufBytes > cl.cfg.maxBufferedBytes
+		overMaxBytes = bufBytes > maxBufBytes
 	}
 
 	if r.Topic == "" {
@@ -521,8 +580,9 @@ func (cl *Client) finishRecordPromise(pr promisedRec, err error) {
 	userSize := pr.userSize()
 	nowBufBytes := p.bufferedBytes.Add(-userSize)
 	nowBufRecs := p.bufferedRecords.Add(-1)
-	wasOverMaxRecs := nowBufRecs >= cl.cfg.maxBufferedRecords
-	wasOverMaxBytes := cl.cfg.maxBufferedBytes > 0 && nowBufBytes+userSize > cl.cfg.maxBufferedBytes
+	maxBufBytes := cl.cfg.maxBufferedBytes.load()
+	wasOverMaxRecs := nowBufRecs >= cl.cfg.maxBufferedRecords.load()
+	wasOverMaxBytes := maxBufBytes > 0 && nowBufBytes+userSize > maxBufBytes
 
 	// We call the promise before finishing the record; this allows users
 	// of Flush to know that all buffered records are completely done
@@ -912,6 +972,16 @@ func (cl *Client) waitUnknownTopic(
 		after = timer.C
 	}
 
+	// If we are configured to create this topic, we create it the first
+	// time we learn it is unknown. created is non-nil while the creation
+	// is in flight.
+	creation, create := cl.cfg.topicCreation(topic)
+	var (
+		created        chan error
+		createdTopic   bool
+		createAttempts int
+	)
+
 	// Ordering: aborting is set first, then unknown topics are manually
 	// canceled in a lock. New unknown topics after that lock will see
 	// aborting here and immediately cancel themselves.
@@ -935,15 +1005,37 @@ func (cl *Client) waitUnknownTopic(
 			}
 			cl.cfg.logger.Log(LogLevelInfo, "new topic metadata wait failed, retrying wait", "topic", topic, "err", retryableErr)
 			tries++
-			if int64(tries) >= cl.cfg.recordRetries {
+			if int64(tries) >= cl.cfg.recordRetries.load() {
 				err = fmt.Errorf("no partitions available after attempting to refresh metadata %d times, last err: %w", tries, retryableErr)
 			}
+			if create && !createdTopic && errors.Is(retryableErr, kerr.UnknownTopicOrPartition) {
+				if created != nil {
+					continue // we do not count unknown failures while creating the topic
+				}
+				created = make(chan error, 1)
+				go func() { created <- cl.createProduceTopic(topic, creation) }()
+				createAttempts++
+				if createAttempts == 1 {
+					continue // nor the failure that prompts us to create it
+				}
+			}
 			if cl.cfg.maxUnknownFailures >= 0 && errors.Is(retryableErr, kerr.UnknownTopicOrPartition) {
 				unknownTries++
 				if unknownTries > cl.cfg.maxUnknownFailures {
 					err = retryableErr
 				}
 			}
+		case cerr := <-created:
+			created = nil
+			switch {
+			case cerr == nil || errors.Is(cerr, kerr.TopicAlreadyExists):
+				createdTopic = true
+				cl.triggerUpdateMetadataNow("topic created on produce")
+			case kerr.IsRetriable(cerr):
+				cl.cfg.logger.Log(LogLevelWarn, "unable to create unknown topic on produce, retrying on the next metadata load", "topic", topic, "err", cerr)
+			default:
+				err = cerr
+			}
 		}
 	}
 
@@ -971,8 +1063,53 @@ func (cl *Client) waitUnknownTopic(
 	})
 }
 
+// createProduceTopic creates a topic that is unknown when producing; see
+// CreateTopicOnProduce.
+func (cl *Client) createProduceTopic(topic string, creation TopicCreation) error {
+	partitions, replicationFactor := creation.Partitions, creation.ReplicationFactor
+	if partitions == 0 {
+		partitions = -1
+	}
+	if replicationFactor == 0 {
+		replicationFactor = -1
+	}
+
+	req := kmsg.NewPtrCreateTopicsRequest()
+	req.TimeoutMillis = int32(cl.cfg.produceTimeout.Milliseconds())
+	reqTopic := kmsg.NewCreateTopicsRequestTopic()
+	reqTopic.Topic = topic
+	reqTopic.NumPartitions = partitions
+	reqTopic.ReplicationFactor = replicationFactor
+	for k, v := range creation.Configs {
+		reqConfig := kmsg.NewCreateTopicsRequestTopicConfig()
+		reqConfig.Name = k
+		reqConfig.Value = kmsg.StringPtr(v)
+		reqTopic.Configs = append(reqTopic.Configs, reqConfig)
+	}
+	req.Topics = append(req.Topics, reqTopic)
+
+	cl.cfg.logger.Log(LogLevelInfo, "creating unknown topic on produce", "topic", topic, "partitions", partitions, "replication_factor", replicationFactor)
+	start := time.Now()
+	resp, err := req.RequestWith(cl.ctx, cl)
+	if err == nil {
+		if len(resp.Topics) != 1 {
+			err = fmt.Errorf("create topics response returned %d topics when we requested 1", len(resp.Topics))
+		} else {
+			err = kerr.ErrorForCode(resp.Topics[0].ErrorCode)
+		}
+	}
+	dur := time.Since(start)
+
+	cl.cfg.hooks.each(func(h Hook) {
+		if h, ok := h.(HookProduceTopicCreated); ok {
+			h.OnProduceTopicCreated(topic, partitions, replicationFactor, dur, err)
+		}
+	})
+	return err
+}
+
 func (cl *Client) unlingerDueToMaxRecsBuffered() {
-	if cl.cfg.linger <= 0 {
+	if cl.cfg.linger.load() <= 0 {
 		return
 	}
 	for _, parts := range cl.producer.topics.load() {
@@ -1005,7 +1142,7 @@ func (cl *Client) Flush(ctx context.Context) error {
 	// linger because the producer's flushing atomic int32 is nonzero. We
 	// must wake anything that could be lingering up, after which all sinks
 	// will loop draining.
-	if cl.cfg.linger > 0 || cl.cfg.manualFlushing {
+	if cl.cfg.linger.load() > 0 || cl.cfg.manualFlushing {
 		for _, parts := range p.topics.load() {
 			for _, part := range parts.load().partitions {
 				part.records.unlingerAndManuallyDrain()
@@ -1066,8 +1203,8 @@ func (p *producer) pause(ctx context.Context) error {
 
 func (p *producer) resume() {
 	if p.inflight.Add(-1<<48) == 0 {
-		p.cl.allSinksAndSources(func(sns sinkAndSource) {
-			sns.sink.maybeDrain()
+		p.cl.allSinks(func(s *sink) {
+			s.maybeDrain()
 		})
 	}
 }
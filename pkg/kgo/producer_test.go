@@ -0,0 +1,86 @@
+package kgo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProduceBatch(t *testing.T) {
+	t.Parallel()
+
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), TransactionalID("txn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	var calls int
+	cl.ProduceBatch(context.Background(), nil, func(rs []Record, err error) {
+		calls++
+		if len(rs) != 0 || err != nil {
+			t.Errorf("empty batch: got %d records and err %v, expected none", len(rs), err)
+		}
+	})
+
+	// Producing outside of a transaction fails every record immediately,
+	// and the batch promise is called once all records are failed.
+	rs := []Record{
+		{Topic: "foo", Value: []byte("v1")},
+		{Topic: "foo", Value: []byte("v2")},
+		{Topic: "foo", Value: []byte("v3")},
+	}
+	done := make(chan struct{})
+	cl.ProduceBatch(context.Background(), rs, func(got []Record, err error) {
+		calls++
+		if &got[0] != &rs[0] || len(got) != len(rs) {
+			t.Error("promise was not called with the produced records")
+		}
+		if !errors.Is(err, errNotInTransaction) {
+			t.Errorf("got err %v, expected %v", err, errNotInTransaction)
+		}
+		close(done)
+	})
+	<-done
+	if calls != 2 {
+		t.Errorf("got %d promise calls, expected 2", calls)
+	}
+	if n := cl.BufferedProduceRecords(); n != 0 {
+		t.Errorf("got %d buffered records after the batch promise, expected 0", n)
+	}
+}
+
+func TestCreateTopicOnProduceConfig(t *testing.T) {
+	t.Parallel()
+
+	exact := TopicCreation{Partitions: 3, ReplicationFactor: 2, Configs: map[string]string{"cleanup.policy": "compact"}}
+	byRegex := TopicCreation{Partitions: 12}
+	cl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		CreateTopicsOnProduceRegex(`^events\.`, byRegex),
+		CreateTopicOnProduce("events.compacted", exact),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	for topic, exp := range map[string]*TopicCreation{
+		"events.compacted": &exact, // exact topics take precedence
+		"events.clicks":    &byRegex,
+		"other":            nil,
+	} {
+		got, ok := cl.cfg.topicCreation(topic)
+		if ok != (exp != nil) || ok && !reflect.DeepEqual(got, *exp) {
+			t.Errorf("topic %s: got %v (%v), expected %v", topic, got, ok, exp)
+		}
+	}
+	if got := cl.OptValue(CreateTopicOnProduce).(map[string]TopicCreation); len(got) != 1 {
+		t.Errorf("got %d exact topic creations, expected 1", len(got))
+	}
+
+	if _, err := NewClient(SeedBrokers("127.0.0.1:1"), CreateTopicsOnProduceRegex("(", byRegex)); err == nil {
+		t.Error("expected error for invalid topic creation regular expression")
+	}
+}
@@ -0,0 +1,344 @@
+package kgo
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+)
+
+// dynCfg contains the non-scalar options that can be changed with
+// Reconfigure. Anything reading these options after NewClient must use the
+// loaded dynCfg rather than the fields in cfg. Scalar options that can be
+// changed are lazy atomics directly in cfg.
+type dynCfg struct {
+	retryBackoff func(int) time.Duration
+	retryTimeout func(int16) time.Duration
+	sasls        []sasl.Mechanism
+	saslUsers    *saslUsers
+	dialTLS      *tls.Config
+	compression  []CompressionCodec
+	compressor   *compressor
+}
+
+func (cl *Client) loadDyn() *dynCfg { return cl.dyn.Load().(*dynCfg) }
+
+// saslUsers counts the authentications in flight that use a dynCfg's SASL
+// mechanisms, such that mechanisms replaced with Reconfigure can be closed
+// once nothing uses them anymore.
+type saslUsers struct {
+	mu      sync.Mutex
+	n       int
+	retired bool
+	closing []sasl.ClosingMechanism // closed once retired and unused
+}
+
+// acquire returns false if the mechanisms have been retired, in which case
+// the caller should load the latest dynCfg and try again.
+func (u *saslUsers) acquire() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.retired {
+		return false
+	}
+	u.n++
+	return true
+}
+
+func (u *saslUsers) release() {
+	u.mu.Lock()
+	u.n--
+	closing := u.takeClosingLocked()
+	u.mu.Unlock()
+	for _, c := range closing {
+		c.Close()
+	}
+}
+
+// retire marks the mechanisms as replaced, closing any that implement
+// sasl.ClosingMechanism once no authentication uses them.
+func (u *saslUsers) retire(replaced []sasl.Mechanism) {
+	u.mu.Lock()
+	u.retired = true
+	for _, m := range replaced {
+		if c, ok := m.(sasl.ClosingMechanism); ok {
+			u.closing = append(u.closing, c)
+		}
+	}
+	closing := u.takeClosingLocked()
+	u.mu.Unlock()
+	for _, c := range closing {
+		c.Close()
+	}
+}
+
+func (u *saslUsers) takeClosingLocked() []sasl.ClosingMechanism {
+	if !u.retired || u.n > 0 {
+		return nil
+	}
+	closing := u.closing
+	u.closing = nil
+	return closing
+}
+
+// reconfigurableCfg returns a cfg containing only the options that can be
+// changed with Reconfigure, loaded from the live config. All other fields are
+// zero, which is how cfgChanges detects options that cannot be reconfigured.
+// Slices are copied so that an option reusing a slice's backing array cannot
+// modify the live config.
+func (cl *Client) reconfigurableCfg(dyn *dynCfg) cfg {
+	return cfg{
+		linger:             lazyI64(cl.cfg.linger.load()),
+		compression:        append([]CompressionCodec(nil), dyn.compression...),
+		maxBytes:           lazyI32(cl.cfg.maxBytes.load()),
+		maxPartBytes:       lazyI32(cl.cfg.maxPartBytes.load()),
+		retries:            lazyI64(cl.cfg.retries.load()),
+		retryBackoff:       dyn.retryBackoff,
+		retryTimeout:       dyn.retryTimeout,
+		recordRetries:      lazyI64(cl.cfg.recordRetries.load()),
+		maxBufferedRecords: lazyI64(cl.cfg.maxBufferedRecords.load()),
+		maxBufferedBytes:   lazyI64(cl.cfg.maxBufferedBytes.load()),
+		sasls:              append([]sasl.Mechanism(nil), dyn.sasls...),
+		dialTLS:            dyn.dialTLS,
+	}
+}
+
+// reconfigurable contains the cfg fields that can be changed with
+// Reconfigure; these are the fields set in reconfigurableCfg.
+var reconfigurable = map[string]bool{
+	"linger":             true,
+	"compression":        true,
+	"maxBytes":           true,
+	"maxPartBytes":       true,
+	"retries":            true,
+	"retryBackoff":       true,
+	"retryTimeout":       true,
+	"recordRetries":      true,
+	"maxBufferedRecords": true,
+	"maxBufferedBytes":   true,
+	"sasls":              true,
+	"dialTLS":            true,
+}
+
+// Reconfigure changes options on a live client. Only the following options
+// can be changed:
+//
+//   - ProducerLinger
+//   - ProducerBatchCompression
+//   - FetchMaxBytes, FetchMaxPartitionBytes
+//   - RequestRetries, RetryBackoffFn, RetryTimeout, RetryTimeoutFn
+//   - RecordRetries
+//   - MaxBufferedRecords, MaxBufferedBytes
+//   - SASL
+//   - DialTLSConfig, DialTLS
+//
+// Any other option returns an error, even if it would not change the
+// client's configuration. The resulting configuration is validated the same as
+// in NewClient, and if anything is invalid, nothing is changed.
+//
+// Unlike in NewClient, SASL replaces all existing mechanisms rather than
+// appending to them. New SASL mechanisms and TLS configs are used for any new
+// connection; existing connections are not closed, but SASL re-authentication
+// (KIP-368) uses the new mechanisms. A TLS config can only be changed if the
+// client was created with DialTLSConfig or DialTLS, since otherwise the client
+// does not own its dialer. Replaced SASL mechanisms that implement
+// sasl.ClosingMechanism are closed once no in progress authentication is
+// using them. If the TLS config or SASL mechanisms change, any
+// HookCredentialsRotated hook is called. To rotate credentials from files on
+// disk, see WatchFiles.
+//
+// Changes to producing options apply to the next record buffered or batch
+// drained, and changes to fetch sizes apply to the next fetch request.
+func (cl *Client) Reconfigure(opts ...Opt) error {
+	cl.reconfigureMu.Lock()
+	defer cl.reconfigureMu.Unlock()
+
+	dyn := cl.loadDyn()
+	prior := cl.reconfigurableCfg(dyn)
+
+	// SASL appends, and functions cannot be compared: we clear these
+	// before applying to know whether any option set them. cfgChanges
+	// restores whatever was not set.
+	next := cl.reconfigurableCfg(dyn)
+	next.sasls = nil
+	next.retryBackoff = nil
+	next.retryTimeout = nil
+	for _, opt := range opts {
+		opt.apply(&next)
+	}
+
+	changed, err := cfgChanges(&prior, &next)
+	if err != nil {
+		return err
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if next.dialTLS != prior.dialTLS && (prior.dialTLS == nil || next.dialTLS == nil) {
+		return errors.New("unable to reconfigure DialTLSConfig: TLS can only be changed, not added nor removed, and only if the client was created with DialTLSConfig or DialTLS")
+	}
+
+	// Validation needs the full config. Every write to the live config
+	// after NewClient is serialized by reconfigureMu, so we can copy it.
+	full := cl.cfg
+	full.linger = next.linger
+	full.compression = next.compression
+	full.maxBytes = next.maxBytes
+	full.maxPartBytes = next.maxPartBytes
+	full.retries = next.retries
+	full.retryBackoff = next.retryBackoff
+	full.retryTimeout = next.retryTimeout
+	full.recordRetries = next.recordRetries
+	full.maxBufferedRecords = next.maxBufferedRecords
+	full.maxBufferedBytes = next.maxBufferedBytes
+	full.sasls = next.sasls
+	full.dialTLS = next.dialTLS
+	if err := full.validate(); err != nil {
+		return err
+	}
+	compressor, err := newCompressor(full.compression...)
+	if err != nil {
+		return err
+	}
+
+	cl.cfg.retries.store(int64(full.retries))
+	cl.cfg.recordRetries.store(int64(full.recordRetries))
+	cl.cfg.maxBufferedRecords.store(int64(full.maxBufferedRecords))
+	cl.cfg.maxBufferedBytes.store(int64(full.maxBufferedBytes))
+	cl.cfg.linger.store(int64(full.linger))
+	cl.cfg.maxBytes.store(int32(full.maxBytes))
+	cl.cfg.maxPartBytes.store(int32(full.maxPartBytes))
+	saslsChanged := !sameMechanisms(prior.sasls, next.sasls)
+	users := dyn.saslUsers
+	if saslsChanged {
+		users = new(saslUsers)
+	}
+	cl.dyn.Store(&dynCfg{
+		retryBackoff: full.retryBackoff,
+		retryTimeout: full.retryTimeout,
+		sasls:        full.sasls,
+		saslUsers:    users,
+		dialTLS:      full.dialTLS,
+		compression:  full.compression,
+		compressor:   compressor,
+	})
+
+	var rotated []string
+	if next.dialTLS != prior.dialTLS {
+		rotated = append(rotated, "DialTLSConfig")
+	}
+	if saslsChanged {
+		dyn.saslUsers.retire(replacedSASLs(prior.sasls, next.sasls))
+		rotated = append(rotated, "SASL")
+	}
+
+	cl.cfg.logger.Log(LogLevelInfo, "reconfigured client", "changed", strings.Join(changed, ", "))
+	if len(rotated) > 0 {
+		cl.cfg.hooks.each(func(h Hook) {
+			if h, ok := h.(HookCredentialsRotated); ok {
+				h.OnCredentialsRotated(rotated, nil)
+			}
+		})
+	}
+	return nil
+}
+
+// replacedSASLs returns the mechanisms in prior that are not in next.
+func replacedSASLs(prior, next []sasl.Mechanism) []sasl.Mechanism {
+	var replaced []sasl.Mechanism
+outer:
+	for _, p := range prior {
+		for _, n := range next {
+			if sameMechanism(p, n) {
+				continue outer
+			}
+		}
+		replaced = append(replaced, p)
+	}
+	return replaced
+}
+
+// sameMechanism returns whether two mechanisms are the same value. Mechanisms
+// whose dynamic values cannot be compared (structs containing funcs, for
+// example) are never the same; closable mechanisms are generally pointers.
+func sameMechanism(p, n sasl.Mechanism) (same bool) {
+	defer func() {
+		if recover() != nil {
+			same = false
+		}
+	}()
+	return p == n
+}
+
+// sameMechanisms returns whether prior and next are the same mechanisms in
+// the same order.
+func sameMechanisms(prior, next []sasl.Mechanism) bool {
+	if len(prior) != len(next) {
+		return false
+	}
+	for i := range prior {
+		if !sameMechanism(prior[i], next[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// cfgChanges returns the options that changed between prior and next, or an
+// error if an option that cannot be reconfigured was used. Both configs must
+// come from reconfigurableCfg, and next must have its SASL mechanisms and
+// retry functions cleared before options were applied; cfgChanges restores
+// those from prior if no option set them.
+//
+// Options that cannot be reconfigured are detected by next having a non-zero
+// field outside of the reconfigurable fields.
+func cfgChanges(prior, next *cfg) ([]string, error) {
+	nv := reflect.ValueOf(next).Elem()
+	for i := 0; i < nv.NumField(); i++ {
+		name := nv.Type().Field(i).Name
+		if !reconfigurable[name] && !nv.Field(i).IsZero() {
+			return nil, fmt.Errorf("unable to reconfigure client: option changing %q cannot be changed at runtime", name)
+		}
+	}
+
+	setBackoff, setTimeout := next.retryBackoff != nil, next.retryTimeout != nil
+	if !setBackoff {
+		next.retryBackoff = prior.retryBackoff
+	}
+	if !setTimeout {
+		next.retryTimeout = prior.retryTimeout
+	}
+	if next.sasls == nil {
+		next.sasls = prior.sasls
+	}
+
+	var changed []string
+	for _, field := range []struct {
+		opt  string
+		same bool
+	}{
+		{"ProducerLinger", prior.linger == next.linger},
+		{"ProducerBatchCompression", reflect.DeepEqual(prior.compression, next.compression)},
+		{"FetchMaxBytes", prior.maxBytes == next.maxBytes},
+		{"FetchMaxPartitionBytes", prior.maxPartBytes == next.maxPartBytes},
+		{"RequestRetries", prior.retries == next.retries},
+		{"RetryBackoffFn", !setBackoff},
+		{"RetryTimeout or RetryTimeoutFn", !setTimeout},
+		{"RecordRetries", prior.recordRetries == next.recordRetries},
+		{"MaxBufferedRecords", prior.maxBufferedRecords == next.maxBufferedRecords},
+		{"MaxBufferedBytes", prior.maxBufferedBytes == next.maxBufferedBytes},
+		{"SASL", sameMechanisms(prior.sasls, next.sasls)},
+		{"DialTLSConfig", prior.dialTLS == next.dialTLS},
+	} {
+		if !field.same {
+			changed = append(changed, field.opt)
+		}
+	}
+	return changed, nil
+}
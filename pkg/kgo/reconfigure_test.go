@@ -0,0 +1,216 @@
+package kgo
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+func TestReconfigure(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), DialTLS())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	mech := plain.Auth{User: "user", Pass: "pass"}.AsMechanism()
+	if err := cl.Reconfigure(
+		ProducerLinger(50*time.Millisecond),
+		RequestRetries(3),
+		MaxBufferedRecords(5),
+		FetchMaxBytes(1<<20),
+		ProducerBatchCompression(NoCompression()),
+		SASL(mech),
+		DialTLS(),
+	); err != nil {
+		t.Fatalf("unexpected reconfigure err: %v", err)
+	}
+
+	for _, test := range []struct {
+		opt any
+		exp any
+	}{
+		{ProducerLinger, 50 * time.Millisecond},
+		{RequestRetries, int64(3)},
+		{MaxBufferedRecords, int64(5)},
+		{FetchMaxBytes, int32(1 << 20)},
+	} {
+		if got := cl.OptValue(test.opt); got != test.exp {
+			t.Errorf("%s: got %v != exp %v", namefn(test.opt), got, test.exp)
+		}
+	}
+	if sasls := cl.loadDyn().sasls; len(sasls) != 1 || sasls[0].Name() != mech.Name() {
+		t.Errorf("got sasls %v, expected only the new mechanism", sasls)
+	}
+
+	// Invalid or immutable options change nothing.
+	for _, opts := range [][]Opt{
+		{ProducerLinger(time.Hour)},
+		{ProducerLinger(time.Second), ClientID("foo")},
+		{ProducerLinger(time.Second), ConsumeTopics("foo")},
+		{ProducerLinger(time.Second), SeedBrokers("127.0.0.1:2")},
+		{ProducerLinger(time.Second), SeedBrokers("127.0.0.1:1")},
+	} {
+		if err := cl.Reconfigure(opts...); err == nil {
+			t.Errorf("expected reconfigure err for %d opts", len(opts))
+		}
+		if got := cl.OptValue(ProducerLinger); got != 50*time.Millisecond {
+			t.Errorf("got linger %v after a failed reconfigure, exp unchanged", got)
+		}
+	}
+	if seeds := cl.OptValue(SeedBrokers).([]string); len(seeds) != 1 || seeds[0] != "127.0.0.1:1" {
+		t.Errorf("got seeds %v after a failed reconfigure, exp unchanged", seeds)
+	}
+
+	// Reconfiguring to the same values changes nothing.
+	if err := cl.Reconfigure(RequestRetries(3), ProducerBatchCompression(NoCompression()), SASL(mech)); err != nil {
+		t.Errorf("unexpected err reconfiguring unchanged options: %v", err)
+	}
+
+	// Without a TLS config from NewClient, TLS cannot be added.
+	plaintext, err := NewClient(SeedBrokers("127.0.0.1:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plaintext.Close()
+	if err := plaintext.Reconfigure(DialTLS()); err == nil {
+		t.Error("expected err adding TLS to a plaintext client")
+	}
+}
+
+type closingMechanism struct {
+	sasl.Mechanism
+	closed *atomic.Int32
+}
+
+func (m closingMechanism) Close() { m.closed.Add(1) }
+
+func TestReconfigureClosesReplacedSASL(t *testing.T) {
+	var closed atomic.Int32
+	mech := func(user string) sasl.Mechanism {
+		return closingMechanism{plain.Auth{User: user}.AsMechanism(), &closed}
+	}
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), SASL(mech("first")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fetch max bytes can be updated concurrently with reconfiguring.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			cl.UpdateFetchMaxBytes(int32(1<<20+i), 1<<20)
+		}
+	}()
+
+	// An authentication in flight keeps a replaced mechanism open.
+	inUse := cl.loadDyn().saslUsers
+	inUse.acquire()
+	if err := cl.Reconfigure(SASL(mech("second"))); err != nil {
+		t.Fatal(err)
+	}
+	if n := closed.Load(); n != 0 {
+		t.Errorf("got %d closed mechanisms while in use, expected 0", n)
+	}
+	inUse.release()
+	if n := closed.Load(); n != 1 {
+		t.Errorf("got %d closed mechanisms after release, expected 1", n)
+	}
+
+	for i := 0; i < 5; i++ {
+		if err := cl.Reconfigure(SASL(mech("rotated" + strconv.Itoa(i)))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+	if n := closed.Load(); n != 6 {
+		t.Errorf("got %d closed mechanisms after rotating, expected 6", n)
+	}
+
+	cl.Close()
+	if n := closed.Load(); n != 7 {
+		t.Errorf("got %d closed mechanisms after close, expected 7", n)
+	}
+}
+
+type rotatedHook chan []string
+
+func (h rotatedHook) OnCredentialsRotated(changed []string, err error) {
+	if err != nil {
+		changed = []string{err.Error()}
+	}
+	h <- changed
+}
+
+func TestWatchFiles(t *testing.T) {
+	rotated := make(rotatedHook, 10)
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), WithHooks(rotated))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	path := filepath.Join(t.TempDir(), "pass")
+	if err := os.WriteFile(path, []byte("one"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cl.WatchFiles(10*time.Millisecond, func() ([]Opt, error) {
+		pass, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(pass) == 0 {
+			return nil, errors.New("empty password")
+		}
+		return []Opt{SASL(plain.Auth{User: "user", Pass: string(pass)}.AsMechanism())}, nil
+	}, path)
+
+	wait := func(exp string) {
+		t.Helper()
+		select {
+		case changed := <-rotated:
+			if len(changed) != 1 || changed[0] != exp {
+				t.Fatalf("got rotated %v, exp %s", changed, exp)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", exp)
+		}
+	}
+
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	wait("empty password")
+	if sasls := cl.loadDyn().sasls; len(sasls) != 0 {
+		t.Errorf("got %d sasls after a failed reload, exp 0", len(sasls))
+	}
+
+	if err := os.WriteFile(path, []byte("two"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for {
+		select {
+		case changed := <-rotated:
+			if len(changed) == 1 && changed[0] == "empty password" {
+				continue // retried before we rewrote the file
+			}
+			if len(changed) != 1 || changed[0] != "SASL" {
+				t.Fatalf("got rotated %v, exp SASL", changed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for SASL rotation")
+		}
+		break
+	}
+	if sasls := cl.loadDyn().sasls; len(sasls) != 1 {
+		t.Errorf("got %d sasls after reloading, exp 1", len(sasls))
+	}
+}
@@ -149,6 +149,16 @@ type Record struct {
 	// producer hooks. It can also be set in a consumer hook to propagate
 	// enrichment to consumer clients.
 	Context context.Context
+
+	// sequence is the idempotent sequence number the record was produced
+	// with, or -1; see DedupByProducerSequence.
+	sequence int32
+
+	// If consuming with PooledRecords, these track where the record is
+	// returned to once released.
+	pool     *recordPool
+	buf      *pooledBuf
+	released bool
 }
 
 func (r *Record) userSize() int64 {
@@ -260,7 +270,20 @@ type FetchPartition struct {
 	// known as the earliest offset in the partition.
 	LogStartOffset int64
 	// Records contains feched records for this partition.
+	//
+	// If consuming with LazyRecordDecoding, this is empty, and records
+	// must be read with EachRecord or RecordIter.
 	Records []*Record
+
+	// batches contains batches that are decoded when iterated, if
+	// consuming with LazyRecordDecoding.
+	batches []fetchBatch
+
+	// filtered is whether ConsumeFilter dropped any record, and
+	// lastFiltered is the last dropped record, which commits must
+	// advance past even if it was after every kept record.
+	filtered     bool
+	lastFiltered cursorOffset
 }
 
 // EachRecord calls fn for each record in the partition.
@@ -268,6 +291,89 @@ func (p *FetchPartition) EachRecord(fn func(*Record)) {
 	for _, r := range p.Records {
 		fn(r)
 	}
+	for i := range p.batches {
+		for _, r := range p.batches[i].decode() {
+			fn(r)
+		}
+	}
+}
+
+// RecordIter returns an iterator over all records in the partition. If
+// consuming with LazyRecordDecoding, batches are decoded one at a time as the
+// iterator reaches them.
+func (p *FetchPartition) RecordIter() *FetchPartitionRecordIter {
+	iter := &FetchPartitionRecordIter{recs: p.Records, batches: p.batches}
+	iter.prepareNext()
+	return iter
+}
+
+// FetchPartitionRecordIter iterates over records in a partition.
+type FetchPartitionRecordIter struct {
+	recs    []*Record
+	batches []fetchBatch
+}
+
+// Done returns whether there are any more records to iterate over.
+func (i *FetchPartitionRecordIter) Done() bool {
+	return len(i.recs) == 0
+}
+
+// Next returns the next record from the partition.
+func (i *FetchPartitionRecordIter) Next() *Record {
+	next := i.recs[0]
+	i.recs = i.recs[1:]
+	i.prepareNext()
+	return next
+}
+
+func (i *FetchPartitionRecordIter) prepareNext() {
+	for len(i.recs) == 0 && len(i.batches) > 0 {
+		i.recs = i.batches[0].decode()
+		i.batches = i.batches[1:]
+	}
+}
+
+func (p *FetchPartition) hasRecords() bool {
+	return len(p.Records) > 0 || len(p.batches) > 0
+}
+
+// hasProgress returns whether the partition has records or dropped records
+// with ConsumeFilter, either of which advance what is consumed.
+func (p *FetchPartition) hasProgress() bool {
+	return p.hasRecords() || p.filtered
+}
+
+// numRecords returns the number of records in the partition. Lazily decoded
+// batches count every record in the batch.
+func (p *FetchPartition) numRecords() int {
+	n := len(p.Records)
+	for i := range p.batches {
+		n += p.batches[i].numRecords()
+	}
+	return n
+}
+
+// lastConsumed returns the offset, leader epoch, and timestamp of the last
+// record in the partition, which must have progress. For lazily decoded
+// batches, this is the last offset in the batch (which may be past the last
+// record if the topic is compacted) and the batch's max timestamp. If the last
+// record was dropped by ConsumeFilter, this returns the dropped record.
+func (p *FetchPartition) lastConsumed() (int64, int32, time.Time) {
+	var (
+		offset int64 = -1
+		epoch  int32 = -1
+		ts     time.Time
+	)
+	if len(p.batches) > 0 {
+		offset, epoch, ts = p.batches[len(p.batches)-1].last()
+	} else if len(p.Records) > 0 {
+		r := p.Records[len(p.Records)-1]
+		offset, epoch, ts = r.Offset, r.LeaderEpoch, r.Timestamp
+	}
+	if f := &p.lastFiltered; p.filtered && f.offset > offset {
+		return f.offset, f.lastConsumedEpoch, f.lastConsumedTime
+	}
+	return offset, epoch, ts
 }
 
 // FetchTopic is a response for a fetched topic from a broker.
@@ -289,9 +395,7 @@ func (t *FetchTopic) EachPartition(fn func(FetchPartition)) {
 // EachRecord calls fn for each record in the topic, in any partition order.
 func (t *FetchTopic) EachRecord(fn func(*Record)) {
 	for i := range t.Partitions {
-		for _, r := range t.Partitions[i].Records {
-			fn(r)
-		}
+		t.Partitions[i].EachRecord(fn)
 	}
 }
 
@@ -303,11 +407,11 @@ func (t *FetchTopic) EachRecord(fn func(*Record)) {
 func (t *FetchTopic) Records() []*Record {
 	var n int
 	t.EachPartition(func(p FetchPartition) {
-		n += len(p.Records)
+		n += p.numRecords()
 	})
 	rs := make([]*Record, 0, n)
-	t.EachPartition(func(p FetchPartition) {
-		rs = append(rs, p.Records...)
+	t.EachRecord(func(r *Record) {
+		rs = append(rs, r)
 	})
 	return rs
 }
@@ -381,7 +485,7 @@ func (f Fetch) hasErrorsOrRecords() bool {
 		t := &f.Topics[i]
 		for j := range t.Partitions {
 			p := &t.Partitions[j]
-			if p.Err != nil || len(p.Records) > 0 {
+			if p.Err != nil || p.hasProgress() {
 				return true
 			}
 		}
@@ -469,9 +573,9 @@ func (fs Fetches) RecordIter() *FetchesRecordIter {
 // FetchesRecordIter iterates over records in a fetch.
 type FetchesRecordIter struct {
 	fetches []Fetch
-	ti      int // index to current topic in fetches[0]
-	pi      int // index to current partition in current topic
-	ri      int // index to current record in current partition
+	ti      int                      // index to current topic in fetches[0]
+	pi      int                      // index to the next partition in current topic
+	p       FetchPartitionRecordIter // iterator over the current partition
 }
 
 // Done returns whether there are any more records to iterate over.
@@ -481,39 +585,27 @@ func (i *FetchesRecordIter) Done() bool {
 
 // Next returns the next record from a fetch.
 func (i *FetchesRecordIter) Next() *Record {
-	next := i.fetches[0].Topics[i.ti].Partitions[i.pi].Records[i.ri]
-	i.ri++
+	next := i.p.Next()
 	i.prepareNext()
 	return next
 }
 
 func (i *FetchesRecordIter) prepareNext() {
-beforeFetch0:
-	if len(i.fetches) == 0 {
-		return
-	}
-
-	fetch0 := &i.fetches[0]
-beforeTopic:
-	if i.ti >= len(fetch0.Topics) {
-		i.fetches = i.fetches[1:]
-		i.ti = 0
-		goto beforeFetch0
-	}
-
-	topic := &fetch0.Topics[i.ti]
-beforePartition:
-	if i.pi >= len(topic.Partitions) {
-		i.ti++
-		i.pi = 0
-		goto beforeTopic
-	}
-
-	partition := &topic.Partitions[i.pi]
-	if i.ri >= len(partition.Records) {
-		i.pi++
-		i.ri = 0
-		goto beforePartition
+	for len(i.fetches) > 0 && i.p.Done() {
+		fetch0 := &i.fetches[0]
+		switch {
+		case i.ti >= len(fetch0.Topics):
+			i.fetches = i.fetches[1:]
+			i.ti, i.pi = 0, 0
+		case i.pi >= len(fetch0.Topics[i.ti].Partitions):
+			i.ti++
+			i.pi = 0
+		default:
+			p := &fetch0.Topics[i.ti].Partitions[i.pi]
+			i.p = FetchPartitionRecordIter{recs: p.Records, batches: p.batches}
+			i.p.prepareNext()
+			i.pi++
+		}
 	}
 }
 
@@ -582,16 +674,19 @@ func (fs Fetches) EachRecord(fn func(*Record)) {
 // functions or the RecordIter.
 func (fs Fetches) Records() []*Record {
 	rs := make([]*Record, 0, fs.NumRecords())
-	fs.EachPartition(func(p FetchTopicPartition) {
-		rs = append(rs, p.Records...)
+	fs.EachRecord(func(r *Record) {
+		rs = append(rs, r)
 	})
 	return rs
 }
 
 // NumRecords returns the total number of records across all fetched partitions.
+//
+// If consuming with LazyRecordDecoding, this counts every record in each
+// fetched batch, which may include records that are skipped when decoding.
 func (fs Fetches) NumRecords() (n int) {
 	fs.EachPartition(func(p FetchTopicPartition) {
-		n += len(p.Records)
+		n += p.numRecords()
 	})
 	return n
 }
@@ -601,7 +696,7 @@ func (fs Fetches) Empty() bool {
 	for i := range fs {
 		for j := range fs[i].Topics {
 			for k := range fs[i].Topics[j].Partitions {
-				if len(fs[i].Topics[j].Partitions[k].Records) > 0 {
+				if fs[i].Topics[j].Partitions[k].hasRecords() {
 					return false
 				}
 			}
@@ -622,7 +717,5 @@ type FetchTopicPartition struct {
 
 // EachRecord calls fn for each record in the topic's partition.
 func (r *FetchTopicPartition) EachRecord(fn func(*Record)) {
-	for _, r := range r.Records {
-		fn(r)
-	}
+	r.FetchPartition.EachRecord(fn)
 }
@@ -0,0 +1,121 @@
+package kgo
+
+import "sync"
+
+// recordPool pools fetched records and the buffers that batches are
+// decompressed into, if consuming with PooledRecords.
+type recordPool struct {
+	records sync.Pool
+	bufs    sync.Pool
+}
+
+func newRecordPool() *recordPool {
+	return &recordPool{
+		records: sync.Pool{New: func() any { return new(Record) }},
+		bufs:    sync.Pool{New: func() any { return new(pooledBuf) }},
+	}
+}
+
+// pooledBuf is a decompressed batch, shared by every record decoded from the
+// batch. The buffer is returned to the pool once all of its records are
+// released and the batch is done being processed.
+type pooledBuf struct {
+	b    []byte
+	refs atomicI32
+	pool *recordPool
+}
+
+// getBuf returns a buffer with one reference, which is held by the batch
+// being processed.
+func (p *recordPool) getBuf() *pooledBuf {
+	buf := p.bufs.Get().(*pooledBuf)
+	buf.pool = p
+	buf.refs.Store(1)
+	return buf
+}
+
+func (buf *pooledBuf) release() {
+	switch refs := buf.refs.Add(-1); {
+	case refs > 0:
+		return
+	case refs < 0:
+		panic("kgo: pooled record buffer released too many times")
+	}
+	if poolDebug {
+		// We poison rather than reuse buffers so that any record key,
+		// value, or header retained past Release is obviously wrong.
+		for i := range buf.b {
+			buf.b[i] = 0xde
+		}
+		return
+	}
+	buf.pool.bufs.Put(buf)
+}
+
+// newRecord returns a record from the pool. If buf is non-nil, the record's
+// key, value, and headers point into buf.
+func (p *recordPool) newRecord(buf *pooledBuf) *Record {
+	r := p.records.Get().(*Record)
+	r.pool, r.buf, r.released = p, buf, false
+	if buf != nil {
+		buf.refs.Add(1)
+	}
+	return r
+}
+
+// Release returns a record to the client's pool if the record was fetched
+// while consuming with PooledRecords, and is a no-op otherwise. The record,
+// including its key, value, and headers, must not be used after it is
+// released. Building with the kgo_debug tag panics if a record is released
+// twice or is committed after being released, and overwrites the memory of
+// released records.
+func (r *Record) Release() {
+	if r.pool == nil {
+		return
+	}
+	r.checkNotReleased()
+	pool, buf := r.pool, r.buf
+
+	h := r.Headers
+	for i := range h {
+		h[i] = RecordHeader{}
+	}
+	*r = Record{Headers: h[:0]}
+	if buf != nil {
+		buf.release()
+	}
+	if poolDebug {
+		r.pool, r.released = pool, true // kept out of the pool so that reuse is caught
+		return
+	}
+	pool.records.Put(r)
+}
+
+// checkNotReleased panics in debug builds if a pooled record was released.
+func (r *Record) checkNotReleased() {
+	if poolDebug && r.released {
+		panic("kgo: use of a Record after it was released")
+	}
+}
+
+// Recycle releases every record in the fetches; see Record.Release and
+// PooledRecords. Records that are decoded while iterating with
+// LazyRecordDecoding are not tracked by the fetches and must be released
+// individually.
+func (fs Fetches) Recycle() {
+	for i := range fs {
+		for j := range fs[i].Topics {
+			for k := range fs[i].Topics[j].Partitions {
+				p := &fs[i].Topics[j].Partitions[k]
+				for _, r := range p.Records {
+					r.Release()
+				}
+				for _, b := range p.batches {
+					for _, r := range b.records {
+						r.Release()
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build kgo_debug
+// +build kgo_debug
+
+package kgo
+
+// poolDebug enables use after release checks for pooled records.
+const poolDebug = true
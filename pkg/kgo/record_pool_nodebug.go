@@ -0,0 +1,8 @@
+//go:build !kgo_debug
+// +build !kgo_debug
+
+package kgo
+
+// poolDebug enables use after release checks for pooled records; build with
+// the kgo_debug tag to enable.
+const poolDebug = false
@@ -20,6 +20,7 @@ import (
 type sink struct {
 	cl     *Client // our owning client, for cfg, metadata triggering, context, etc.
 	nodeID int32   // the node ID of the broker this sink belongs to
+	cxnIdx int     // which of the broker's produce connections this sink uses
 
 	// inflightSem controls the number of concurrent produce requests.  We
 	// start with a limit of 1, which covers Kafka v0.11.0. On the first
@@ -58,10 +59,11 @@ type seqResp struct {
 	promise func(*broker, kmsg.Response, error)
 }
 
-func (cl *Client) newSink(nodeID int32) *sink {
+func (cl *Client) newSink(nodeID int32, cxnIdx int) *sink {
 	s := &sink{
 		cl:     cl,
 		nodeID: nodeID,
+		cxnIdx: cxnIdx,
 	}
 	s.produceVersion.Store(-1)
 	maxInflight := 1
@@ -76,16 +78,18 @@ func (cl *Client) newSink(nodeID int32) *sink {
 // and whether there are more records to create more requests immediately.
 func (s *sink) createReq(id int64, epoch int16) (*produceRequest, *kmsg.AddPartitionsToTxnRequest, bool) {
 	req := &produceRequest{
-		txnID:   s.cl.cfg.txnID,
-		acks:    s.cl.cfg.acks.val,
-		timeout: int32(s.cl.cfg.produceTimeout.Milliseconds()),
-		batches: make(seqRecBatches, 5),
+		cxnIdx:    s.cxnIdx,
+		txnID:     s.cl.cfg.txnID,
+		namespace: s.cl.cfg.namespace,
+		acks:      s.cl.cfg.acks.val,
+		timeout:   int32(s.cl.cfg.produceTimeout.Milliseconds()),
+		batches:   make(seqRecBatches, 5),
 
 		producerID:    id,
 		producerEpoch: epoch,
 
 		hasHook:    s.cl.producer.hasHookBatchWritten,
-		compressor: s.cl.compressor,
+		compressor: s.cl.loadDyn().compressor,
 
 		wireLength:      s.cl.baseProduceRequestLength(), // start length with no topics
 		wireLengthLimit: s.cl.cfg.maxBrokerWriteBytes,
@@ -202,7 +206,7 @@ func (s *sink) maybeBackoff() {
 	s.cl.triggerUpdateMetadata(false, "opportunistic load during sink backoff") // as good a time as any
 
 	tries := int(s.consecutiveFailures.Add(1))
-	after := time.NewTimer(s.cl.cfg.retryBackoff(tries))
+	after := time.NewTimer(s.cl.loadDyn().retryBackoff(tries))
 	defer after.Stop()
 
 	select {
@@ -734,7 +738,7 @@ func (s *sink) handleReqRespBatch(
 	case kerr.IsRetriable(err) &&
 		!failUnknown &&
 		err != kerr.CorruptMessage &&
-		batch.tries < s.cl.cfg.recordRetries:
+		batch.tries < s.cl.cfg.recordRetries.load():
 
 		if debug {
 			fmt.Fprintf(b, "retrying@%d,%d(%s)}, ", rp.BaseOffset, nrec, err)
@@ -846,7 +850,7 @@ func (s *sink) handleReqRespBatch(
 				"partition", rp.Partition,
 				"err", err,
 				"err_is_retryable", kerr.IsRetriable(err),
-				"max_retries_reached", !failUnknown && batch.tries >= s.cl.cfg.recordRetries,
+				"max_retries_reached", !failUnknown && batch.tries >= s.cl.cfg.recordRetries.load(),
 			)
 		} else {
 			batch.owner.okOnSink = true
@@ -1212,7 +1216,7 @@ func (recBuf *recBuf) bufferRecord(pr promisedRec, abortOnNewBatch bool) bool {
 		recBuf.batches = append(recBuf.batches, newBatch)
 	}
 
-	if recBuf.cl.cfg.linger == 0 {
+	if recBuf.cl.cfg.linger.load() == 0 {
 		if onDrainBatch {
 			recBuf.sink.maybeDrain()
 		}
@@ -1248,7 +1252,7 @@ func (recBuf *recBuf) bufferRecord(pr promisedRec, abortOnNewBatch bool) bool {
 // lingering, then we are flushing and also indicate there is more to drain.
 func (recBuf *recBuf) tryStopLingerForDraining() bool {
 	recBuf.lockedStopLinger()
-	canLinger := recBuf.cl.cfg.linger == 0
+	canLinger := recBuf.cl.cfg.linger.load() == 0
 	moreToDrain := !canLinger && len(recBuf.batches) > recBuf.batchDrainIdx ||
 		canLinger && (len(recBuf.batches) > recBuf.batchDrainIdx+1 ||
 			len(recBuf.batches) == recBuf.batchDrainIdx+1 && !recBuf.lockedMaybeStartLinger())
@@ -1260,7 +1264,7 @@ func (recBuf *recBuf) lockedMaybeStartLinger() bool {
 	if recBuf.cl.producer.flushing.Load() > 0 || recBuf.cl.producer.blocked.Load() > 0 {
 		return false
 	}
-	recBuf.lingering = time.AfterFunc(recBuf.cl.cfg.linger, recBuf.sink.maybeDrain)
+	recBuf.lingering = time.AfterFunc(time.Duration(recBuf.cl.cfg.linger.load()), recBuf.sink.maybeDrain)
 	return true
 }
 
@@ -1431,7 +1435,7 @@ func (b *recBatch) maybeFailErr(cfg *cfg) error {
 	switch {
 	case b.isTimedOut(cfg.recordTimeout):
 		return ErrRecordTimeout
-	case b.tries >= cfg.recordRetries:
+	case b.tries >= cfg.recordRetries.load():
 		return ErrRecordRetries
 	case b.owner.cl.producer.isAborting():
 		return ErrAborting
@@ -1547,13 +1551,15 @@ func (b *recBatch) decInflight() {
 // It is the same as kmsg.ProduceRequest, but with a custom AppendTo.
 type produceRequest struct {
 	version int16
+	cxnIdx  int // which of the broker's produce connections to use
 
 	backoffSeq uint32
 
-	txnID   *string
-	acks    int16
-	timeout int32
-	batches seqRecBatches
+	txnID     *string
+	namespace string // prefixed to the transactional ID and topics; see Namespace
+	acks      int16
+	timeout   int32
+	batches   seqRecBatches
 
 	producerID    int64
 	producerEpoch int16
@@ -1608,9 +1614,9 @@ func (p *produceRequest) tryAddBatch(produceVersion int32, recBuf *recBuf, batch
 	batchWireLength += 4 // int32 partition prefix
 
 	if partitions, exists := p.batches[recBuf.topic]; !exists {
-		lt := int32(len(recBuf.topic))
+		lt := int32(len(p.namespace) + len(recBuf.topic))
 		if flexible {
-			batchWireLength += uvarlen(len(recBuf.topic)) + lt + 1 // compact string len, topic, compact array len for 1 item
+			batchWireLength += uvarlen(int(lt)) + lt + 1 // compact string len, topic, compact array len for 1 item
 		} else {
 			batchWireLength += 2 + lt + 4 // string len, topic, partition array len
 		}
@@ -1751,7 +1757,7 @@ func (cl *Client) baseProduceRequestLength() int32 {
 		baseLength += int32(len(*cl.cfg.id))
 	}
 	if cl.cfg.txnID != nil {
-		baseLength += int32(len(*cl.cfg.txnID))
+		baseLength += int32(len(cl.cfg.namespace) + len(*cl.cfg.txnID))
 	}
 	return baseLength
 }
@@ -1925,10 +1931,14 @@ func (p *produceRequest) AppendTo(dst []byte) []byte {
 	}
 
 	if p.version >= 3 {
+		txnID := p.txnID
+		if txnID != nil && p.namespace != "" {
+			txnID = kmsg.StringPtr(p.namespace + *txnID)
+		}
 		if flexible {
-			dst = kbin.AppendCompactNullableString(dst, p.txnID)
+			dst = kbin.AppendCompactNullableString(dst, txnID)
 		} else {
-			dst = kbin.AppendNullableString(dst, p.txnID)
+			dst = kbin.AppendNullableString(dst, txnID)
 		}
 	}
 
@@ -1941,11 +1951,12 @@ func (p *produceRequest) AppendTo(dst []byte) []byte {
 	}
 
 	for topic, partitions := range p.batches {
+		wireTopic := p.namespace + topic
 		if flexible {
-			dst = kbin.AppendCompactString(dst, topic)
+			dst = kbin.AppendCompactString(dst, wireTopic)
 			dst = kbin.AppendCompactArrayLen(dst, len(partitions))
 		} else {
-			dst = kbin.AppendString(dst, topic)
+			dst = kbin.AppendString(dst, wireTopic)
 			dst = kbin.AppendArrayLen(dst, len(partitions))
 		}
 
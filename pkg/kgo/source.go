@@ -26,6 +26,7 @@ type readerFrom interface {
 type source struct {
 	cl     *Client // our owning client, for cfg, metadata triggering, context, etc.
 	nodeID int32   // the node ID of the broker this sink belongs to
+	cxnIdx int     // which of the broker's fetch connections this source uses
 
 	// Tracks how many _failed_ fetch requests we have in a row (unable to
 	// receive a response). Any response, even responses with an ErrorCode
@@ -43,10 +44,11 @@ type source struct {
 	cursorsStart int       // incremented every fetch req to ensure all partitions are fetched
 }
 
-func (cl *Client) newSource(nodeID int32) *source {
+func (cl *Client) newSource(nodeID int32, cxnIdx int) *source {
 	s := &source{
 		cl:     cl,
 		nodeID: nodeID,
+		cxnIdx: cxnIdx,
 		sem:    make(chan struct{}),
 	}
 	if cl.cfg.disableFetchSessions {
@@ -98,7 +100,8 @@ type cursor struct {
 
 	unknownIDFails atomicI32
 
-	keepControl bool // whether to keep control records
+	keepControl bool               // whether to keep control records
+	filter      func(*Record) bool // if non-nil, drops records that return false
 
 	cursorsIdx int // updated under source mutex
 
@@ -113,6 +116,11 @@ type cursor struct {
 	// transitioning from used to usable.
 	source *source
 
+	// sourceNode mirrors source.nodeID and is updated whenever source is
+	// changed, allowing the node to be read outside of the session (i.e.,
+	// for ConsumerState) without racing with a preferred replica move.
+	sourceNode atomicI32
+
 	// useState is an atomic that has two states: unusable and usable. A
 	// cursor can be used in a fetch request if it is in the usable state.
 	// Once used, the cursor is unusable, and will be set back to usable
@@ -238,6 +246,20 @@ type cursorOffsetPreferred struct {
 // a fetch response, which means within the context of a live session.
 func (p *cursorOffsetPreferred) move() {
 	c := p.from
+
+	// If the preferred replica is degraded, we keep fetching from where
+	// we are. The leader will redirect us again on our next fetch, so we
+	// wait a retry backoff before fetching to avoid spinning.
+	if cl := c.source.cl; cl.cfg.breakerFailures > 0 {
+		cl.brokersMu.RLock()
+		b := findBroker(cl.brokers, p.preferredReplica)
+		cl.brokersMu.RUnlock()
+		if b != nil && b.isDegraded() {
+			time.AfterFunc(cl.loadDyn().retryBackoff(1), c.allowUsable)
+			return
+		}
+	}
+
 	defer c.allowUsable()
 
 	// Before we migrate the cursor, we check if the destination source
@@ -256,10 +278,17 @@ func (p *cursorOffsetPreferred) move() {
 	// we will not have a buffered fetch since moving replicas is called
 	// before buffering a fetch.
 	c.source.removeCursor(c)
-	c.source = sns.source
+	c.setSource(sns.source(c.topic, c.partition))
 	c.source.addCursor(c)
 }
 
+// setSource sets the cursor's source and the atomically readable node ID of
+// that source.
+func (c *cursor) setSource(s *source) {
+	c.source = s
+	c.sourceNode.Store(s.nodeID)
+}
+
 type cursorPreferreds []cursorOffsetPreferred
 
 func (cs cursorPreferreds) eachPreferred(fn func(cursorOffsetPreferred)) {
@@ -333,10 +362,13 @@ func (s *source) hook(f *Fetch, buffered, polled bool) {
 		t := &f.Topics[i]
 		for j := range t.Partitions {
 			p := &t.Partitions[j]
-			nrecs += len(p.Records)
+			nrecs += p.numRecords()
 			for k := range p.Records {
 				nbytes += p.Records[k].userSize()
 			}
+			for k := range p.batches {
+				nbytes += p.batches[k].size()
+			}
 		}
 	}
 	if buffered {
@@ -426,25 +458,30 @@ func (s *source) discardBuffered() {
 }
 
 // takeNBuffered takes a limited amount of records from a buffered fetch,
-// updating offsets in each partition per records taken.
+// updating offsets in each partition per records taken. If limit is non-nil,
+// it further limits the records taken per partition; partitions that are
+// limited to zero records are skipped and kept buffered.
 //
 // This only allows a new fetch once every buffered record has been taken.
 //
 // This returns the number of records taken and whether the source has been
 // completely drained.
-func (s *source) takeNBuffered(paused pausedTopics, n int) (Fetch, int, bool) {
+func (s *source) takeNBuffered(paused pausedTopics, n int, limit func(string, int32) int) (Fetch, int, bool) {
 	var r Fetch
 	var taken int
 
 	b := &s.buffered
 	bf := &b.fetch
-	for len(bf.Topics) > 0 && n > 0 {
-		t := &bf.Topics[0]
+	keept := bf.Topics[:0]
+	for _, t := range bf.Topics {
+		if n <= 0 {
+			keept = append(keept, t)
+			continue
+		}
 
 		// If the topic is outright paused, we allowUsable all
 		// partitions in the topic and skip the topic entirely.
 		if paused.has(t.Topic, -1) {
-			bf.Topics = bf.Topics[1:]
 			for _, pCursor := range b.usedOffsets[t.Topic] {
 				pCursor.from.allowUsable()
 			}
@@ -457,18 +494,21 @@ func (s *source) takeNBuffered(paused pausedTopics, n int) (Fetch, int, bool) {
 			if rt != nil {
 				return
 			}
-			r.Topics = append(r.Topics, *t)
+			r.Topics = append(r.Topics, t)
 			rt = &r.Topics[len(r.Topics)-1]
 			rt.Partitions = nil
 		}
 
 		tCursors := b.usedOffsets[t.Topic]
 
-		for len(t.Partitions) > 0 && n > 0 {
-			p := &t.Partitions[0]
+		keepp := t.Partitions[:0]
+		for _, p := range t.Partitions {
+			if n <= 0 {
+				keepp = append(keepp, p)
+				continue
+			}
 
 			if paused.has(t.Topic, p.Partition) {
-				t.Partitions = t.Partitions[1:]
 				pCursor := tCursors[p.Partition]
 				pCursor.from.allowUsable()
 				delete(tCursors, p.Partition)
@@ -478,26 +518,50 @@ func (s *source) takeNBuffered(paused pausedTopics, n int) (Fetch, int, bool) {
 				continue
 			}
 
+			max := n
+			if limit != nil {
+				if l := limit(t.Topic, p.Partition); l < max {
+					max = l
+				}
+				if max <= 0 && p.hasRecords() {
+					keepp = append(keepp, p)
+					continue
+				}
+			}
+
 			ensureTopicAdded()
-			rt.Partitions = append(rt.Partitions, *p)
+			rt.Partitions = append(rt.Partitions, p)
 			rp := &rt.Partitions[len(rt.Partitions)-1]
 
-			take := n
-			if take > len(p.Records) {
-				take = len(p.Records)
+			var take int
+			if len(p.batches) > 0 {
+				// If lazily decoding, we take whole batches,
+				// which may return more than n records.
+				var nb int
+				for nb < len(p.batches) && take < max {
+					take += p.batches[nb].numRecords()
+					nb++
+				}
+				rp.batches = p.batches[:nb:nb]
+				p.batches = p.batches[nb:]
+			} else {
+				take = max
+				if take > len(p.Records) {
+					take = len(p.Records)
+				}
+				rp.Records = p.Records[:take:take]
+				p.Records = p.Records[take:]
+			}
+			if p.hasRecords() {
+				rp.filtered = false // anything filtered is tracked by what remains
 			}
-
-			rp.Records = p.Records[:take:take]
-			p.Records = p.Records[take:]
 
 			n -= take
 			taken += take
 
 			pCursor := tCursors[p.Partition]
 
-			if len(p.Records) == 0 {
-				t.Partitions = t.Partitions[1:]
-
+			if !p.hasRecords() {
 				pCursor.from.setOffset(pCursor.cursorOffset)
 				pCursor.from.allowUsable()
 				delete(tCursors, p.Partition)
@@ -507,19 +571,22 @@ func (s *source) takeNBuffered(paused pausedTopics, n int) (Fetch, int, bool) {
 				continue
 			}
 
-			lastReturnedRecord := rp.Records[len(rp.Records)-1]
+			keepp = append(keepp, p)
+			lastOffset, lastEpoch, lastTime := rp.lastConsumed()
 			pCursor.from.setOffset(cursorOffset{
-				offset:            lastReturnedRecord.Offset + 1,
-				lastConsumedEpoch: lastReturnedRecord.LeaderEpoch,
-				lastConsumedTime:  lastReturnedRecord.Timestamp,
+				offset:            lastOffset + 1,
+				lastConsumedEpoch: lastEpoch,
+				lastConsumedTime:  lastTime,
 				hwm:               p.HighWatermark,
 			})
 		}
 
-		if len(t.Partitions) == 0 {
-			bf.Topics = bf.Topics[1:]
+		if len(keepp) > 0 {
+			t.Partitions = keepp
+			keept = append(keept, t)
 		}
 	}
+	bf.Topics = keept
 
 	s.hook(&r, false, true) // unbuffered, polled
 
@@ -545,6 +612,8 @@ func (s *source) takeBufferedFn(polled bool, offsetFn func(usedOffsets)) Fetch {
 // createReq actually creates a fetch request.
 func (s *source) createReq() *fetchRequest {
 	req := &fetchRequest{
+		cxnIdx:         s.cxnIdx,
+		namespace:      s.cl.cfg.namespace,
 		maxWait:        s.cl.cfg.maxWait,
 		minBytes:       s.cl.cfg.minBytes,
 		maxBytes:       s.cl.cfg.maxBytes.load(),
@@ -552,6 +621,7 @@ func (s *source) createReq() *fetchRequest {
 		rack:           s.cl.cfg.rack,
 		isolationLevel: s.cl.cfg.isolationLevel,
 		preferLagFn:    s.cl.cfg.preferLagFn,
+		priorities:     s.cl.cfg.topicPriorities,
 
 		// We copy a view of the session for the request, which allows
 		// modify source while the request may be reading its copy.
@@ -573,6 +643,17 @@ func (s *source) createReq() *fetchRequest {
 		req.addCursor(c)
 	}
 
+	// With fair partitions, we also rotate partitions within each topic:
+	// the global rotation above only rotates a topic's partitions while
+	// the start is within that topic's cursors.
+	if s.cl.cfg.fairPartitions {
+		for t, ps := range req.porder {
+			if n := s.cursorsStart % len(ps); n > 0 {
+				req.porder[t] = append(ps[n:], ps[:n]...)
+			}
+		}
+	}
+
 	// We could have lost our only record buffer just before we grabbed the
 	// source lock above.
 	if len(s.cursors) > 0 {
@@ -666,6 +747,7 @@ func (s *source) killSessionOnClose(ctx context.Context) {
 	}
 	s.session.kill()
 	req := &fetchRequest{
+		cxnIdx:         s.cxnIdx,
 		maxWait:        1,
 		minBytes:       1,
 		maxBytes:       1,
@@ -673,6 +755,7 @@ func (s *source) killSessionOnClose(ctx context.Context) {
 		rack:           s.cl.cfg.rack,
 		isolationLevel: s.cl.cfg.isolationLevel,
 		session:        s.session,
+		namespace:      s.cl.cfg.namespace,
 	}
 	ch := make(chan struct{})
 	br.do(ctx, req, func(kmsg.Response, error) { close(ch) })
@@ -762,7 +845,7 @@ func (s *source) fetch(consumerSession *consumerSession, doneFetch chan<- struct
 
 		s.cl.triggerUpdateMetadata(false, fmt.Sprintf("opportunistic load during source backoff: %v", why)) // as good a time as any
 		s.consecutiveFailures++
-		after := time.NewTimer(s.cl.cfg.retryBackoff(s.consecutiveFailures))
+		after := time.NewTimer(s.cl.loadDyn().retryBackoff(s.consecutiveFailures))
 		defer after.Stop()
 		select {
 		case <-after.C:
@@ -954,6 +1037,8 @@ func (s *source) handleReqResp(br *broker, req *fetchRequest, resp *kmsg.FetchRe
 		debugWhyStripped.add(t, p, err)
 	}
 
+	processed := s.processRespPartitions(br, req, resp)
+
 	for _, rt := range resp.Topics {
 		topic := rt.Topic
 		// v13 only uses topic IDs, so we have to map the response
@@ -1003,7 +1088,10 @@ func (s *source) handleReqResp(br *broker, req *fetchRequest, resp *kmsg.FetchRe
 				continue
 			}
 
-			fp := partOffset.processRespPartition(br, rp, s.cl.decompressor, s.cl.cfg.hooks)
+			fp, ok := processed[rp]
+			if !ok {
+				fp = partOffset.processRespPartition(br, rp, s.cl.decompressor, s.cl.recordPool, s.cl.cfg.hooks, s.cl.cfg.lazyDecode)
+			}
 			if fp.Err != nil {
 				if moving := kmove.maybeAddFetchPartition(resp, rp, partOffset.from); moving {
 					strip(topic, partition, fp.Err)
@@ -1180,7 +1268,7 @@ func (s *source) handleReqResp(br *broker, req *fetchRequest, resp *kmsg.FetchRe
 
 // processRespPartition processes all records in all potentially compressed
 // batches (or message sets).
-func (o *cursorOffsetNext) processRespPartition(br *broker, rp *kmsg.FetchResponseTopicPartition, decompressor *decompressor, hooks hooks) FetchPartition {
+func (o *cursorOffsetNext) processRespPartition(br *broker, rp *kmsg.FetchResponseTopicPartition, decompressor *decompressor, pool *recordPool, hooks hooks, lazy bool) FetchPartition {
 	fp := FetchPartition{
 		Partition:        rp.Partition,
 		Err:              kerr.ErrorForCode(rp.ErrorCode),
@@ -1309,7 +1397,18 @@ func (o *cursorOffsetNext) processRespPartition(br *broker, rp *kmsg.FetchRespon
 		case *kmsg.RecordBatch:
 			m.CompressedBytes = len(t.Records) // for record batches, we only track the record batch length
 			m.CompressionType = uint8(t.Attributes) & 0b0000_0111
-			m.NumRecords, m.UncompressedBytes = o.processRecordBatch(&fp, t, aborter, decompressor)
+			if lazy {
+				m.NumRecords, m.UncompressedBytes = o.processLazyRecordBatch(&fp, t, aborter, decompressor, pool)
+			} else {
+				m.NumRecords, m.UncompressedBytes = o.processRecordBatch(&fp, t, aborter, decompressor, pool)
+			}
+		}
+
+		// If we are lazily decoding, anything we had to decode
+		// immediately is kept in order with the lazy batches.
+		if lazy && len(fp.Records) > 0 {
+			fp.batches = append(fp.batches, fetchBatch{records: fp.Records})
+			fp.Records = nil
 		}
 
 		if m.UncompressedBytes == 0 {
@@ -1325,6 +1424,72 @@ func (o *cursorOffsetNext) processRespPartition(br *broker, rp *kmsg.FetchRespon
 	return fp
 }
 
+// processRespPartitions processes the partitions in a fetch response in
+// parallel if FetchDecodeParallelism allows it, returning nil if partitions
+// should be processed serially in handleReqResp. Each partition's cursor
+// offset is only modified by the one goroutine processing that partition.
+func (s *source) processRespPartitions(br *broker, req *fetchRequest, resp *kmsg.FetchResponse) map[*kmsg.FetchResponseTopicPartition]FetchPartition {
+	workers := s.cl.cfg.decodeParallelism
+	if workers <= 1 {
+		return nil
+	}
+
+	type job struct {
+		rp *kmsg.FetchResponseTopicPartition
+		o  *cursorOffsetNext
+	}
+	var jobs []job
+	for i := range resp.Topics {
+		rt := &resp.Topics[i]
+		topic := rt.Topic
+		if resp.Version >= 13 {
+			topic = req.id2topic[rt.TopicID]
+		}
+		topicOffsets := req.usedOffsets[topic]
+		for j := range rt.Partitions {
+			rp := &rt.Partitions[j]
+			partOffset, ok := topicOffsets[rp.Partition]
+			if !ok || resp.Version >= 11 && rp.PreferredReadReplica >= 0 || len(rp.RecordBatches) == 0 {
+				continue // skipped or cheaply processed in handleReqResp
+			}
+			jobs = append(jobs, job{rp, partOffset})
+		}
+	}
+	if len(jobs) < 2 {
+		return nil
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+
+	var (
+		results = make([]FetchPartition, len(jobs))
+		next    atomicI64
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1)) - 1
+				if i >= len(jobs) {
+					return
+				}
+				j := jobs[i]
+				results[i] = j.o.processRespPartition(br, j.rp, s.cl.decompressor, s.cl.recordPool, s.cl.cfg.hooks, s.cl.cfg.lazyDecode)
+			}
+		}()
+	}
+	wg.Wait()
+
+	processed := make(map[*kmsg.FetchResponseTopicPartition]FetchPartition, len(jobs))
+	for i, j := range jobs {
+		processed[j.rp] = results[i]
+	}
+	return processed
+}
+
 type aborter map[int64][]int64
 
 func buildAborter(rp *kmsg.FetchResponseTopicPartition) aborter {
@@ -1390,6 +1555,7 @@ func (o *cursorOffsetNext) processRecordBatch(
 	batch *kmsg.RecordBatch,
 	aborter aborter,
 	decompressor *decompressor,
+	pool *recordPool,
 ) (int, int) {
 	if batch.Magic != 2 {
 		fp.Err = fmt.Errorf("unknown batch magic %d", batch.Magic)
@@ -1403,12 +1569,12 @@ func (o *cursorOffsetNext) processRecordBatch(
 		return 0, 0
 	}
 
-	rawRecords := batch.Records
-	if compression := byte(batch.Attributes & 0x0007); compression != 0 {
-		var err error
-		if rawRecords, err = decompressor.decompress(rawRecords, compression); err != nil {
-			return 0, 0 // truncated batch
-		}
+	rawRecords, buf, err := decompressBatch(batch, decompressor, pool)
+	if err != nil {
+		return 0, 0 // truncated batch
+	}
+	if buf != nil {
+		defer buf.release()
 	}
 
 	uncompressedBytes := len(rawRecords)
@@ -1435,13 +1601,14 @@ func (o *cursorOffsetNext) processRecordBatch(
 
 	abortBatch := aborter.shouldAbortBatch(batch)
 	for i := range krecords {
-		record := recordToRecord(
+		record := newFetchedRecord(
+			pool,
+			buf,
 			o.from.topic,
 			fp.Partition,
 			batch,
 			&krecords[i],
 		)
-		o.maybeKeepRecord(fp, record, abortBatch)
 
 		if abortBatch && record.Attrs.IsControl() {
 			// A control record has a key and a value where the key
@@ -1451,11 +1618,126 @@ func (o *cursorOffsetNext) processRecordBatch(
 				aborter.trackAbortedPID(batch.ProducerID)
 			}
 		}
+
+		o.maybeKeepRecord(fp, record, abortBatch)
 	}
 
 	return len(krecords), uncompressedBytes
 }
 
+// processLazyRecordBatch validates a record batch and keeps it to be decoded
+// when iterated, advancing our offset past the batch. Control batches and
+// aborted batches are processed immediately: control batches are small and
+// are needed to track aborted transactions, and aborted batches are dropped.
+//
+// Once we advance past a batch, the batch's offsets can be committed, so a
+// batch should not fail to decode when iterated. Rather than decompress here,
+// we check the batch's compression framing (the batch's CRC was already
+// checked); a batch with invalid framing is processed immediately, exactly as
+// when not lazily decoding.
+func (o *cursorOffsetNext) processLazyRecordBatch(
+	fp *FetchPartition,
+	batch *kmsg.RecordBatch,
+	aborter aborter,
+	decompressor *decompressor,
+	pool *recordPool,
+) (int, int) {
+	if batch.Magic != 2 {
+		fp.Err = fmt.Errorf("unknown batch magic %d", batch.Magic)
+		return 0, 0
+	}
+	if batch.Attributes&0b0010_0000 != 0 || aborter.shouldAbortBatch(batch) {
+		return o.processRecordBatch(fp, batch, aborter, decompressor, pool)
+	}
+	lastOffset := batch.FirstOffset + int64(batch.LastOffsetDelta)
+	if lastOffset < o.offset {
+		return 0, 0
+	}
+	if err := checkFraming(batch.Records, byte(batch.Attributes&0x0007)); err != nil {
+		return o.processRecordBatch(fp, batch, aborter, decompressor, pool)
+	}
+
+	fp.batches = append(fp.batches, fetchBatch{
+		topic:     o.from.topic,
+		partition: fp.Partition,
+		batch:     batch,
+		skipBelow: o.offset,
+		d:         decompressor,
+		pool:      pool,
+		filter:    o.from.filter,
+	})
+	o.offset = lastOffset + 1
+	o.lastConsumedEpoch = batch.PartitionLeaderEpoch
+	o.lastConsumedTime = timeFromMillis(batch.MaxTimestamp)
+	return int(batch.NumRecords), 0 // uncompressed bytes are unknown
+}
+
+// fetchBatch is either a record batch that is decoded when iterated, or
+// records that were decoded when the fetch was processed.
+type fetchBatch struct {
+	topic     string
+	partition int32
+	batch     *kmsg.RecordBatch
+	skipBelow int64 // records before our fetch offset are skipped
+	d         *decompressor
+	pool      *recordPool
+	filter    func(*Record) bool
+
+	records []*Record // if batch is nil
+}
+
+func (b *fetchBatch) decode() []*Record {
+	if b.batch == nil {
+		return b.records
+	}
+	// The batch's framing was checked when it was buffered, but its
+	// compressed data can still be corrupt, in which case we have no
+	// records to return; see processLazyRecordBatch.
+	rawRecords, buf, _ := decompressBatch(b.batch, b.d, b.pool)
+	if buf != nil {
+		defer buf.release()
+	}
+	krecords := readRawRecords(int(b.batch.NumRecords), rawRecords)
+	rs := make([]*Record, 0, len(krecords))
+	for i := range krecords {
+		r := newFetchedRecord(b.pool, buf, b.topic, b.partition, b.batch, &krecords[i])
+		if r.Offset < b.skipBelow || b.filter != nil && !b.filter(r) {
+			r.Release()
+			continue
+		}
+		rs = append(rs, r)
+	}
+	return rs
+}
+
+func (b *fetchBatch) numRecords() int {
+	if b.batch == nil {
+		return len(b.records)
+	}
+	return int(b.batch.NumRecords)
+}
+
+// size returns the compressed size of a lazy batch, or the user size of
+// decoded records.
+func (b *fetchBatch) size() int64 {
+	if b.batch == nil {
+		var n int64
+		for _, r := range b.records {
+			n += r.userSize()
+		}
+		return n
+	}
+	return int64(len(b.batch.Records))
+}
+
+func (b *fetchBatch) last() (int64, int32, time.Time) {
+	if b.batch == nil {
+		r := b.records[len(b.records)-1]
+		return r.Offset, r.LeaderEpoch, r.Timestamp
+	}
+	return b.batch.FirstOffset + int64(b.batch.LastOffsetDelta), b.batch.PartitionLeaderEpoch, timeFromMillis(b.batch.MaxTimestamp)
+}
+
 // Processes an outer v1 message. There could be no inner message, which makes
 // this easy, but if not, we decompress and process each inner message as
 // either v0 or v1. We only expect the inner message to be v1, but technically
@@ -1653,6 +1935,7 @@ func (o *cursorOffsetNext) maybeKeepRecord(fp *FetchPartition, record *Record, a
 	if record.Offset < o.offset {
 		// We asked for offset 5, but that was in the middle of a
 		// batch; we got offsets 0 thru 4 that we need to skip.
+		record.Release()
 		return
 	}
 
@@ -1660,15 +1943,28 @@ func (o *cursorOffsetNext) maybeKeepRecord(fp *FetchPartition, record *Record, a
 	if record.Attrs.IsControl() {
 		abort = !o.from.keepControl
 	}
-	if !abort {
-		fp.Records = append(fp.Records, record)
+	if !abort && o.from.filter != nil && !o.from.filter(record) {
+		abort = true
+		fp.filtered = true
+		fp.lastFiltered = cursorOffset{
+			offset:            record.Offset,
+			lastConsumedEpoch: record.LeaderEpoch,
+			lastConsumedTime:  record.Timestamp,
+		}
 	}
 
 	// The record offset may be much larger than our expected offset if the
-	// topic is compacted.
+	// topic is compacted. We update our position before releasing a
+	// dropped record, since a released pooled record can be reused.
 	o.offset = record.Offset + 1
 	o.lastConsumedEpoch = record.LeaderEpoch
 	o.lastConsumedTime = record.Timestamp
+
+	if !abort {
+		fp.Records = append(fp.Records, record)
+	} else {
+		record.Release()
+	}
 }
 
 ///////////////////////////////
@@ -1679,6 +1975,46 @@ func timeFromMillis(millis int64) time.Time {
 	return time.Unix(0, millis*1e6)
 }
 
+// decompressBatch returns the decompressed records of a batch. If pooling
+// and the batch is compressed, the records are decompressed into a pooled
+// buffer, which is returned with one reference that the caller must release.
+func decompressBatch(batch *kmsg.RecordBatch, decompressor *decompressor, pool *recordPool) ([]byte, *pooledBuf, error) {
+	compression := byte(batch.Attributes & 0x0007)
+	if compression == 0 {
+		return batch.Records, nil, nil
+	}
+	if pool == nil {
+		raw, err := decompressor.decompress(batch.Records, compression)
+		return raw, nil, err
+	}
+	buf := pool.getBuf()
+	raw, err := decompressor.decompressTo(buf.b, batch.Records, compression)
+	if err != nil {
+		buf.release()
+		return nil, nil, err
+	}
+	buf.b = raw
+	return raw, buf, nil
+}
+
+// newFetchedRecord converts a kmsg.RecordBatch's Record to a kgo Record,
+// using a pooled record if pool is non-nil.
+func newFetchedRecord(
+	pool *recordPool,
+	buf *pooledBuf,
+	topic string,
+	partition int32,
+	batch *kmsg.RecordBatch,
+	record *kmsg.Record,
+) *Record {
+	if pool == nil {
+		return recordToRecord(topic, partition, batch, record)
+	}
+	r := pool.newRecord(buf)
+	fillRecord(r, topic, partition, batch, record)
+	return r
+}
+
 // recordToRecord converts a kmsg.RecordBatch's Record to a kgo Record.
 func recordToRecord(
 	topic string,
@@ -1686,7 +2022,24 @@ func recordToRecord(
 	batch *kmsg.RecordBatch,
 	record *kmsg.Record,
 ) *Record {
-	h := make([]RecordHeader, 0, len(record.Headers))
+	r := new(Record)
+	fillRecord(r, topic, partition, batch, record)
+	return r
+}
+
+// fillRecord fills r from a kmsg.RecordBatch's Record, reusing r's headers
+// slice if possible.
+func fillRecord(
+	r *Record,
+	topic string,
+	partition int32,
+	batch *kmsg.RecordBatch,
+	record *kmsg.Record,
+) {
+	h := r.Headers
+	if h == nil || cap(h) < len(record.Headers) {
+		h = make([]RecordHeader, 0, len(record.Headers))
+	}
 	for _, kv := range record.Headers {
 		h = append(h, RecordHeader{
 			Key:   kv.Key,
@@ -1694,7 +2047,7 @@ func recordToRecord(
 		})
 	}
 
-	r := &Record{
+	*r = Record{
 		Key:           record.Key,
 		Value:         record.Value,
 		Headers:       h,
@@ -1705,13 +2058,19 @@ func recordToRecord(
 		ProducerEpoch: batch.ProducerEpoch,
 		LeaderEpoch:   batch.PartitionLeaderEpoch,
 		Offset:        batch.FirstOffset + int64(record.OffsetDelta),
+
+		sequence: -1,
+		pool:     r.pool,
+		buf:      r.buf,
+	}
+	if batch.FirstSequence >= 0 {
+		r.sequence = batch.FirstSequence + record.OffsetDelta
 	}
 	if r.Attrs.TimestampType() == 0 {
 		r.Timestamp = timeFromMillis(batch.FirstTimestamp + record.TimestampDelta64)
 	} else {
 		r.Timestamp = timeFromMillis(batch.MaxTimestamp)
 	}
-	return r
 }
 
 func messageAttrsToRecordAttrs(attrs int8, v0 bool) RecordAttrs {
@@ -1765,6 +2124,8 @@ func v1MessageToRecord(
 
 type fetchRequest struct {
 	version      int16
+	cxnIdx       int    // which of the broker's fetch connections to use
+	namespace    string // prefixed to topics; see Namespace
 	maxWait      int32
 	minBytes     int32
 	maxBytes     int32
@@ -1773,6 +2134,7 @@ type fetchRequest struct {
 
 	isolationLevel int8
 	preferLagFn    PreferLagFn
+	priorities     map[string]int
 
 	numOffsets  int
 	usedOffsets usedOffsets
@@ -2032,7 +2394,20 @@ func (f *fetchRequest) adjustPreferringLag() {
 	}
 }
 
+// If any topic priorities are configured, we stable sort our topic order so
+// that higher priority topics are requested first. Within a priority, the
+// rotated (or lag preferred) order is kept.
+func (f *fetchRequest) adjustPriorities() {
+	if len(f.priorities) == 0 {
+		return
+	}
+	sort.SliceStable(f.torder, func(i, j int) bool {
+		return f.priorities[f.torder[i]] > f.priorities[f.torder[j]]
+	})
+}
+
 func (*fetchRequest) Key() int16 { return 1 }
+
 func (f *fetchRequest) MaxVersion() int16 {
 	if f.disableIDs || f.session.disableIDs {
 		return 12
@@ -2063,6 +2438,7 @@ func (f *fetchRequest) AppendTo(dst []byte) []byte {
 	}
 
 	f.adjustPreferringLag()
+	f.adjustPriorities()
 
 	for _, topic := range f.torder {
 		partitions := f.usedOffsets[topic]
@@ -2154,6 +2530,15 @@ func (f *fetchRequest) AppendTo(dst []byte) []byte {
 		}
 	}
 
+	if f.namespace != "" {
+		for i := range req.Topics {
+			req.Topics[i].Topic = f.namespace + req.Topics[i].Topic
+		}
+		for i := range req.ForgottenTopics {
+			req.ForgottenTopics[i].Topic = f.namespace + req.ForgottenTopics[i].Topic
+		}
+	}
+
 	return req.AppendTo(dst)
 }
 
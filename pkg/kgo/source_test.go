@@ -0,0 +1,514 @@
+package kgo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// fetchedBatch returns a wire encoded record batch of n records starting at
+// firstOffset, with each record's value being its offset.
+func fetchedBatch(t *testing.T, firstOffset int64, n int, gz bool) []byte {
+	var raw []byte
+	for i := 0; i < n; i++ {
+		r := kmsg.Record{
+			OffsetDelta: int32(i),
+			Value:       []byte(strconv.FormatInt(firstOffset+int64(i), 10)),
+		}
+		r.Length = int32(len(r.AppendTo(nil)) - 1) // everything after the one byte length
+		raw = r.AppendTo(raw)
+	}
+	var attrs int16
+	if gz {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(raw); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		raw, attrs = buf.Bytes(), 1
+	}
+	b := kmsg.RecordBatch{
+		FirstOffset:          firstOffset,
+		PartitionLeaderEpoch: 3,
+		Magic:                2,
+		Attributes:           attrs,
+		LastOffsetDelta:      int32(n - 1),
+		FirstTimestamp:       1000,
+		MaxTimestamp:         1000,
+		ProducerID:           -1,
+		ProducerEpoch:        -1,
+		FirstSequence:        -1,
+		NumRecords:           int32(n),
+		Records:              raw,
+	}
+	wire := b.AppendTo(nil)
+	binary.BigEndian.PutUint32(wire[8:], uint32(len(wire)-12))
+	binary.BigEndian.PutUint32(wire[17:], crc32.Checksum(wire[21:], crc32c))
+	return wire
+}
+
+func TestLazyRecordDecoding(t *testing.T) {
+	t.Parallel()
+
+	rp := &kmsg.FetchResponseTopicPartition{
+		Partition:     0,
+		HighWatermark: 10,
+		RecordBatches: append(fetchedBatch(t, 0, 5, false), fetchedBatch(t, 5, 5, true)...),
+	}
+
+	process := func(lazy bool) (FetchPartition, *cursorOffsetNext) {
+		o := &cursorOffsetNext{
+			cursorOffset: cursorOffset{offset: 2, lastConsumedEpoch: -1},
+			from:         &cursor{topic: "foo"},
+		}
+		return o.processRespPartition(&broker{}, rp, newDecompressor(), nil, nil, lazy), o
+	}
+	values := func(fp *FetchPartition) []string {
+		var vs []string
+		for iter := fp.RecordIter(); !iter.Done(); {
+			vs = append(vs, string(iter.Next().Value))
+		}
+		return vs
+	}
+
+	eager, eo := process(false)
+	lazy, lo := process(true)
+	if eager.Err != nil || lazy.Err != nil {
+		t.Fatalf("unexpected errors: eager %v, lazy %v", eager.Err, lazy.Err)
+	}
+	if len(lazy.Records) != 0 || len(lazy.batches) != 2 {
+		t.Fatalf("lazy: got %d records and %d batches, expected 0 and 2", len(lazy.Records), len(lazy.batches))
+	}
+	if eo.offset != 10 || lo.offset != 10 || lo.lastConsumedEpoch != 3 {
+		t.Errorf("got eager offset %d, lazy offset %d and epoch %d, expected 10, 10, and 3", eo.offset, lo.offset, lo.lastConsumedEpoch)
+	}
+
+	exp := []string{"2", "3", "4", "5", "6", "7", "8", "9"}
+	for _, got := range [][]string{values(&eager), values(&lazy), values(&lazy)} {
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("got values %v, expected %v", got, exp)
+		}
+	}
+
+	fs := Fetches{{Topics: []FetchTopic{{Topic: "foo", Partitions: []FetchPartition{lazy, lazy}}}}}
+	var n int
+	fs.EachRecord(func(*Record) { n++ })
+	if n != 16 {
+		t.Errorf("got %d records iterating fetches, expected 16", n)
+	}
+	if offset, epoch, _ := lazy.lastConsumed(); offset != 9 || epoch != 3 {
+		t.Errorf("got last consumed offset %d epoch %d, expected 9 and 3", offset, epoch)
+	}
+
+	// A batch with invalid compression framing must not be skipped (and
+	// then committed past): as when eagerly decoding, we do not advance.
+	corrupt := fetchedBatch(t, 10, 5, true)
+	corrupt[61] ^= 0xff // the first byte of the gzip header
+	binary.BigEndian.PutUint32(corrupt[17:], crc32.Checksum(corrupt[21:], crc32c))
+	rp.RecordBatches = append(rp.RecordBatches, corrupt...)
+	rp.HighWatermark = 15
+	eager, eo = process(false)
+	lazy, lo = process(true)
+	if eo.offset != 10 || lo.offset != 10 {
+		t.Errorf("got eager offset %d and lazy offset %d with a corrupt batch, expected 10 and 10", eo.offset, lo.offset)
+	}
+	for _, got := range [][]string{values(&eager), values(&lazy)} {
+		if !reflect.DeepEqual(got, exp) {
+			t.Errorf("got values %v with a corrupt batch, expected %v", got, exp)
+		}
+	}
+}
+
+func TestPooledRecords(t *testing.T) {
+	t.Parallel()
+
+	rp := &kmsg.FetchResponseTopicPartition{
+		Partition:     0,
+		HighWatermark: 10,
+		RecordBatches: append(fetchedBatch(t, 0, 5, false), fetchedBatch(t, 5, 5, true)...),
+	}
+	o := &cursorOffsetNext{
+		cursorOffset: cursorOffset{offset: 6, lastConsumedEpoch: -1},
+		from:         &cursor{topic: "foo"},
+	}
+	pool := newRecordPool()
+	fp := o.processRespPartition(&broker{}, rp, newDecompressor(), pool, nil, false)
+	if fp.Err != nil || len(fp.Records) != 4 {
+		t.Fatalf("got err %v and %d records, expected no error and 4 records", fp.Err, len(fp.Records))
+	}
+
+	buf := fp.Records[0].buf
+	for i, r := range fp.Records {
+		if r.pool != pool || r.buf != buf {
+			t.Fatalf("record %d is not pooled in the batch's buffer", i)
+		}
+		if exp := strconv.Itoa(6 + i); string(r.Value) != exp {
+			t.Errorf("record %d: got value %q, expected %q", i, r.Value, exp)
+		}
+	}
+	if refs := buf.refs.Load(); refs != 4 {
+		t.Errorf("got %d buffer references, expected 4 (one per kept record)", refs)
+	}
+
+	fs := Fetches{{Topics: []FetchTopic{{Topic: "foo", Partitions: []FetchPartition{fp}}}}}
+	fs.Recycle()
+	if refs := buf.refs.Load(); refs != 0 {
+		t.Errorf("got %d buffer references after recycling, expected 0", refs)
+	}
+
+	(&Record{Value: []byte("unpooled")}).Release() // no-op
+
+	if poolDebug {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic releasing a record twice")
+				}
+			}()
+			fp.Records[0].Release()
+		}()
+	}
+}
+
+// encodeBatch returns a wire encoded record batch, filling in the records,
+// lengths, and crc.
+func encodeBatch(b kmsg.RecordBatch, rs ...kmsg.Record) []byte {
+	b.Magic = 2
+	b.LastOffsetDelta = int32(len(rs) - 1)
+	b.NumRecords = int32(len(rs))
+	b.PartitionLeaderEpoch = 3
+	b.FirstTimestamp, b.MaxTimestamp = 1000, 1000
+	for i := range rs {
+		rs[i].OffsetDelta = int32(i)
+		rs[i].Length = int32(len(rs[i].AppendTo(nil)) - 1)
+		b.Records = rs[i].AppendTo(b.Records)
+	}
+	wire := b.AppendTo(nil)
+	binary.BigEndian.PutUint32(wire[8:], uint32(len(wire)-12))
+	binary.BigEndian.PutUint32(wire[17:], crc32.Checksum(wire[21:], crc32c))
+	return wire
+}
+
+// TestPooledRecordsDropped checks that our position advances past pooled
+// records that are dropped (and released) while processing a fetch.
+func TestPooledRecordsDropped(t *testing.T) {
+	t.Parallel()
+
+	var (
+		txn     = kmsg.RecordBatch{Attributes: 0b0001_0000, ProducerID: 1}
+		control = kmsg.RecordBatch{Attributes: 0b0011_0000, ProducerID: 1, FirstOffset: 2}
+		plain   = kmsg.RecordBatch{ProducerID: -1}
+		v       = func(s string) kmsg.Record { return kmsg.Record{Value: []byte(s)} }
+		marker  = kmsg.Record{Key: []byte{0, 0, 0, 0}, Value: []byte{0, 0, 0, 0, 0, 0}} // abort
+	)
+
+	for _, test := range []struct {
+		name    string
+		batches [][]byte
+		aborted bool
+		filter  func(*Record) bool
+		exp     []string
+	}{
+		{
+			name:    "aborted",
+			batches: [][]byte{encodeBatch(txn, v("0"), v("1"), v("2"))},
+			aborted: true,
+		},
+		{
+			name:    "control",
+			batches: [][]byte{encodeBatch(txn, v("0"), v("1")), encodeBatch(control, marker)},
+			exp:     []string{"0", "1"},
+		},
+		{
+			name:    "aborted then control",
+			batches: [][]byte{encodeBatch(txn, v("0"), v("1")), encodeBatch(control, marker)},
+			aborted: true,
+		},
+		{
+			name:    "filtered",
+			batches: [][]byte{encodeBatch(plain, v("0"), v("1"), v("2"))},
+			filter:  func(r *Record) bool { return string(r.Value) == "1" },
+			exp:     []string{"1"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			rp := &kmsg.FetchResponseTopicPartition{HighWatermark: 3}
+			for _, b := range test.batches {
+				rp.RecordBatches = append(rp.RecordBatches, b...)
+			}
+			if test.aborted {
+				rp.AbortedTransactions = []kmsg.FetchResponseTopicPartitionAbortedTransaction{{ProducerID: 1, FirstOffset: 0}}
+			}
+			o := &cursorOffsetNext{
+				cursorOffset: cursorOffset{offset: 0, lastConsumedEpoch: -1},
+				from:         &cursor{topic: "foo", filter: test.filter},
+			}
+			fp := o.processRespPartition(&broker{}, rp, newDecompressor(), newRecordPool(), nil, false)
+			if fp.Err != nil {
+				t.Fatal(fp.Err)
+			}
+			var got []string
+			for _, r := range fp.Records {
+				got = append(got, string(r.Value))
+			}
+			if !reflect.DeepEqual(got, test.exp) {
+				t.Errorf("got values %v, expected %v", got, test.exp)
+			}
+			if o.offset != 3 || o.lastConsumedEpoch != 3 || o.lastConsumedTime.UnixMilli() != 1000 {
+				t.Errorf("got offset %d, epoch %d, time %d, expected 3, 3, and 1000", o.offset, o.lastConsumedEpoch, o.lastConsumedTime.UnixMilli())
+			}
+			if test.filter != nil && (!fp.filtered || fp.lastFiltered.offset != 2 || fp.lastFiltered.lastConsumedEpoch != 3) {
+				t.Errorf("got filtered %v and last filtered %+v, expected offset 2 epoch 3", fp.filtered, fp.lastFiltered)
+			}
+		})
+	}
+}
+
+func TestFetchDecodeParallelism(t *testing.T) {
+	t.Parallel()
+
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), FetchDecodeParallelism(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+	s := &source{cl: cl}
+
+	const nparts = 8
+	req := &fetchRequest{usedOffsets: usedOffsets{"foo": make(map[int32]*cursorOffsetNext)}}
+	resp := &kmsg.FetchResponse{Version: 12, Topics: []kmsg.FetchResponseTopic{{Topic: "foo"}}}
+	for p := int32(0); p < nparts; p++ {
+		req.usedOffsets["foo"][p] = &cursorOffsetNext{
+			cursorOffset: cursorOffset{offset: int64(p), lastConsumedEpoch: -1},
+			from:         &cursor{topic: "foo", partition: p},
+		}
+		resp.Topics[0].Partitions = append(resp.Topics[0].Partitions, kmsg.FetchResponseTopicPartition{
+			Partition:            p,
+			HighWatermark:        20,
+			PreferredReadReplica: -1,
+			RecordBatches:        append(fetchedBatch(t, 0, 10, true), fetchedBatch(t, 10, 10, true)...),
+		})
+	}
+
+	processed := s.processRespPartitions(&broker{}, req, resp)
+	if len(processed) != nparts {
+		t.Fatalf("got %d processed partitions, expected %d", len(processed), nparts)
+	}
+	for p := int32(0); p < nparts; p++ {
+		fp := processed[&resp.Topics[0].Partitions[p]]
+		if fp.Err != nil || fp.Partition != p || len(fp.Records) != 20-int(p) {
+			t.Fatalf("partition %d: got err %v, partition %d, %d records", p, fp.Err, fp.Partition, len(fp.Records))
+		}
+		for i, r := range fp.Records {
+			if exp := strconv.Itoa(int(p) + i); string(r.Value) != exp {
+				t.Errorf("partition %d record %d: got value %q, expected %q", p, i, r.Value, exp)
+			}
+		}
+		if o := req.usedOffsets["foo"][p]; o.offset != 20 {
+			t.Errorf("partition %d: got next offset %d, expected 20", p, o.offset)
+		}
+	}
+}
+
+func TestConsumeFilter(t *testing.T) {
+	t.Parallel()
+
+	rp := &kmsg.FetchResponseTopicPartition{
+		Partition:     0,
+		HighWatermark: 10,
+		RecordBatches: append(fetchedBatch(t, 0, 5, false), fetchedBatch(t, 5, 5, true)...),
+	}
+	even := func(r *Record) bool {
+		n, _ := strconv.Atoi(string(r.Value))
+		return n%2 == 0
+	}
+
+	for _, lazy := range []bool{false, true} {
+		o := &cursorOffsetNext{
+			cursorOffset: cursorOffset{offset: 2, lastConsumedEpoch: -1},
+			from:         &cursor{topic: "foo", filter: even},
+		}
+		fp := o.processRespPartition(&broker{}, rp, newDecompressor(), nil, nil, lazy)
+
+		var got []string
+		fp.EachRecord(func(r *Record) { got = append(got, string(r.Value)) })
+		if exp := []string{"2", "4", "6", "8"}; !reflect.DeepEqual(got, exp) {
+			t.Errorf("lazy %v: got values %v, expected %v", lazy, got, exp)
+		}
+		if o.offset != 10 {
+			t.Errorf("lazy %v: got next offset %d, expected 10", lazy, o.offset)
+		}
+		if offset, _, _ := fp.lastConsumed(); offset != 9 {
+			t.Errorf("lazy %v: got last consumed offset %d, expected 9", lazy, offset)
+		}
+	}
+
+	// A partition with every record filtered still has progress to commit.
+	o := &cursorOffsetNext{
+		cursorOffset: cursorOffset{offset: 0, lastConsumedEpoch: -1},
+		from:         &cursor{topic: "foo", filter: func(*Record) bool { return false }},
+	}
+	fp := o.processRespPartition(&broker{}, rp, newDecompressor(), nil, nil, false)
+	if fp.hasRecords() || !fp.hasProgress() {
+		t.Errorf("got records %v and progress %v, expected no records with progress", fp.hasRecords(), fp.hasProgress())
+	}
+	if offset, epoch, _ := fp.lastConsumed(); offset != 9 || epoch != 3 {
+		t.Errorf("got last consumed offset %d epoch %d, expected 9 and 3", offset, epoch)
+	}
+}
+
+// bufferedSource returns a source with a buffered fetch for the topic, with
+// one partition per element of timestamps, and one record per timestamp
+// (in millis).
+func bufferedSource(cl *Client, topic string, timestamps [][]int64) *source {
+	s := &source{cl: cl, sem: make(chan struct{})}
+	f := FetchTopic{Topic: topic}
+	used := make(map[int32]*cursorOffsetNext)
+	for p, tss := range timestamps {
+		fp := FetchPartition{Partition: int32(p)}
+		for o, ts := range tss {
+			fp.Records = append(fp.Records, &Record{
+				Topic:     topic,
+				Partition: int32(p),
+				Offset:    int64(o),
+				Timestamp: time.UnixMilli(ts),
+			})
+		}
+		f.Partitions = append(f.Partitions, fp)
+		used[int32(p)] = &cursorOffsetNext{from: &cursor{topic: topic, partition: int32(p), source: s}}
+	}
+	s.buffered = bufferedFetch{
+		fetch:       Fetch{Topics: []FetchTopic{f}},
+		doneFetch:   make(chan struct{}, 1),
+		usedOffsets: usedOffsets{topic: used},
+	}
+	return s
+}
+
+func TestPollPrioritized(t *testing.T) {
+	t.Parallel()
+
+	cl, err := NewClient(
+		SeedBrokers("127.0.0.1:1"),
+		ConsumeTopicPriorities(map[string]int{"ctl": 1}),
+		FairPartitionPolling(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	// Buffer a busy topic on one source and a control topic on another,
+	// with the busy source ready for draining first.
+	c := &cl.consumer
+	c.sourcesReadyForDraining = []*source{
+		bufferedSource(cl, "bulk", [][]int64{make([]int64, 10), make([]int64, 10)}),
+		bufferedSource(cl, "ctl", [][]int64{make([]int64, 3)}),
+	}
+
+	polled := func(fs Fetches) []string {
+		var got []string
+		fs.EachRecord(func(r *Record) { got = append(got, r.Topic+strconv.Itoa(int(r.Partition))) })
+		return got
+	}
+
+	fs := cl.PollRecords(nil, 5)
+	if exp := []string{"ctl0", "ctl0", "ctl0", "bulk0", "bulk1"}; !reflect.DeepEqual(polled(fs), exp) {
+		t.Errorf("got %v, expected %v", polled(fs), exp)
+	}
+	if len(fs) != 2 || fs[0].Topics[0].Topic != "ctl" {
+		t.Errorf("expected two fetches with the control topic first, got %d", len(fs))
+	}
+	if len(c.sourcesReadyForDraining) != 1 {
+		t.Errorf("got %d sources ready for draining, expected 1", len(c.sourcesReadyForDraining))
+	}
+
+	fs = cl.PollRecords(nil, 4)
+	if exp := []string{"bulk0", "bulk0", "bulk1", "bulk1"}; !reflect.DeepEqual(polled(fs), exp) {
+		t.Errorf("got %v, expected %v", polled(fs), exp)
+	}
+}
+
+func TestShareRecords(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range []struct {
+		n       int
+		weights []int
+		have    []int
+		rotate  int
+		exp     []int
+	}{
+		{10, []int{3, 1}, []int{100, 100}, 0, []int{8, 2}}, // 7.5 & 2.5 round down, leftover 1 given starting at 0
+		{10, []int{3, 1}, []int{2, 100}, 0, []int{2, 8}},   // unused share is redistributed
+		{2, []int{1, 1, 1}, []int{5, 5, 5}, 1, []int{0, 1, 1}},
+		{2, []int{1, 1, 1}, []int{5, 5, 5}, 2, []int{1, 0, 1}},
+		{5, []int{1, 1}, []int{1, 1}, 0, []int{1, 1}},
+	} {
+		if got := shareRecords(test.n, test.weights, test.have, test.rotate); !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("shareRecords(%d, %v, %v, %d): got %v, expected %v", test.n, test.weights, test.have, test.rotate, got, test.exp)
+		}
+	}
+}
+
+func TestConsumeReplay(t *testing.T) {
+	t.Parallel()
+
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), ConsumeReplay(time.Time{}, 1000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	c := &cl.consumer
+	c.sourcesReadyForDraining = []*source{
+		bufferedSource(cl, "foo", [][]int64{{0, 2000, 60000}, {1000, 3000}}),
+	}
+
+	var got []int64
+	polled := func(fs Fetches) {
+		fs.EachRecord(func(r *Record) { got = append(got, r.Timestamp.UnixMilli()) })
+	}
+
+	// Replaying starts paused at the earliest timestamp: only the first
+	// record is due, and polling with a context waits for more.
+	cl.PauseReplay()
+	polled(cl.PollRecords(nil, 0))
+	if pos, ok := cl.ReplayPosition(); !ok || pos.UnixMilli() != 0 {
+		t.Errorf("got replay position %v (started %v), expected 0", pos.UnixMilli(), ok)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	fs := cl.PollRecords(ctx, 0)
+	cancel()
+	if err := fs.Err0(); err != context.DeadlineExceeded {
+		t.Errorf("got err %v polling while paused, expected %v", err, context.DeadlineExceeded)
+	}
+
+	cl.ResumeReplay()
+	for len(got) < 5 {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		fs := cl.PollRecords(ctx, 1)
+		cancel()
+		if err := fs.Err0(); err != nil {
+			t.Fatalf("unexpected poll error: %v", err)
+		}
+		polled(fs)
+	}
+	if exp := []int64{0, 1000, 2000, 3000, 60000}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("got timestamps %v, expected %v", got, exp)
+	}
+	if len(c.sourcesReadyForDraining) != 0 {
+		t.Errorf("got %d sources ready for draining, expected 0", len(c.sourcesReadyForDraining))
+	}
+}
@@ -0,0 +1,318 @@
+package kgo
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// ClientState is a point in time snapshot of a client, useful for readiness
+// probes and debug pages. All fields are exported with JSON friendly types so
+// that the state can be served directly; see StateHandler.
+type ClientState struct {
+	// Brokers contains the state of every seed and discovered broker.
+	Brokers []BrokerState
+
+	// MetadataUpdatedAt is when metadata was last successfully updated,
+	// and is the zero time if metadata has never been loaded.
+	MetadataUpdatedAt time.Time
+	// MetadataAge is how long ago metadata was last successfully updated,
+	// and is zero if metadata has never been loaded.
+	MetadataAge time.Duration
+	// MetadataErr is the error from the most recent metadata update, if
+	// that update failed.
+	MetadataErr string
+
+	Producer ProducerState
+	Consumer ConsumerState
+}
+
+// Healthy returns whether metadata has been loaded at least once and the most
+// recent metadata update succeeded, which means the client can currently
+// reach the cluster.
+func (s ClientState) Healthy() bool {
+	return !s.MetadataUpdatedAt.IsZero() && s.MetadataErr == ""
+}
+
+// BrokerState is the state of an individual broker in the client.
+type BrokerState struct {
+	// Meta is the metadata for this broker. Seed brokers have a NodeID
+	// that is very negative.
+	Meta BrokerMetadata
+	// Seed is whether this is a seed broker.
+	Seed bool
+	// OpenConnections is the number of currently open connections to
+	// this broker.
+	OpenConnections int
+	// LastErr is the most recent error opening or initializing a
+	// connection to this broker, if any.
+	LastErr string
+	// LastErrAt is when LastErr occurred.
+	LastErrAt time.Time
+	// Degraded is whether the broker's circuit breaker is open; see
+	// BrokerCircuitBreaker.
+	Degraded bool
+	// RequestTimeoutOverhead is the overhead currently used when deadlining
+	// requests to this broker; see AdaptiveRequestTimeoutOverhead.
+	RequestTimeoutOverhead time.Duration
+}
+
+// ProducerState is the state of the producer portion of a client, including
+// any transaction.
+type ProducerState struct {
+	BufferedRecords int64
+	BufferedBytes   int64
+
+	// ProducerID and ProducerEpoch are the currently loaded producer ID
+	// and epoch, and are -1 if not yet loaded.
+	ProducerID    int64
+	ProducerEpoch int16
+	// ProducerIDErr is the error from loading the producer ID, if any.
+	ProducerIDErr string
+
+	// TransactionalID is the configured transactional ID, if any.
+	TransactionalID string
+	// InTransaction is whether a transaction has been begun and not yet
+	// ended.
+	InTransaction bool
+	// Aborting is whether buffered records are currently being aborted.
+	Aborting bool
+
+	// Partitions contains every partition the client has produced to.
+	Partitions []ProducerPartitionState
+}
+
+// ProducerPartitionState is the producer state for an individual partition.
+type ProducerPartitionState struct {
+	Topic     string
+	Partition int32
+	// Leader is the broker currently being produced to.
+	Leader int32
+	// BufferedRecords is the number of records buffered for this
+	// partition, including records in inflight batches.
+	BufferedRecords int64
+	// BufferedBatches is the number of batches buffered for this
+	// partition, including inflight batches.
+	BufferedBatches int
+	// InflightBatches is the number of batches that have been written to
+	// the broker and are awaiting a response.
+	InflightBatches int
+	// InflightRequests is the number of produce requests inflight that
+	// contain a batch for this partition.
+	InflightRequests int
+	// Failing is whether the partition hit a temporary error and is
+	// waiting for a metadata update.
+	Failing bool
+}
+
+// ConsumerState is the state of the consumer portion of a client.
+type ConsumerState struct {
+	BufferedRecords int64
+	BufferedBytes   int64
+
+	// Partitions contains every partition currently being consumed.
+	Partitions []ConsumerPartitionState
+
+	// Group is the state of the group member, if group consuming.
+	Group *GroupState
+}
+
+// ConsumerPartitionState is the consumer state for an individual partition.
+type ConsumerPartitionState struct {
+	Topic     string
+	Partition int32
+	// Leader is the leader of this partition.
+	Leader int32
+	// FetchingFrom is the broker being fetched from, which can differ
+	// from the leader if consuming from a follower (KIP-392).
+	FetchingFrom int32
+	// Offset is the next offset to be fetched.
+	Offset int64
+	// LastConsumedEpoch is the leader epoch of the last consumed record.
+	LastConsumedEpoch int32
+	// HighWatermark is the most recently seen high watermark.
+	HighWatermark int64
+}
+
+// GroupState is the state of a group member.
+type GroupState struct {
+	Group string
+	// MemberID and Generation are the current member ID and generation,
+	// which are empty and -1 if the member has not joined.
+	MemberID   string
+	Generation int32
+	// Joined is whether the member is currently in the group.
+	Joined bool
+	// Leader is whether this member is the group leader.
+	Leader bool
+	// Assigned is the member's current assignment.
+	Assigned map[string][]int32
+}
+
+// State returns a snapshot of the client's current state. Consumer partition
+// offsets are read under the same lock that polling uses, meaning this may
+// block briefly while a poll or rebalance is in progress.
+func (cl *Client) State() ClientState {
+	var s ClientState
+
+	cl.metaStateMu.Lock()
+	if s.MetadataUpdatedAt = cl.metaAt; !s.MetadataUpdatedAt.IsZero() {
+		s.MetadataAge = time.Since(s.MetadataUpdatedAt)
+	}
+	if cl.metaErr != nil {
+		s.MetadataErr = cl.metaErr.Error()
+	}
+	cl.metaStateMu.Unlock()
+
+	cl.brokersMu.RLock()
+	for _, b := range cl.loadSeeds() {
+		bs := b.state()
+		bs.Seed = true
+		s.Brokers = append(s.Brokers, bs)
+	}
+	for _, b := range cl.brokers {
+		s.Brokers = append(s.Brokers, b.state())
+	}
+	cl.brokersMu.RUnlock()
+
+	s.Producer = cl.producer.state()
+	s.Consumer = cl.consumer.state()
+	return s
+}
+
+// StateHandler returns an http.Handler that serves the client's State as
+// JSON. The handler replies with 200 if the state is Healthy, and 503
+// otherwise, allowing the handler to be used directly as a readiness probe.
+func (cl *Client) StateHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		s := cl.State()
+		w.Header().Set("Content-Type", "application/json")
+		if !s.Healthy() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(s) //nolint:errcheck // nothing to do if writing the response fails
+	})
+}
+
+func (b *broker) state() BrokerState {
+	s := BrokerState{Meta: b.meta}
+
+	b.reapMu.Lock()
+	for _, cxn := range b.allCxns() {
+		if cxn != nil && !cxn.dead.Load() {
+			s.OpenConnections++
+		}
+	}
+	b.reapMu.Unlock()
+
+	s.Degraded = b.isDegraded()
+	s.RequestTimeoutOverhead = b.timeoutOverhead()
+
+	b.errMu.Lock()
+	if b.lastErr != nil {
+		s.LastErr = b.lastErr.Error()
+		s.LastErrAt = b.lastErrAt
+	}
+	b.errMu.Unlock()
+
+	return s
+}
+
+func (p *producer) state() ProducerState {
+	s := ProducerState{
+		BufferedRecords: p.bufferedRecords.Load(),
+		BufferedBytes:   p.bufferedBytes.Load(),
+		ProducerID:      -1,
+		ProducerEpoch:   -1,
+		Aborting:        p.isAborting(),
+	}
+
+	if id, _ := p.id.Load().(*producerID); id != nil {
+		if id.err != nil {
+			s.ProducerIDErr = id.err.Error()
+		} else {
+			s.ProducerID, s.ProducerEpoch = id.id, id.epoch
+		}
+	}
+
+	if txnID := p.cl.cfg.txnID; txnID != nil {
+		s.TransactionalID = *txnID
+		p.txnMu.Lock()
+		s.InTransaction = p.inTxn
+		p.txnMu.Unlock()
+	}
+
+	for topic, parts := range p.topics.load() {
+		for _, tp := range parts.load().partitions {
+			recBuf := tp.records
+			if recBuf == nil {
+				continue
+			}
+			recBuf.mu.Lock()
+			s.Partitions = append(s.Partitions, ProducerPartitionState{
+				Topic:            topic,
+				Partition:        recBuf.partition,
+				Leader:           recBuf.leader,
+				BufferedRecords:  recBuf.buffered.Load(),
+				BufferedBatches:  len(recBuf.batches),
+				InflightBatches:  recBuf.batchDrainIdx,
+				InflightRequests: int(recBuf.inflight),
+				Failing:          recBuf.failing,
+			})
+			recBuf.mu.Unlock()
+		}
+	}
+	sort.Slice(s.Partitions, func(i, j int) bool {
+		l, r := &s.Partitions[i], &s.Partitions[j]
+		return l.Topic < r.Topic || l.Topic == r.Topic && l.Partition < r.Partition
+	})
+
+	return s
+}
+
+func (c *consumer) state() ConsumerState {
+	s := ConsumerState{
+		BufferedRecords: c.bufferedRecords.Load(),
+		BufferedBytes:   c.bufferedBytes.Load(),
+	}
+
+	// Cursors are modified while polling and assigning, both of which
+	// hold the consumer mu, and while finishing list or epoch loads, which
+	// holds the session's listOrEpochMu.
+	c.mu.Lock()
+	session := c.loadSession()
+	session.listOrEpochMu.Lock()
+	for cursor := range c.usingCursors {
+		s.Partitions = append(s.Partitions, ConsumerPartitionState{
+			Topic:             cursor.topic,
+			Partition:         cursor.partition,
+			Leader:            cursor.leader,
+			FetchingFrom:      cursor.sourceNode.Load(),
+			Offset:            cursor.offset,
+			LastConsumedEpoch: cursor.lastConsumedEpoch,
+			HighWatermark:     cursor.hwm,
+		})
+	}
+	session.listOrEpochMu.Unlock()
+	c.mu.Unlock()
+
+	sort.Slice(s.Partitions, func(i, j int) bool {
+		l, r := &s.Partitions[i], &s.Partitions[j]
+		return l.Topic < r.Topic || l.Topic == r.Topic && l.Partition < r.Partition
+	})
+
+	if g := c.g; g != nil {
+		memberID, generation := g.memberGen.load()
+		s.Group = &GroupState{
+			Group:      g.cfg.group,
+			MemberID:   memberID,
+			Generation: generation,
+			Joined:     memberID != "" && generation >= 0,
+			Leader:     g.leader.Load(),
+			Assigned:   g.nowAssigned.read(),
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,55 @@
+package kgo
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientState(t *testing.T) {
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), TransactionalID("txn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	seed := cl.loadSeeds()[0]
+	seed.setLastErr(errors.New("dial failed"))
+
+	s := cl.State()
+	if s.Healthy() {
+		t.Error("expected state to be unhealthy before metadata is loaded")
+	}
+	if len(s.Brokers) != 1 || !s.Brokers[0].Seed || s.Brokers[0].LastErr != "dial failed" {
+		t.Errorf("unexpected broker state %+v", s.Brokers)
+	}
+	if s.Producer.TransactionalID != "txn" || s.Producer.InTransaction {
+		t.Errorf("unexpected producer state %+v", s.Producer)
+	}
+
+	rr := httptest.NewRecorder()
+	cl.StateHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("got status %d != exp %d", rr.Code, http.StatusServiceUnavailable)
+	}
+
+	cl.metaStateMu.Lock()
+	cl.metaAt = time.Now()
+	cl.metaStateMu.Unlock()
+
+	rr = httptest.NewRecorder()
+	cl.StateHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+	if rr.Code != http.StatusOK {
+		t.Errorf("got status %d != exp %d", rr.Code, http.StatusOK)
+	}
+	var served ClientState
+	if err := json.Unmarshal(rr.Body.Bytes(), &served); err != nil {
+		t.Fatalf("unable to unmarshal served state: %v", err)
+	}
+	if !served.Healthy() || len(served.Brokers) != 1 {
+		t.Errorf("unexpected served state %+v", served)
+	}
+}
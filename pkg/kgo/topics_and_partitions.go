@@ -582,7 +582,7 @@ func (old *topicPartition) migrateCursorTo( //nolint:revive // old/new naming ma
 
 	// With the session stopped, we can update fields on the old cursor
 	// with no concurrency issue.
-	old.cursor.source = new.cursor.source
+	old.cursor.setSource(new.cursor.source)
 
 	// KIP-320: if we had consumed some messages, we need to validate the
 	// leader epoch on the new broker to see if we experienced data loss
@@ -695,10 +695,7 @@ func (k *kip951move) ensureSinksAndSources(cl *Client) {
 		if _, exists := cl.sinksAndSources[leader]; exists {
 			return
 		}
-		cl.sinksAndSources[leader] = sinkAndSource{
-			sink:   cl.newSink(leader),
-			source: cl.newSource(leader),
-		}
+		cl.sinksAndSources[leader] = cl.newSinkAndSource(leader)
 	}
 
 	for _, td := range k.recBufs {
@@ -786,7 +783,7 @@ func (k *kip951move) doMove(cl *Client) {
 	// same (this allows easier injection of failures in local testing).  A
 	// higher epoch can come from a concurrent metadata update that
 	// actually performed the move first.
-	modifyP := func(d *topicPartitionsData, partition int32, td topicPartitionData) (old, new *topicPartition, modified bool) {
+	modifyP := func(d *topicPartitionsData, topic string, partition int32, td topicPartitionData) (old, new *topicPartition, modified bool) {
 		old = d.partitions[partition]
 		if old.leaderEpoch > td.leaderEpoch {
 			return nil, nil, false
@@ -807,14 +804,14 @@ func (k *kip951move) doMove(cl *Client) {
 		}
 		if new.records != nil {
 			new.records = &recBuf{
-				sink:               sns.sink,
+				sink:               sns.sink(topic, partition),
 				topicPartitionData: new.topicPartitionData,
 			}
 		} else {
 			new.cursor = &cursor{
-				source:             sns.source,
 				topicPartitionData: new.topicPartitionData,
 			}
+			new.cursor.setSource(sns.source(topic, partition))
 		}
 
 		// We now have to mirror the new partition back to the topic
@@ -838,7 +835,7 @@ func (k *kip951move) doMove(cl *Client) {
 			if !ok {
 				continue // perhaps concurrently purged
 			}
-			old, new, modified := modifyP(lr.r, recBuf.partition, td)
+			old, new, modified := modifyP(lr.r, recBuf.topic, recBuf.partition, td)
 			if modified {
 				cl.cfg.logger.Log(LogLevelInfo, "moving producing partition due to kip-951 not_leader_for_partition",
 					"topic", recBuf.topic,
@@ -869,7 +866,7 @@ func (k *kip951move) doMove(cl *Client) {
 			if !ok {
 				continue // perhaps concurrently purged
 			}
-			old, new, modified := modifyP(lr.r, cursor.partition, td)
+			old, new, modified := modifyP(lr.r, cursor.topic, cursor.partition, td)
 			if modified {
 				cl.cfg.logger.Log(LogLevelInfo, "moving consuming partition due to kip-951 not_leader_for_partition",
 					"topic", cursor.topic,
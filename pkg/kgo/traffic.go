@@ -0,0 +1,210 @@
+package kgo
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kbin"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// trafficMagic begins every traffic capture, and versions the format.
+const trafficMagic = "KGOTRAF1"
+
+// TrafficEntry is a single request recorded with RecordTraffic.
+type TrafficEntry struct {
+	// Broker is the node ID of the broker the request was issued to.
+	// Seed brokers have very negative node IDs; see NodeName.
+	Broker int32
+	// Start is when the request was enqueued to be written.
+	Start time.Time
+	// Latency is how long the request took from being enqueued until
+	// the response was read (or the request failed).
+	Latency time.Duration
+	// Request is the request that was issued.
+	Request kmsg.Request
+	// Response is the response to the request, and is nil if the request
+	// failed or if it was a produce request with no acks.
+	Response kmsg.Response
+	// Err is the error writing the request or reading the response, if
+	// any.
+	Err string
+}
+
+// RecordTraffic records every request the client issues to brokers and every
+// response it receives, with timing, to w. Captures can be read with
+// ReadTraffic, and the entries read can be replayed against a kfake cluster
+// with the kfake Cluster's Replay method to reproduce issues offline.
+//
+// Requests issued while initializing connections (ApiVersions and SASL) are
+// not recorded, meaning credentials are never written to the capture. Other
+// requests are recorded in full, including any record keys and values that
+// are produced or consumed.
+//
+// Entries are written as requests complete. If writing to w fails, the
+// failure is logged and recording stops. The writer is not closed when the
+// client is closed; if the writer is buffered, flush it after closing the
+// client.
+//
+// The format of a capture is the 8 byte magic "KGOTRAF1" followed by entries,
+// each of which is a big endian int32 length followed by: an int64 start
+// time in unix nanoseconds, an int64 latency in nanoseconds, an int32 broker
+// node ID, an int16 request key and int16 request version, the request body
+// and response body as int32 length prefixed bytes (with length -1 for no
+// response), and the error as an int16 length prefixed string. Request and
+// response bodies do not include the Kafka request or response headers.
+func RecordTraffic(w io.Writer) Opt {
+	return clientOpt{func(cfg *cfg) { cfg.traffic = w }}
+}
+
+type trafficRecorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	failed  bool
+	started bool
+	buf     []byte
+}
+
+// recordTraffic writes an entry for a request if the client is recording
+// traffic. The response body is nil if there was no response.
+func (cl *Client) recordTraffic(
+	nodeID int32,
+	key int16,
+	version int16,
+	start time.Time,
+	reqBody []byte,
+	respBody []byte,
+	err error,
+) {
+	t := cl.traffic
+	if t == nil || reqBody == nil {
+		return
+	}
+	latency := time.Since(start)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.failed {
+		return
+	}
+
+	dst := t.buf[:0]
+	if !t.started {
+		dst = append(dst, trafficMagic...)
+	}
+	lenAt := len(dst)
+	dst = append(dst, 0, 0, 0, 0)
+	dst = kbin.AppendInt64(dst, start.UnixNano())
+	dst = kbin.AppendInt64(dst, int64(latency))
+	dst = kbin.AppendInt32(dst, nodeID)
+	dst = kbin.AppendInt16(dst, key)
+	dst = kbin.AppendInt16(dst, version)
+	dst = kbin.AppendBytes(dst, reqBody)
+	dst = kbin.AppendNullableBytes(dst, respBody)
+	var errStr string
+	if err != nil {
+		errStr = err.Error()
+	}
+	dst = kbin.AppendString(dst, errStr)
+	binary.BigEndian.PutUint32(dst[lenAt:], uint32(len(dst)-lenAt-4))
+	t.buf = dst
+
+	if _, werr := t.w.Write(dst); werr != nil {
+		t.failed = true
+		cl.cfg.logger.Log(LogLevelError, "unable to write recorded traffic, no longer recording", "err", werr)
+		return
+	}
+	t.started = true
+}
+
+// requestHeaderLen returns the length of the request header (including the
+// length prefix) that the client's request formatter writes before the
+// request body.
+func (cl *Client) requestHeaderLen(req kmsg.Request) int {
+	n := 4 + 2 + 2 + 4 // length, key, version, correlation ID
+	if req.Key() == 7 && req.GetVersion() == 0 {
+		return n // controlled shutdown v0 has no client ID
+	}
+	n += 2
+	if cl.cfg.id != nil {
+		n += len(*cl.cfg.id)
+	}
+	if req.IsFlexible() {
+		n++ // empty tags
+	}
+	return n
+}
+
+// TrafficReader reads entries from a capture written with RecordTraffic.
+type TrafficReader struct {
+	r       *bufio.Reader
+	started bool
+}
+
+// ReadTraffic returns a reader for a capture written with RecordTraffic.
+func ReadTraffic(r io.Reader) *TrafficReader {
+	return &TrafficReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next entry in the capture, or io.EOF if there are no more
+// entries. A capture that ends in the middle of an entry returns
+// io.ErrUnexpectedEOF.
+func (t *TrafficReader) Next() (TrafficEntry, error) {
+	if !t.started {
+		magic := make([]byte, len(trafficMagic))
+		if _, err := io.ReadFull(t.r, magic); err != nil {
+			return TrafficEntry{}, err
+		}
+		if string(magic) != trafficMagic {
+			return TrafficEntry{}, errors.New("input is not a kgo traffic capture")
+		}
+		t.started = true
+	}
+
+	var size [4]byte
+	if _, err := io.ReadFull(t.r, size[:]); err != nil {
+		return TrafficEntry{}, err // io.EOF if there are no more entries
+	}
+	raw := make([]byte, binary.BigEndian.Uint32(size[:]))
+	if _, err := io.ReadFull(t.r, raw); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return TrafficEntry{}, err
+	}
+
+	b := kbin.Reader{Src: raw}
+	var e TrafficEntry
+	e.Start = time.Unix(0, b.Int64())
+	e.Latency = time.Duration(b.Int64())
+	e.Broker = b.Int32()
+	key, version := b.Int16(), b.Int16()
+	reqBody := b.Bytes()
+	respBody := b.NullableBytes()
+	e.Err = b.String()
+	if err := b.Complete(); err != nil {
+		return TrafficEntry{}, fmt.Errorf("invalid traffic entry: %w", err)
+	}
+
+	e.Request = kmsg.RequestForKey(key)
+	if e.Request == nil {
+		return TrafficEntry{}, fmt.Errorf("invalid traffic entry: unknown request key %d", key)
+	}
+	e.Request.SetVersion(version)
+	if err := e.Request.ReadFrom(reqBody); err != nil {
+		return TrafficEntry{}, fmt.Errorf("unable to decode recorded %s v%d request: %w", kmsg.NameForKey(key), version, err)
+	}
+	if respBody != nil {
+		e.Response = e.Request.ResponseKind()
+		e.Response.SetVersion(version)
+		if err := e.Response.ReadFrom(respBody); err != nil {
+			return TrafficEntry{}, fmt.Errorf("unable to decode recorded %s v%d response: %w", kmsg.NameForKey(key), version, err)
+		}
+	}
+	return e, nil
+}
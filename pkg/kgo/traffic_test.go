@@ -0,0 +1,79 @@
+package kgo
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestTraffic(t *testing.T) {
+	var buf bytes.Buffer
+	cl, err := NewClient(SeedBrokers("127.0.0.1:1"), ClientID("foo"), RecordTraffic(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	// The body we record must be exactly what follows the header our
+	// formatter writes, for flexible and non-flexible requests.
+	for _, version := range []int16{4, 12} {
+		req := kmsg.NewPtrMetadataRequest()
+		req.Version = version
+		rt := kmsg.NewMetadataRequestTopic()
+		rt.Topic = kmsg.StringPtr("bar")
+		req.Topics = append(req.Topics, rt)
+
+		full := cl.reqFormatter.AppendRequest(nil, req, 3)
+		body := req.AppendTo(nil)
+		if got := full[cl.requestHeaderLen(req):]; !bytes.Equal(got, body) {
+			t.Errorf("v%d: got body %x, exp %x", version, got, body)
+		}
+
+		resp := req.ResponseKind().(*kmsg.MetadataResponse)
+		resp.Version = version
+		rb := kmsg.NewMetadataResponseBroker()
+		rb.NodeID, rb.Host, rb.Port = 1, "example.com", 9092
+		resp.Brokers = append(resp.Brokers, rb)
+
+		cl.recordTraffic(1, req.Key(), version, time.Now(), body, resp.AppendTo(nil), nil)
+	}
+	cl.recordTraffic(2, 0, 9, time.Now(), kmsg.NewPtrProduceRequest().AppendTo(nil), nil, errors.New("write failed"))
+	cl.recordTraffic(2, 3, 9, time.Now(), nil, nil, nil) // not recorded: no request body
+
+	r := ReadTraffic(bytes.NewReader(buf.Bytes()))
+	for _, version := range []int16{4, 12} {
+		e, err := r.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, ok := e.Request.(*kmsg.MetadataRequest)
+		if !ok || req.Version != version || len(req.Topics) != 1 || *req.Topics[0].Topic != "bar" {
+			t.Errorf("v%d: got unexpected request %#v", version, e.Request)
+		}
+		resp, ok := e.Response.(*kmsg.MetadataResponse)
+		if !ok || len(resp.Brokers) != 1 || resp.Brokers[0].Host != "example.com" || e.Broker != 1 || e.Err != "" {
+			t.Errorf("v%d: got unexpected response %#v", version, e.Response)
+		}
+	}
+	e, err := r.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := e.Request.(*kmsg.ProduceRequest); !ok || e.Response != nil || e.Err != "write failed" {
+		t.Errorf("got unexpected failed entry %#v", e)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("got err %v at end of capture, exp io.EOF", err)
+	}
+
+	if _, err := ReadTraffic(bytes.NewReader(buf.Bytes()[:buf.Len()-1])).Next(); err != nil {
+		t.Errorf("unexpected err reading the first entry of a truncated capture: %v", err)
+	}
+	if _, err := ReadTraffic(bytes.NewReader([]byte("not a capture"))).Next(); err == nil {
+		t.Error("expected error reading an invalid capture")
+	}
+}
@@ -408,7 +408,7 @@ retry:
 
 		case errors.Is(endTxnErr, kerr.UnknownServerError):
 			s.cl.cfg.logger.Log(LogLevelInfo, "end transaction with commit unknown server error; retrying")
-			after := time.NewTimer(s.cl.cfg.retryBackoff(tries))
+			after := time.NewTimer(s.cl.loadDyn().retryBackoff(tries))
 			select {
 			case <-after.C: // context canceled; we will see when we retry
 			case <-s.cl.ctx.Done():
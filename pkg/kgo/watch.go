@@ -0,0 +1,112 @@
+package kgo
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"time"
+)
+
+// WatchFiles polls the given files every interval and, whenever the contents
+// of any file change, calls reload and passes the returned options to
+// Reconfigure. This is meant for rotating credentials that are stored on disk:
+// client certificates and CA bundles for DialTLSConfig, or SASL credentials.
+// Watching stops when the client is closed.
+//
+// Files are compared by content rather than by modification time, so files
+// that are replaced by a rename or a symlink swap (as is common for mounted
+// secrets) are detected. A file that cannot be read is assumed to be mid
+// rotation and is checked again on the next interval.
+//
+// If reload or Reconfigure fails, the failure is logged, any
+// HookCredentialsRotated hook is called with the error, and the client
+// continues to use its prior options; reloading is retried every interval
+// until it succeeds. If reloading succeeds, HookCredentialsRotated is called
+// from Reconfigure. If interval is not positive, files are checked every
+// minute.
+//
+// For example, to reload a client certificate and CA bundle when either
+// changes:
+//
+//	cl.WatchFiles(time.Minute, func() ([]kgo.Opt, error) {
+//		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+//		if err != nil {
+//			return nil, err
+//		}
+//		ca, err := os.ReadFile(caFile)
+//		if err != nil {
+//			return nil, err
+//		}
+//		pool := x509.NewCertPool()
+//		pool.AppendCertsFromPEM(ca)
+//		return []kgo.Opt{kgo.DialTLSConfig(&tls.Config{
+//			Certificates: []tls.Certificate{cert},
+//			RootCAs:      pool,
+//		})}, nil
+//	}, certFile, keyFile, caFile)
+//
+// Note that SASL credentials that rotate through a secrets manager do not need
+// to be watched: the SASL mechanisms in this repo accept a function that is
+// called to load credentials for every authentication, which includes KIP-368
+// reauthentication.
+func (cl *Client) WatchFiles(interval time.Duration, reload func() ([]Opt, error), paths ...string) {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	sums := make([][]byte, len(paths))
+	for i, path := range paths {
+		sums[i] = fileSum(path)
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-cl.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			var changed bool
+			for i, path := range paths {
+				sum := fileSum(path)
+				if sum == nil || bytes.Equal(sum, sums[i]) {
+					continue
+				}
+				sums[i] = sum
+				changed = true
+			}
+			if !changed {
+				continue
+			}
+
+			cl.cfg.logger.Log(LogLevelInfo, "watched files changed, reloading", "files", paths)
+			opts, err := reload()
+			if err == nil {
+				err = cl.Reconfigure(opts...)
+			}
+			if err != nil {
+				cl.cfg.logger.Log(LogLevelWarn, "unable to reload options from watched files, continuing with prior options and retrying next interval", "files", paths, "err", err)
+				for i := range sums {
+					sums[i] = nil // retry next interval; files may be mid rotation
+				}
+				cl.cfg.hooks.each(func(h Hook) {
+					if h, ok := h.(HookCredentialsRotated); ok {
+						h.OnCredentialsRotated(nil, err)
+					}
+				})
+			}
+		}
+	}()
+}
+
+// fileSum returns the sha256 of the file at path, or nil if the file cannot
+// be read.
+func fileSum(path string) []byte {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	sum := sha256.Sum256(raw)
+	return sum[:]
+}
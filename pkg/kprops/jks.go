@@ -0,0 +1,197 @@
+package kprops
+
+import (
+	"bytes"
+	"crypto/sha1" //nolint:gosec // JKS is defined in terms of sha1
+	"crypto/subtle"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// jksKeyEntry is a private key and its certificate chain from a JKS keystore.
+type jksKeyEntry struct {
+	alias string
+	key   []byte // PKCS #8 DER
+	chain [][]byte
+}
+
+// jks contains the decoded entries in a JKS keystore.
+type jks struct {
+	keys  []jksKeyEntry
+	certs [][]byte // trusted certificates, DER
+}
+
+const (
+	jksMagic      = 0xfeedfeed
+	jksPrivateKey = 1
+	jksTrusted    = 2
+)
+
+// Sun's proprietary JKS key protection algorithm.
+var oidJKSKeyProtector = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 42, 2, 17, 1, 1}
+
+// jksPassword returns the password as Java encodes it for JKS: UTF-16BE.
+func jksPassword(password string) []byte {
+	u := utf16.Encode([]rune(password))
+	b := make([]byte, 0, 2*len(u))
+	for _, c := range u {
+		b = append(b, byte(c>>8), byte(c))
+	}
+	return b
+}
+
+type jksReader struct {
+	b   []byte
+	err error
+}
+
+func (r *jksReader) next(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errors.New("truncated JKS keystore")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+func (r *jksReader) u16() uint16 {
+	if b := r.next(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *jksReader) u32() uint32 {
+	if b := r.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *jksReader) bytes() []byte { return r.next(int(r.u32())) }
+func (r *jksReader) utf() string   { return string(r.next(int(r.u16()))) } // modified UTF-8; aliases are ASCII in practice
+
+// decodeJKS decodes a JKS keystore, verifying its integrity with password if
+// the password is non-empty (as keytool does), and decrypting private keys
+// with keyPassword.
+func decodeJKS(data []byte, password, keyPassword string) (*jks, error) {
+	if len(data) < sha1.Size {
+		return nil, errors.New("truncated JKS keystore")
+	}
+	body, digest := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	if password != "" {
+		h := sha1.New() //nolint:gosec // see above
+		h.Write(jksPassword(password))
+		h.Write([]byte("Mighty Aphrodite"))
+		h.Write(body)
+		if subtle.ConstantTimeCompare(h.Sum(nil), digest) != 1 {
+			return nil, errors.New("JKS keystore was tampered with, or the password is incorrect")
+		}
+	}
+
+	r := &jksReader{b: body}
+	if magic := r.u32(); r.err == nil && magic != jksMagic {
+		return nil, errors.New("invalid JKS keystore magic; is this a PKCS12 keystore?")
+	}
+	version := r.u32()
+	if r.err == nil && version != 1 && version != 2 {
+		return nil, fmt.Errorf("unsupported JKS keystore version %d", version)
+	}
+
+	var ks jks
+	for n := r.u32(); n > 0 && r.err == nil; n-- {
+		tag := r.u32()
+		alias := r.utf()
+		r.next(8) // timestamp
+		switch tag {
+		case jksPrivateKey:
+			encrypted := r.bytes()
+			var chain [][]byte
+			for n := r.u32(); n > 0 && r.err == nil; n-- {
+				if version == 2 {
+					r.utf() // certificate type, always X.509
+				}
+				chain = append(chain, r.bytes())
+			}
+			if r.err != nil {
+				break
+			}
+			key, err := jksDecryptKey(encrypted, keyPassword)
+			if err != nil {
+				return nil, fmt.Errorf("unable to decrypt JKS key %q: %w", alias, err)
+			}
+			ks.keys = append(ks.keys, jksKeyEntry{alias, key, chain})
+		case jksTrusted:
+			if version == 2 {
+				r.utf()
+			}
+			ks.certs = append(ks.certs, r.bytes())
+		default:
+			return nil, fmt.Errorf("unknown JKS entry tag %d for alias %q", tag, alias)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &ks, nil
+}
+
+// jksDecryptKey decrypts a JKS protected private key, returning the PKCS #8
+// DER encoded key.
+func jksDecryptKey(der []byte, password string) ([]byte, error) {
+	var info struct {
+		Algo pkix.AlgorithmIdentifier
+		Data []byte
+	}
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	if !info.Algo.Algorithm.Equal(oidJKSKeyProtector) {
+		return nil, fmt.Errorf("unsupported key protection algorithm %v", info.Algo.Algorithm)
+	}
+	if len(info.Data) < 2*sha1.Size {
+		return nil, errors.New("encrypted key is too short")
+	}
+
+	var (
+		pw         = jksPassword(password)
+		salt       = info.Data[:sha1.Size]
+		encrypted  = info.Data[sha1.Size : len(info.Data)-sha1.Size]
+		check      = info.Data[len(info.Data)-sha1.Size:]
+		plain      = make([]byte, len(encrypted))
+		digest     = salt
+		h          = sha1.New() //nolint:gosec // see above
+		keystreamI = sha1.Size
+	)
+	for i := range encrypted {
+		if keystreamI == sha1.Size {
+			h.Reset()
+			h.Write(pw)
+			h.Write(digest)
+			digest = h.Sum(nil)
+			keystreamI = 0
+		}
+		plain[i] = encrypted[i] ^ digest[keystreamI]
+		keystreamI++
+	}
+
+	h.Reset()
+	h.Write(pw)
+	h.Write(plain)
+	if !bytes.Equal(h.Sum(nil), check) {
+		return nil, errors.New("incorrect key password")
+	}
+	if _, err := x509.ParsePKCS8PrivateKey(plain); err != nil {
+		return nil, fmt.Errorf("decrypted key is not PKCS #8: %w", err)
+	}
+	return plain, nil
+}
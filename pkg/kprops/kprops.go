@@ -0,0 +1,493 @@
+// Package kprops parses Java client.properties files into kgo options.
+//
+// This allows one configuration file to be shared between Java and Go
+// clients. Every key that is understood is converted to the equivalent kgo
+// option. Keys that only make sense in Java (serializers and deserializers,
+// and Java security provider algorithms) are ignored, and any other key
+// returns an error: an unsupported key may change how a client behaves, and
+// silently dropping it would make the Go client behave differently than the
+// Java client.
+//
+// TLS is configured from PEM, JKS, or PKCS12 truststores and keystores, and
+// SASL is configured for the PLAIN and SCRAM mechanisms from sasl.jaas.config.
+package kprops
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ParseFile reads the Java properties file at path and returns the kgo
+// options it specifies. See ParseMap for more details.
+func ParseFile(path string) ([]kgo.Opt, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// Parse reads Java properties from r and returns the kgo options they specify.
+// See ParseMap for more details.
+func Parse(r io.Reader) ([]kgo.Opt, error) {
+	props, err := ReadProperties(r)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMap(props)
+}
+
+// ParseMap returns the kgo options for the given Java client properties.
+//
+// All problems are returned at once in a single error: every unsupported key
+// and every invalid value. Unsupported keys are keys that have no kgo
+// equivalent, and keys with values that kgo does not support (such as
+// sasl.mechanism=GSSAPI).
+//
+// The returned options are meant to be passed to kgo.NewClient before any
+// options specific to your application, such that your own options can
+// override anything from the properties.
+func ParseMap(props map[string]string) ([]kgo.Opt, error) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := &parser{}
+	var unsupported []string
+	for _, k := range keys {
+		v := strings.TrimSpace(props[k])
+		known, err := p.parse(k, v)
+		if !known {
+			unsupported = append(unsupported, k)
+			continue
+		}
+		if err != nil {
+			p.errs = append(p.errs, fmt.Errorf("invalid %s=%q: %w", k, v, err))
+		}
+	}
+	if len(unsupported) > 0 {
+		p.errs = append([]error{fmt.Errorf("unsupported properties: %s", strings.Join(unsupported, ", "))}, p.errs...)
+	}
+	p.finish()
+	if len(p.errs) > 0 {
+		return nil, errors.Join(p.errs...)
+	}
+	return p.opts, nil
+}
+
+// ignored contains Java-only keys that have no meaning in Go.
+var ignored = map[string]bool{
+	"key.serializer":             true,
+	"value.serializer":           true,
+	"key.deserializer":           true,
+	"value.deserializer":         true,
+	"ssl.keymanager.algorithm":   true,
+	"ssl.trustmanager.algorithm": true,
+}
+
+type parser struct {
+	opts []kgo.Opt
+	errs []error
+
+	securityProtocol string
+	saslMechanism    string
+	saslJAAS         string
+	tls              tlsProps
+
+	acks        string
+	idempotence *bool
+
+	compression string
+	levels      map[string]int
+
+	retryBackoff    time.Duration
+	retryBackoffMax time.Duration
+
+	recoveryStrategy string
+	recoveryTrigger  time.Duration
+}
+
+func (p *parser) add(opts ...kgo.Opt) { p.opts = append(p.opts, opts...) }
+
+// parse handles a single property, returning whether the key is known and
+// any error for the value.
+func (p *parser) parse(k, v string) (bool, error) {
+	if ignored[k] {
+		return true, nil
+	}
+
+	var err error
+	switch k {
+	case "bootstrap.servers":
+		var seeds []string
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				seeds = append(seeds, s)
+			}
+		}
+		p.add(kgo.SeedBrokers(seeds...))
+	case "client.id":
+		p.add(kgo.ClientID(v))
+	case "client.rack":
+		p.add(kgo.Rack(v))
+	case "client.dns.lookup":
+		switch v {
+		case "use_all_dns_ips":
+			p.add(kgo.DNSResolver(net.DefaultResolver))
+		default:
+			err = errors.New("only use_all_dns_ips is supported")
+		}
+	case "metadata.max.age.ms":
+		err = addMs(p, v, kgo.MetadataMaxAge)
+	case "retry.backoff.ms":
+		p.retryBackoff, err = ms(v)
+	case "retry.backoff.max.ms":
+		p.retryBackoffMax, err = ms(v)
+	case "connections.max.idle.ms":
+		err = addMs(p, v, kgo.ConnIdleTimeout)
+	case "socket.connection.setup.timeout.ms":
+		err = addMs(p, v, kgo.DialTimeout)
+	case "metadata.recovery.strategy":
+		p.recoveryStrategy = v
+		if v != "none" && v != "rebootstrap" {
+			err = errors.New("must be none or rebootstrap")
+		}
+	case "metadata.recovery.rebootstrap.trigger.ms":
+		p.recoveryTrigger, err = ms(v)
+
+	case "security.protocol":
+		p.securityProtocol = strings.ToUpper(v)
+		switch p.securityProtocol {
+		case "PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL":
+		default:
+			err = errors.New("must be PLAINTEXT, SSL, SASL_PLAINTEXT, or SASL_SSL")
+		}
+	case "sasl.mechanism":
+		p.saslMechanism = v
+	case "sasl.jaas.config":
+		p.saslJAAS = v
+
+	case "ssl.truststore.location":
+		p.tls.truststoreLocation = v
+	case "ssl.truststore.type":
+		p.tls.truststoreType = v
+	case "ssl.truststore.password":
+		p.tls.truststorePassword = v
+	case "ssl.truststore.certificates":
+		p.tls.truststoreCerts = v
+	case "ssl.keystore.location":
+		p.tls.keystoreLocation = v
+	case "ssl.keystore.type":
+		p.tls.keystoreType = v
+	case "ssl.keystore.password":
+		p.tls.keystorePassword = v
+	case "ssl.key.password":
+		p.tls.keyPassword = v
+	case "ssl.keystore.key":
+		p.tls.keystoreKey = v
+	case "ssl.keystore.certificate.chain":
+		p.tls.keystoreChain = v
+	case "ssl.endpoint.identification.algorithm":
+		p.tls.endpointIdentification = &v
+	case "ssl.protocol":
+		// The SSLContext protocol; only the enabled protocols limit
+		// which versions are used.
+		if _, ok := tlsVersions[v]; !ok && v != "TLS" {
+			err = errors.New("must be TLS, TLSv1.2, or TLSv1.3")
+		}
+	case "ssl.enabled.protocols":
+		for _, proto := range strings.Split(v, ",") {
+			version, ok := tlsVersions[strings.TrimSpace(proto)]
+			if !ok {
+				err = fmt.Errorf("unsupported protocol %q, only TLSv1.2 and TLSv1.3 are supported", proto)
+				break
+			}
+			if p.tls.minVersion == 0 || version < p.tls.minVersion {
+				p.tls.minVersion = version
+			}
+			if version > p.tls.maxVersion {
+				p.tls.maxVersion = version
+			}
+		}
+	case "ssl.cipher.suites":
+		p.tls.cipherSuites, err = cipherSuites(v)
+
+	case "acks":
+		p.acks = v
+		switch v {
+		case "all", "-1":
+			p.add(kgo.RequiredAcks(kgo.AllISRAcks()))
+		case "1":
+			p.add(kgo.RequiredAcks(kgo.LeaderAck()))
+		case "0":
+			p.add(kgo.RequiredAcks(kgo.NoAck()))
+		default:
+			err = errors.New("must be all, -1, 0, or 1")
+		}
+	case "enable.idempotence":
+		var b bool
+		if b, err = strconv.ParseBool(v); err == nil {
+			p.idempotence = &b
+		}
+	case "linger.ms":
+		err = addMs(p, v, kgo.ProducerLinger)
+	case "batch.size":
+		err = addI32(p, v, kgo.ProducerBatchMaxBytes)
+	case "max.request.size":
+		err = addI32(p, v, kgo.BrokerMaxWriteBytes)
+	case "compression.type":
+		p.compression = v
+	case "compression.gzip.level", "compression.lz4.level", "compression.zstd.level":
+		var level int
+		if level, err = strconv.Atoi(v); err == nil {
+			if p.levels == nil {
+				p.levels = make(map[string]int)
+			}
+			p.levels[strings.Split(k, ".")[1]] = level
+		}
+	case "max.in.flight.requests.per.connection":
+		var n int
+		if n, err = strconv.Atoi(v); err == nil {
+			p.add(kgo.MaxProduceRequestsInflightPerBroker(n))
+		}
+	case "transactional.id":
+		p.add(kgo.TransactionalID(v))
+	case "transaction.timeout.ms":
+		err = addMs(p, v, kgo.TransactionTimeout)
+	case "retries":
+		var n int64
+		if n, err = strconv.ParseInt(v, 10, 32); err == nil {
+			p.add(kgo.RecordRetries(int(n)))
+		}
+	case "delivery.timeout.ms":
+		err = addMs(p, v, kgo.RecordDeliveryTimeout)
+	case "request.timeout.ms":
+		// Java uses this both as the produce request timeout that
+		// brokers wait for acks, and as how long to wait for any
+		// response. kgo waits for the request's own timeout (if any)
+		// plus RequestTimeoutOverhead, so the overhead alone bounds
+		// requests without a timeout, as in Java.
+		var d time.Duration
+		if d, err = ms(v); err == nil {
+			p.add(kgo.ProduceRequestTimeout(d))
+			p.add(kgo.RequestTimeoutOverhead(d))
+		}
+	case "buffer.memory":
+		var n int64
+		if n, err = strconv.ParseInt(v, 10, 64); err == nil {
+			p.add(kgo.MaxBufferedBytes(int(n)))
+		}
+
+	case "group.id":
+		p.add(kgo.ConsumerGroup(v))
+	case "group.instance.id":
+		p.add(kgo.InstanceID(v))
+	case "auto.offset.reset":
+		switch v {
+		case "earliest":
+			p.add(kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()))
+		case "latest":
+			p.add(kgo.ConsumeResetOffset(kgo.NewOffset().AtEnd()))
+		case "none":
+			p.add(kgo.ConsumeResetOffset(kgo.NoResetOffset()))
+		default:
+			err = errors.New("must be earliest, latest, or none")
+		}
+	case "enable.auto.commit":
+		var b bool
+		if b, err = strconv.ParseBool(v); err == nil && !b {
+			p.add(kgo.DisableAutoCommit())
+		}
+	case "auto.commit.interval.ms":
+		err = addMs(p, v, kgo.AutoCommitInterval)
+	case "session.timeout.ms":
+		err = addMs(p, v, kgo.SessionTimeout)
+	case "heartbeat.interval.ms":
+		err = addMs(p, v, kgo.HeartbeatInterval)
+	case "max.poll.interval.ms":
+		err = addMs(p, v, kgo.RebalanceTimeout)
+	case "max.poll.records":
+		err = errors.New("kgo has no equivalent option; use PollRecords when polling")
+	case "fetch.min.bytes":
+		err = addI32(p, v, kgo.FetchMinBytes)
+	case "fetch.max.bytes":
+		err = addI32(p, v, kgo.FetchMaxBytes)
+	case "max.partition.fetch.bytes":
+		err = addI32(p, v, kgo.FetchMaxPartitionBytes)
+	case "fetch.max.wait.ms":
+		err = addMs(p, v, kgo.FetchMaxWait)
+	case "isolation.level":
+		switch v {
+		case "read_uncommitted":
+			p.add(kgo.FetchIsolationLevel(kgo.ReadUncommitted()))
+		case "read_committed":
+			p.add(kgo.FetchIsolationLevel(kgo.ReadCommitted()))
+		default:
+			err = errors.New("must be read_uncommitted or read_committed")
+		}
+	case "partition.assignment.strategy":
+		var balancers []kgo.GroupBalancer
+		if balancers, err = assignors(v); err == nil {
+			p.add(kgo.Balancers(balancers...))
+		}
+	case "allow.auto.create.topics":
+		var b bool
+		if b, err = strconv.ParseBool(v); err == nil && b {
+			p.add(kgo.AllowAutoTopicCreation())
+		}
+
+	default:
+		return false, nil
+	}
+	return true, err
+}
+
+// finish adds the options that depend on multiple properties.
+func (p *parser) finish() {
+	// Java disables idempotency if acks is not all and idempotency was
+	// not explicitly enabled; explicitly enabling it is an error.
+	if p.acks == "0" || p.acks == "1" {
+		if p.idempotence != nil && *p.idempotence {
+			p.errs = append(p.errs, errors.New("enable.idempotence=true requires acks=all"))
+		} else {
+			p.idempotence = new(bool)
+		}
+	}
+	if p.idempotence != nil && !*p.idempotence {
+		p.add(kgo.DisableIdempotentWrite())
+	}
+
+	if p.compression != "" {
+		codec, ok := map[string]kgo.CompressionCodec{
+			"none":   kgo.NoCompression(),
+			"gzip":   kgo.GzipCompression(),
+			"snappy": kgo.SnappyCompression(),
+			"lz4":    kgo.Lz4Compression(),
+			"zstd":   kgo.ZstdCompression(),
+		}[p.compression]
+		if !ok {
+			p.errs = append(p.errs, fmt.Errorf("invalid compression.type=%q: must be none, gzip, snappy, lz4, or zstd", p.compression))
+		} else {
+			if level, ok := p.levels[p.compression]; ok {
+				codec = codec.WithLevel(level)
+			}
+			p.add(kgo.ProducerBatchCompression(codec))
+		}
+	}
+
+	if p.retryBackoff > 0 || p.retryBackoffMax > 0 {
+		p.add(kgo.RetryBackoffFn(javaBackoff(p.retryBackoff, p.retryBackoffMax)))
+	}
+
+	switch p.recoveryStrategy {
+	case "none":
+		p.add(kgo.RebootstrapAfter(0))
+	case "rebootstrap":
+		trigger := p.recoveryTrigger
+		if trigger <= 0 {
+			trigger = 5 * time.Minute // Java's metadata.recovery.rebootstrap.trigger.ms default
+		}
+		p.add(kgo.RebootstrapAfter(trigger))
+	}
+
+	if strings.HasPrefix(p.securityProtocol, "SASL_") {
+		mechanism, err := saslMechanism(p.saslMechanism, p.saslJAAS)
+		if err != nil {
+			p.errs = append(p.errs, err)
+		} else {
+			p.add(kgo.SASL(mechanism))
+		}
+	}
+	if strings.HasSuffix(p.securityProtocol, "SSL") {
+		tc, err := p.tls.config()
+		if err != nil {
+			p.errs = append(p.errs, err)
+		} else {
+			p.add(kgo.DialTLSConfig(tc))
+		}
+	}
+}
+
+// javaBackoff mirrors the Java client's retry backoff: exponential from base
+// to max with 20% jitter. Java defaults to a base of 100ms and max of 1s.
+func javaBackoff(base, ceil time.Duration) func(int) time.Duration {
+	if base <= 0 {
+		base = 100 * time.Millisecond
+	}
+	if ceil <= 0 {
+		ceil = time.Second
+	}
+	return func(tries int) time.Duration {
+		if ceil <= base {
+			return base
+		}
+		backoff := float64(base) * math.Pow(2, float64(tries-1))
+		backoff *= 0.8 + 0.4*rand.Float64() //nolint:gosec // jitter does not need crypto/rand
+		if backoff > float64(ceil) {
+			backoff = float64(ceil)
+		}
+		return time.Duration(backoff)
+	}
+}
+
+func ms(v string) (time.Duration, error) {
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errors.New("must not be negative")
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
+func addMs[O kgo.Opt](p *parser, v string, fn func(time.Duration) O) error {
+	d, err := ms(v)
+	if err == nil {
+		p.add(fn(d))
+	}
+	return err
+}
+
+func addI32[O kgo.Opt](p *parser, v string, fn func(int32) O) error {
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err == nil {
+		p.add(fn(int32(n)))
+	}
+	return err
+}
+
+// assignors converts Java partition assignor class names to balancers.
+func assignors(v string) ([]kgo.GroupBalancer, error) {
+	var balancers []kgo.GroupBalancer
+	for _, class := range strings.Split(v, ",") {
+		class = strings.TrimSpace(class)
+		switch class[strings.LastIndexByte(class, '.')+1:] {
+		case "RangeAssignor":
+			balancers = append(balancers, kgo.RangeBalancer())
+		case "RoundRobinAssignor":
+			balancers = append(balancers, kgo.RoundRobinBalancer())
+		case "StickyAssignor":
+			balancers = append(balancers, kgo.StickyBalancer())
+		case "CooperativeStickyAssignor":
+			balancers = append(balancers, kgo.CooperativeStickyBalancer())
+		default:
+			return nil, fmt.Errorf("unsupported assignor %q", class)
+		}
+	}
+	return balancers, nil
+}
@@ -0,0 +1,279 @@
+package kprops
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // JKS is defined in terms of sha1
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func TestReadProperties(t *testing.T) {
+	in := `
+# comment
+! also a comment
+a=1
+b = 2
+c: 3
+d 4
+e=multi \
+    line
+f=esc\=apedA\tx
+g\ key=5
+empty=
+`
+	got, err := ReadProperties(strings.NewReader(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := map[string]string{
+		"a":     "1",
+		"b":     "2",
+		"c":     "3",
+		"d":     "4",
+		"e":     "multi line",
+		"f":     "esc=apedA\tx",
+		"g key": "5",
+		"empty": "",
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %q != exp %q", got, exp)
+	}
+}
+
+func TestParseMap(t *testing.T) {
+	opts, err := ParseMap(map[string]string{
+		"bootstrap.servers":             "localhost:9092, localhost:9093",
+		"client.id":                     "cid",
+		"key.serializer":                "org.apache.kafka.common.serialization.StringSerializer",
+		"linger.ms":                     "20",
+		"acks":                          "1",
+		"group.id":                      "grp",
+		"session.timeout.ms":            "30000",
+		"request.timeout.ms":            "45000",
+		"partition.assignment.strategy": "org.apache.kafka.clients.consumer.RangeAssignor",
+		"security.protocol":             "SASL_PLAINTEXT",
+		"sasl.mechanism":                "SCRAM-SHA-512",
+		"sasl.jaas.config":              `org.apache.kafka.common.security.scram.ScramLoginModule required username="alice" password="p w;\"";`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl, err := kgo.NewClient(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	for _, test := range []struct {
+		opt any
+		exp any
+	}{
+		{kgo.ClientID, "cid"},
+		{kgo.ProducerLinger, 20 * time.Millisecond},
+		{kgo.RequiredAcks, kgo.LeaderAck()},
+		{kgo.DisableIdempotentWrite, true},
+		{kgo.ConsumerGroup, "grp"},
+		{kgo.SessionTimeout, 30 * time.Second},
+		{kgo.ProduceRequestTimeout, 45 * time.Second},
+		{kgo.RequestTimeoutOverhead, 45 * time.Second},
+	} {
+		if got := cl.OptValue(test.opt); got != test.exp {
+			t.Errorf("got %v != exp %v", got, test.exp)
+		}
+	}
+	if seeds := cl.OptValue(kgo.SeedBrokers).([]string); len(seeds) != 2 {
+		t.Errorf("got seeds %v, exp 2", seeds)
+	}
+	if sasls := cl.OptValues(kgo.SASL); len(sasls) != 1 || sasls[0] == nil {
+		t.Errorf("got sasls %v, exp one", sasls)
+	}
+}
+
+func TestParseMapErrors(t *testing.T) {
+	_, err := ParseMap(map[string]string{
+		"bootstrap.servers":  "localhost:9092",
+		"unknown.b":          "x",
+		"unknown.a":          "x",
+		"linger.ms":          "soon",
+		"acks":               "0",
+		"enable.idempotence": "true",
+		"security.protocol":  "SASL_SSL",
+		"sasl.mechanism":     "GSSAPI",
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, exp := range []string{
+		"unsupported properties: unknown.a, unknown.b",
+		`invalid linger.ms="soon"`,
+		"enable.idempotence=true requires acks=all",
+		"GSSAPI is not supported",
+	} {
+		if !strings.Contains(err.Error(), exp) {
+			t.Errorf("error %q does not contain %q", err, exp)
+		}
+	}
+}
+
+func TestParseJAAS(t *testing.T) {
+	c, err := parseJAAS(`org.apache.kafka.common.security.plain.PlainLoginModule required username=bob password="a \"b\" c";`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := &jaasConfig{
+		module:  "org.apache.kafka.common.security.plain.PlainLoginModule",
+		options: map[string]string{"username": "bob", "password": `a "b" c`},
+	}
+	if !reflect.DeepEqual(c, exp) {
+		t.Errorf("got %+v != exp %+v", c, exp)
+	}
+	if _, err := parseJAAS(`a required; b required;`); err == nil {
+		t.Error("expected error for multiple login modules")
+	}
+}
+
+func TestTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "test"}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	for _, test := range []struct {
+		name  string
+		props tlsProps
+	}{
+		{"pem", tlsProps{
+			truststoreLocation: write("ca.pem", certPEM),
+			keystoreKey:        string(keyPEM),
+			keystoreChain:      string(certPEM),
+		}},
+		{"jks", tlsProps{
+			truststoreLocation: write("trust.jks", encodeJKS(t, "trustpw", "", der, nil)),
+			truststorePassword: "trustpw",
+			keystoreLocation:   write("key.jks", encodeJKS(t, "storepw", "keypw", der, pkcs8)),
+			keystorePassword:   "storepw",
+			keyPassword:        "keypw",
+		}},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			tc, err := test.props.config()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(tc.Certificates) != 1 || !bytes.Equal(tc.Certificates[0].Certificate[0], der) {
+				t.Error("keystore certificate not loaded")
+			}
+			if tc.RootCAs == nil || tc.MinVersion != tls.VersionTLS12 {
+				t.Error("truststore not loaded")
+			}
+		})
+	}
+
+	bad := tlsProps{
+		keystoreLocation: write("bad.jks", encodeJKS(t, "storepw", "keypw", der, pkcs8)),
+		keystorePassword: "storepw",
+		keyPassword:      "wrong",
+	}
+	if _, err := bad.config(); err == nil || !strings.Contains(err.Error(), "incorrect key password") {
+		t.Errorf("got err %v, expected incorrect key password", err)
+	}
+}
+
+// encodeJKS encodes a JKS keystore containing cert as a trusted certificate,
+// or as the chain for key if key is non-nil.
+func encodeJKS(t *testing.T, password, keyPassword string, cert, key []byte) []byte {
+	var b bytes.Buffer
+	u32 := func(v uint32) { binary.Write(&b, binary.BigEndian, v) }
+	utf := func(s string) { binary.Write(&b, binary.BigEndian, uint16(len(s))); b.WriteString(s) }
+	blob := func(v []byte) { u32(uint32(len(v))); b.Write(v) }
+
+	u32(jksMagic)
+	u32(2)
+	u32(1)
+	if key == nil {
+		u32(jksTrusted)
+		utf("ca")
+		b.Write(make([]byte, 8))
+		utf("X.509")
+		blob(cert)
+	} else {
+		pw := jksPassword(keyPassword)
+		salt := make([]byte, sha1.Size)
+		rand.Read(salt)
+		data := append([]byte(nil), salt...)
+		digest := salt
+		for i := 0; i < len(key); i += sha1.Size {
+			h := sha1.New() //nolint:gosec // see above
+			h.Write(pw)
+			h.Write(digest)
+			digest = h.Sum(nil)
+			for j := 0; j < sha1.Size && i+j < len(key); j++ {
+				data = append(data, key[i+j]^digest[j])
+			}
+		}
+		h := sha1.New() //nolint:gosec // see above
+		h.Write(pw)
+		h.Write(key)
+		data = h.Sum(data)
+
+		encrypted, err := asn1.Marshal(struct {
+			Algo pkix.AlgorithmIdentifier
+			Data []byte
+		}{pkix.AlgorithmIdentifier{Algorithm: oidJKSKeyProtector, Parameters: asn1.NullRawValue}, data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		u32(jksPrivateKey)
+		utf("key")
+		b.Write(make([]byte, 8))
+		blob(encrypted)
+		u32(1)
+		utf("X.509")
+		blob(cert)
+	}
+
+	h := sha1.New() //nolint:gosec // see above
+	h.Write(jksPassword(password))
+	h.Write([]byte("Mighty Aphrodite"))
+	h.Write(b.Bytes())
+	return h.Sum(b.Bytes())
+}
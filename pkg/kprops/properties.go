@@ -0,0 +1,138 @@
+package kprops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadProperties reads a Java properties file, returning all keys and values.
+//
+// This supports the full java.util.Properties line format: "#" and "!"
+// comments, "=", ":", or whitespace separated keys and values, backslash line
+// continuations, and backslash escapes (including \uXXXX). If a key is
+// duplicated, the last value wins, as in Java.
+func ReadProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<20)
+
+	var (
+		lineno  int
+		logical strings.Builder
+	)
+	for scanner.Scan() {
+		lineno++
+		line := strings.TrimLeft(scanner.Text(), " \t\f")
+		if logical.Len() == 0 && (line == "" || line[0] == '#' || line[0] == '!') {
+			continue
+		}
+
+		// A line continues if it ends in an odd number of backslashes.
+		var trailing int
+		for i := len(line) - 1; i >= 0 && line[i] == '\\'; i-- {
+			trailing++
+		}
+		if trailing%2 == 1 {
+			logical.WriteString(line[:len(line)-1])
+			continue
+		}
+		logical.WriteString(line)
+
+		k, v, err := splitProperty(logical.String())
+		logical.Reset()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+		props[k] = v
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if logical.Len() > 0 {
+		k, v, err := splitProperty(logical.String())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineno, err)
+		}
+		props[k] = v
+	}
+	return props, nil
+}
+
+// splitProperty splits a logical line into an unescaped key and value.
+func splitProperty(line string) (string, string, error) {
+	// The key ends at the first unescaped '=', ':', or whitespace.
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if c == '\\' {
+			i++
+			continue
+		}
+		if c == '=' || c == ':' || c == ' ' || c == '\t' || c == '\f' {
+			end = i
+			break
+		}
+	}
+	rawKey, rest := line[:end], line[end:]
+
+	// The separator is any whitespace, optionally followed by one '=' or
+	// ':', optionally followed by more whitespace.
+	rest = strings.TrimLeft(rest, " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+
+	k, err := unescapeProperty(rawKey)
+	if err != nil {
+		return "", "", err
+	}
+	v, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return k, v, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, "\\") {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		if i == len(s) {
+			break
+		}
+		switch c = s[i]; c {
+		case 't':
+			sb.WriteByte('\t')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid \\u escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid \\u escape in %q", s)
+			}
+			sb.WriteRune(rune(r))
+			i += 4
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,141 @@
+package kprops
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// jaasConfig is a parsed sasl.jaas.config login module entry.
+type jaasConfig struct {
+	module  string
+	options map[string]string
+}
+
+// parseJAAS parses a single JAAS login module entry, i.e.:
+//
+//	org.apache.kafka.common.security.plain.PlainLoginModule required username="u" password="p";
+func parseJAAS(s string) (*jaasConfig, error) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, ";")
+
+	fields, err := jaasFields(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) < 2 {
+		return nil, errors.New("sasl.jaas.config must contain a login module and a control flag")
+	}
+	switch strings.ToLower(fields[1]) {
+	case "required", "requisite", "sufficient", "optional":
+	default:
+		return nil, fmt.Errorf("invalid sasl.jaas.config control flag %q", fields[1])
+	}
+
+	c := &jaasConfig{module: fields[0], options: make(map[string]string)}
+	for _, f := range fields[2:] {
+		k, v, ok := strings.Cut(f, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid sasl.jaas.config option %q, missing '='", f)
+		}
+		c.options[k] = v
+	}
+	return c, nil
+}
+
+// jaasFields splits s on whitespace, keeping (and unquoting) double quoted
+// sections intact.
+func jaasFields(s string) ([]string, error) {
+	var (
+		fields  []string
+		field   strings.Builder
+		inField bool
+		quoted  bool
+	)
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quoted && c == '\\' && i+1 < len(s):
+			i++
+			field.WriteByte(s[i])
+		case c == '"':
+			quoted = !quoted
+			inField = true
+		case !quoted && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			if inField {
+				fields = append(fields, field.String())
+				field.Reset()
+				inField = false
+			}
+		case !quoted && c == ';':
+			return nil, errors.New("sasl.jaas.config contains multiple login modules, only one is supported")
+		default:
+			field.WriteByte(c)
+			inField = true
+		}
+	}
+	if quoted {
+		return nil, errors.New("sasl.jaas.config has an unterminated quote")
+	}
+	if inField {
+		fields = append(fields, field.String())
+	}
+	return fields, nil
+}
+
+// saslMechanism returns the SASL mechanism for the sasl.mechanism and
+// sasl.jaas.config properties.
+func saslMechanism(mechanism, jaas string) (sasl.Mechanism, error) {
+	mechanism = strings.ToUpper(mechanism)
+	switch mechanism {
+	case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+	case "":
+		return nil, errors.New("sasl.mechanism is unset and defaults to GSSAPI, which is not supported; use the github.com/twmb/franz-go/pkg/sasl/kerberos package directly")
+	case "GSSAPI":
+		return nil, errors.New("sasl.mechanism GSSAPI is not supported; use the github.com/twmb/franz-go/pkg/sasl/kerberos package directly")
+	case "OAUTHBEARER":
+		return nil, errors.New("sasl.mechanism OAUTHBEARER is not supported because tokens come from Java callback handlers; use the github.com/twmb/franz-go/pkg/sasl/oauth package directly")
+	case "AWS_MSK_IAM":
+		return nil, errors.New("sasl.mechanism AWS_MSK_IAM is not supported because credentials come from the Java AWS SDK; use the github.com/twmb/franz-go/pkg/sasl/aws package directly")
+	default:
+		return nil, fmt.Errorf("unknown sasl.mechanism %q", mechanism)
+	}
+
+	if jaas == "" {
+		return nil, fmt.Errorf("sasl.mechanism %s requires sasl.jaas.config", mechanism)
+	}
+	c, err := parseJAAS(jaas)
+	if err != nil {
+		return nil, err
+	}
+	user, pass := c.options["username"], c.options["password"]
+	if user == "" || pass == "" {
+		return nil, errors.New("sasl.jaas.config must contain a username and password")
+	}
+
+	module := c.module[strings.LastIndexByte(c.module, '.')+1:]
+	switch mechanism {
+	case "PLAIN":
+		if module != "PlainLoginModule" {
+			return nil, fmt.Errorf("sasl.mechanism PLAIN requires the PlainLoginModule, not %s", c.module)
+		}
+		return plain.Auth{User: user, Pass: pass}.AsMechanism(), nil
+	default:
+		if module != "ScramLoginModule" {
+			return nil, fmt.Errorf("sasl.mechanism %s requires the ScramLoginModule, not %s", mechanism, c.module)
+		}
+		auth := scram.Auth{
+			User:    user,
+			Pass:    pass,
+			IsToken: strings.EqualFold(c.options["tokenauth"], "true"),
+		}
+		if mechanism == "SCRAM-SHA-256" {
+			return auth.AsSha256Mechanism(), nil
+		}
+		return auth.AsSha512Mechanism(), nil
+	}
+}
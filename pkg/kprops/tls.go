@@ -0,0 +1,253 @@
+package kprops
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/pkcs12" //nolint:staticcheck // the only PKCS12 decoder available without new dependencies
+)
+
+// tlsProps contains the ssl.* properties, which are combined into one
+// *tls.Config once all properties are parsed.
+type tlsProps struct {
+	truststoreLocation string
+	truststoreType     string
+	truststorePassword string
+	truststoreCerts    string
+
+	keystoreLocation string
+	keystoreType     string
+	keystorePassword string
+	keyPassword      string
+	keystoreKey      string
+	keystoreChain    string
+
+	endpointIdentification *string
+	minVersion, maxVersion uint16
+	cipherSuites           []uint16
+}
+
+// config builds a TLS config from the parsed properties.
+func (p *tlsProps) config() (*tls.Config, error) {
+	tc := &tls.Config{
+		MinVersion:   p.minVersion,
+		MaxVersion:   p.maxVersion,
+		CipherSuites: p.cipherSuites,
+	}
+	if tc.MinVersion == 0 {
+		tc.MinVersion = tls.VersionTLS12
+	}
+
+	if p.truststoreLocation != "" && p.truststoreCerts != "" {
+		return nil, errors.New("ssl.truststore.location and ssl.truststore.certificates cannot both be set")
+	}
+	if p.truststoreLocation != "" || p.truststoreCerts != "" {
+		var (
+			data = []byte(p.truststoreCerts)
+			typ  = p.truststoreType
+			err  error
+		)
+		if p.truststoreLocation != "" {
+			if data, err = os.ReadFile(p.truststoreLocation); err != nil {
+				return nil, fmt.Errorf("unable to read ssl.truststore.location: %w", err)
+			}
+		} else if typ == "" {
+			typ = "PEM"
+		}
+		certs, err := loadTruststore(data, typ, p.truststorePassword)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load truststore: %w", err)
+		}
+		tc.RootCAs = x509.NewCertPool()
+		for _, cert := range certs {
+			tc.RootCAs.AddCert(cert)
+		}
+	}
+
+	if p.keystoreLocation != "" && (p.keystoreKey != "" || p.keystoreChain != "") {
+		return nil, errors.New("ssl.keystore.location cannot be set with ssl.keystore.key nor ssl.keystore.certificate.chain")
+	}
+	if (p.keystoreKey == "") != (p.keystoreChain == "") {
+		return nil, errors.New("ssl.keystore.key and ssl.keystore.certificate.chain must be set together")
+	}
+	if p.keystoreLocation != "" || p.keystoreKey != "" {
+		var (
+			data = []byte(p.keystoreKey + "\n" + p.keystoreChain)
+			typ  = p.keystoreType
+			err  error
+		)
+		if p.keystoreLocation != "" {
+			if data, err = os.ReadFile(p.keystoreLocation); err != nil {
+				return nil, fmt.Errorf("unable to read ssl.keystore.location: %w", err)
+			}
+		} else if typ == "" {
+			typ = "PEM"
+		}
+		keyPassword := p.keyPassword
+		if keyPassword == "" {
+			keyPassword = p.keystorePassword
+		}
+		cert, err := loadKeystore(data, typ, p.keystorePassword, keyPassword)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load keystore: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+
+	// Java verifies hostnames by default (the "https" algorithm). An empty
+	// algorithm disables hostname verification, but the chain is still
+	// verified.
+	if p.endpointIdentification != nil {
+		switch alg := strings.ToLower(*p.endpointIdentification); alg {
+		case "https":
+		case "":
+			tc.InsecureSkipVerify = true
+			tc.VerifyConnection = verifyChainOnly(tc.RootCAs)
+		default:
+			return nil, fmt.Errorf("unsupported ssl.endpoint.identification.algorithm %q", alg)
+		}
+	}
+	return tc, nil
+}
+
+// verifyChainOnly returns a function that verifies the peer certificate chain
+// against roots (or the system roots if nil), without verifying the hostname.
+func verifyChainOnly(roots *x509.CertPool) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("broker did not present a certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+		}
+		for _, cert := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(cert)
+		}
+		_, err := cs.PeerCertificates[0].Verify(opts)
+		return err
+	}
+}
+
+var tlsVersions = map[string]uint16{
+	"TLSv1.2": tls.VersionTLS12,
+	"TLSv1.3": tls.VersionTLS13,
+}
+
+// cipherSuites converts a comma separated list of cipher suite names, which
+// are the same in Java and Go, to cipher suite IDs.
+func cipherSuites(v string) ([]uint16, error) {
+	byName := make(map[string]uint16)
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		byName[s.Name] = s.ID
+	}
+	var ids []uint16
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		id, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// storeType returns the keystore type, detecting it from the content if the
+// type was not specified.
+func storeType(data []byte, typ string) string {
+	if typ != "" {
+		return strings.ToUpper(typ)
+	}
+	switch {
+	case bytes.Contains(data, []byte("-----BEGIN")):
+		return "PEM"
+	case len(data) >= 4 && bytes.Equal(data[:4], []byte{0xfe, 0xed, 0xfe, 0xed}):
+		return "JKS"
+	default:
+		return "PKCS12"
+	}
+}
+
+func loadTruststore(data []byte, typ, password string) ([]*x509.Certificate, error) {
+	var ders [][]byte
+	switch typ = storeType(data, typ); typ {
+	case "PEM":
+		for block, rest := pem.Decode(data); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type == "CERTIFICATE" {
+				ders = append(ders, block.Bytes)
+			}
+		}
+	case "JKS":
+		ks, err := decodeJKS(data, password, password)
+		if err != nil {
+			return nil, err
+		}
+		ders = ks.certs
+	case "PKCS12":
+		blocks, err := pkcs12.ToPEM(data, password)
+		if err != nil {
+			return nil, err
+		}
+		for _, block := range blocks {
+			if block.Type == "CERTIFICATE" {
+				ders = append(ders, block.Bytes)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported store type %q", typ)
+	}
+	if len(ders) == 0 {
+		return nil, fmt.Errorf("%s truststore contains no certificates", typ)
+	}
+	certs := make([]*x509.Certificate, 0, len(ders))
+	for _, der := range ders {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+func loadKeystore(data []byte, typ, password, keyPassword string) (tls.Certificate, error) {
+	var pemData []byte
+	switch typ = storeType(data, typ); typ {
+	case "PEM":
+		if bytes.Contains(data, []byte("ENCRYPTED PRIVATE KEY")) {
+			return tls.Certificate{}, errors.New("encrypted PEM private keys are not supported")
+		}
+		pemData = data
+	case "JKS":
+		ks, err := decodeJKS(data, password, keyPassword)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		if len(ks.keys) != 1 {
+			return tls.Certificate{}, fmt.Errorf("JKS keystore must contain exactly one private key, found %d", len(ks.keys))
+		}
+		key := ks.keys[0]
+		pemData = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: key.key})
+		for _, der := range key.chain {
+			pemData = append(pemData, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+		}
+	case "PKCS12":
+		blocks, err := pkcs12.ToPEM(data, password)
+		if err != nil {
+			return tls.Certificate{}, err
+		}
+		for _, block := range blocks {
+			pemData = append(pemData, pem.EncodeToMemory(block)...)
+		}
+	default:
+		return tls.Certificate{}, fmt.Errorf("unsupported store type %q", typ)
+	}
+	return tls.X509KeyPair(pemData, pemData)
+}
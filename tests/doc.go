@@ -0,0 +1,4 @@
+// Package tests contains tests that need both kfake and the kgo in this
+// repository. kfake pins a published version of kgo, so tests of new kgo
+// features against kfake cannot live in either module.
+package tests
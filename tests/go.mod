@@ -0,0 +1,23 @@
+module github.com/twmb/franz-go/tests
+
+go 1.21
+
+require (
+	github.com/twmb/franz-go v1.16.1
+	github.com/twmb/franz-go/pkg/kfake v0.0.0-00010101000000-000000000000
+	github.com/twmb/franz-go/pkg/kmsg v1.8.0
+)
+
+require (
+	github.com/klauspost/compress v1.17.8 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/crypto v0.23.0 // indirect
+)
+
+// These tests run against the packages in this repository, rather than
+// against published versions: kfake pins a published kgo, so only a module
+// that replaces both can test new kgo features against kfake.
+replace (
+	github.com/twmb/franz-go => ../
+	github.com/twmb/franz-go/pkg/kfake => ../pkg/kfake
+)
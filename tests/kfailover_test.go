@@ -0,0 +1,160 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfailover"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// downable returns a kfake cluster that fails every request, closing the
+// connection, while down is true.
+func downable(t *testing.T, down *atomic.Bool) *kfake.Cluster {
+	t.Helper()
+	c, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "foo"), kfake.GroupMinSessionTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Control(func(kmsg.Request) (kmsg.Response, error, bool) {
+		c.KeepControl()
+		if down.Load() {
+			return nil, errors.New("down"), true
+		}
+		return nil, nil, false
+	})
+	return c
+}
+
+func TestFailover(t *testing.T) {
+	var primaryDown atomic.Bool
+	c1 := downable(t, &primaryDown)
+	defer c1.Close()
+	c2 := downable(t, new(atomic.Bool))
+	defer c2.Close()
+
+	// The secondary is a mirror of the primary that has five older
+	// records, so offsets differ between the clusters while timestamps
+	// are preserved.
+	base := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	record := func(i int) *kgo.Record {
+		return &kgo.Record{Topic: "foo", Value: []byte(strconv.Itoa(i)), Timestamp: base.Add(time.Duration(i) * time.Second)}
+	}
+	mirror, err := kgo.NewClient(kgo.SeedBrokers(c2.ListenAddrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mirror.Close()
+	for i := -5; i < 10; i++ {
+		if err := mirror.ProduceSync(context.Background(), record(i)).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := make(chan kfailover.Event, 10)
+	cl, err := kfailover.NewClient(
+		[]kgo.Opt{
+			kgo.SeedBrokers(c1.ListenAddrs()...),
+			kgo.ConsumerGroup("g"),
+			kgo.SessionTimeout(500 * time.Millisecond),
+			kgo.ConsumeTopics("foo"),
+			kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+			kgo.RetryTimeout(time.Second),
+		},
+		[]kgo.Opt{
+			kgo.SeedBrokers(c2.ListenAddrs()...),
+			kgo.ConsumerGroup("g"),
+			kgo.SessionTimeout(500 * time.Millisecond),
+			kgo.ConsumeTopics("foo"),
+			kgo.ConsumeResetOffset(kgo.NewOffset().AtStart()),
+			kgo.RetryTimeout(time.Second),
+		},
+		kfailover.CheckInterval(100*time.Millisecond),
+		kfailover.UnhealthyAfter(2),
+		kfailover.HealthyAfter(2),
+		kfailover.OnEvent(func(e kfailover.Event) { events <- e }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	wait := func(exp kfailover.EventType) kfailover.Event {
+		t.Helper()
+		select {
+		case e := <-events:
+			if e.Type != exp {
+				t.Fatalf("got event %+v, exp %s", e, exp)
+			}
+			return e
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for %s", exp)
+			return kfailover.Event{}
+		}
+	}
+	poll := func(n int) []*kgo.Record {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		var rs []*kgo.Record
+		for len(rs) < n {
+			fs := cl.PollRecords(ctx, n-len(rs))
+			if ctx.Err() != nil {
+				t.Fatalf("timed out polling, got %d of %d records", len(rs), n)
+			}
+			rs = append(rs, fs.Records()...)
+		}
+		return rs
+	}
+
+	// Produce to and consume from the primary.
+	for i := 0; i < 10; i++ {
+		if err := cl.ProduceSync(context.Background(), record(i)).FirstErr(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if rs := poll(5); string(rs[4].Value) != "4" || rs[4].Offset != 4 {
+		t.Fatalf("got last record %s at %d on the primary, exp 4 at 4", rs[4].Value, rs[4].Offset)
+	}
+
+	// The primary goes down: we fail over, translating our position by
+	// timestamp and committing it to the group on the secondary.
+	primaryDown.Store(true)
+	if e := wait(kfailover.EventFailover); e.Reason == nil || e.OffsetsErr != nil {
+		t.Fatalf("got failover %+v, exp a reason and no offsets error", e)
+	}
+	if rs := poll(1); string(rs[0].Value) != "4" || rs[0].Offset != 9 {
+		t.Fatalf("got first record %s at %d on the secondary, exp 4 at 9 (the last consumed record, again)", rs[0].Value, rs[0].Offset)
+	}
+	r := record(10)
+	if err := cl.ProduceSync(context.Background(), r).FirstErr(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Offset != 15 {
+		t.Errorf("got produced offset %d on the secondary, exp 15", r.Offset)
+	}
+
+	// Once the primary is back, we fail back and resume on the primary
+	// from the position we consumed to on the secondary. Our member in
+	// the primary's group could not leave while the primary was down, so
+	// we wait for its session to time out; until then, committing the
+	// translated offsets to the group fails.
+	rs := poll(3)
+	if last := rs[len(rs)-1]; string(last.Value) != "7" {
+		t.Fatalf("got last record %s on the secondary, exp 7", last.Value)
+	}
+	time.Sleep(time.Second)
+	primaryDown.Store(false)
+	if e := wait(kfailover.EventFailback); e.OffsetsErr != nil {
+		t.Fatalf("got failback offsets error %v", e.OffsetsErr)
+	}
+	if rs := poll(1); string(rs[0].Value) != "7" || rs[0].Offset != 7 {
+		t.Fatalf("got first record %s at %d after failing back, exp 7 at 7", rs[0].Value, rs[0].Offset)
+	}
+}
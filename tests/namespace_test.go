@@ -0,0 +1,51 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestNamespaceRequest(t *testing.T) {
+	c, err := kfake.NewCluster(kfake.NumBrokers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	seen := make(chan string, 1)
+	c.ControlKey(int16(kmsg.CreateTopics), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		seen <- kreq.(*kmsg.CreateTopicsRequest).Topics[0].Topic
+		return nil, nil, false
+	})
+
+	cl, err := kgo.NewClient(kgo.SeedBrokers(c.ListenAddrs()...), kgo.Namespace("tenant."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	req := kmsg.NewPtrCreateTopicsRequest()
+	rt := kmsg.NewCreateTopicsRequestTopic()
+	rt.Topic = "foo"
+	rt.NumPartitions = 1
+	rt.ReplicationFactor = 1
+	req.Topics = append(req.Topics, rt)
+	resp, err := req.RequestWith(context.Background(), cl)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if topic := <-seen; topic != "tenant.foo" {
+		t.Errorf("broker saw topic %q, exp tenant.foo", topic)
+	}
+	if req.Topics[0].Topic != "foo" {
+		t.Errorf("our request was modified to topic %q", req.Topics[0].Topic)
+	}
+	if len(resp.Topics) != 1 || resp.Topics[0].Topic != "foo" {
+		t.Errorf("got response topics %v, exp foo", resp.Topics)
+	}
+}
@@ -0,0 +1,84 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// BenchmarkProduceBatch compares producing records with ProduceBatch to
+// producing the same records with Produce, through the full produce path to a
+// kfake broker. Each op produces 1000 records and waits for them all; the
+// allocations reported include kfake's, which are the same for both.
+func BenchmarkProduceBatch(b *testing.B) {
+	const nrecs = 1000
+	keys := make([][]byte, nrecs)
+	for i := range keys {
+		keys[i] = []byte(strconv.Itoa(i))
+	}
+	value := bytes.Repeat([]byte("v"), 100)
+
+	for _, codec := range []struct {
+		name  string
+		codec kgo.CompressionCodec
+	}{
+		{"no compression", kgo.NoCompression()},
+		{"snappy", kgo.SnappyCompression()},
+		{"zstd", kgo.ZstdCompression()},
+	} {
+		c, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(3, "foo"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		cl, err := kgo.NewClient(
+			kgo.SeedBrokers(c.ListenAddrs()...),
+			kgo.DefaultProduceTopic("foo"),
+			kgo.ProducerBatchCompression(codec.codec),
+		)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(codec.name+"/Produce", func(b *testing.B) {
+			var wg sync.WaitGroup
+			promise := func(_ *kgo.Record, err error) {
+				if err != nil {
+					b.Error(err)
+				}
+				wg.Done()
+			}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				wg.Add(nrecs)
+				for j := 0; j < nrecs; j++ {
+					cl.Produce(context.Background(), &kgo.Record{Key: keys[j], Value: value}, promise)
+				}
+				wg.Wait()
+			}
+		})
+
+		b.Run(codec.name+"/ProduceBatch", func(b *testing.B) {
+			rs := make([]kgo.Record, nrecs)
+			done := make(chan error, 1)
+			promise := func(_ []kgo.Record, err error) { done <- err }
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for j := range rs {
+					rs[j] = kgo.Record{Key: keys[j], Value: value}
+				}
+				cl.ProduceBatch(context.Background(), rs, promise)
+				if err := <-done; err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		cl.Close()
+		c.Close()
+	}
+}
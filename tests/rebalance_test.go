@@ -0,0 +1,162 @@
+package tests
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+type rebalanceHook struct {
+	ch chan kgo.GroupRebalanceMetrics
+}
+
+func newRebalanceHook() *rebalanceHook {
+	return &rebalanceHook{ch: make(chan kgo.GroupRebalanceMetrics, 100)}
+}
+
+func (h *rebalanceHook) OnGroupRebalance(m kgo.GroupRebalanceMetrics) {
+	h.ch <- m
+}
+
+// until returns every stage up to and including the next complete stage.
+func (h *rebalanceHook) until(t *testing.T) []kgo.GroupRebalanceMetrics {
+	t.Helper()
+	var ms []kgo.GroupRebalanceMetrics
+	for {
+		select {
+		case m := <-h.ch:
+			ms = append(ms, m)
+			if m.Stage == kgo.GroupRebalanceComplete {
+				return ms
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for a rebalance to complete, got %v", ms)
+		}
+	}
+}
+
+func stages(ms []kgo.GroupRebalanceMetrics) []kgo.GroupRebalanceStage {
+	var ss []kgo.GroupRebalanceStage
+	for _, m := range ms {
+		ss = append(ss, m.Stage)
+	}
+	return ss
+}
+
+func partitions(m map[string][]int32) []int32 {
+	ps := append([]int32(nil), m["foo"]...)
+	sort.Slice(ps, func(i, j int) bool { return ps[i] < ps[j] })
+	return ps
+}
+
+func TestGroupRebalanceHook(t *testing.T) {
+	c, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(4, "foo"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	newConsumer := func(h *rebalanceHook) *kgo.Client {
+		cl, err := kgo.NewClient(
+			kgo.SeedBrokers(c.ListenAddrs()...),
+			kgo.ConsumerGroup("g"),
+			kgo.ConsumeTopics("foo"),
+			kgo.HeartbeatInterval(100*time.Millisecond),
+			kgo.WithHooks(h),
+			// Slow assignment down so that stage timings are
+			// measurable.
+			kgo.OnPartitionsAssigned(func(context.Context, *kgo.Client, map[string][]int32) {
+				time.Sleep(50 * time.Millisecond)
+			}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		go func() {
+			for !cl.PollFetches(context.Background()).IsClientClosed() {
+			}
+		}()
+		return cl
+	}
+
+	h1 := newRebalanceHook()
+	cl1 := newConsumer(h1)
+	defer cl1.Close()
+
+	first := h1.until(t)
+	ms := stages(first)
+	exp := []kgo.GroupRebalanceStage{
+		kgo.GroupRebalanceStart,
+		kgo.GroupRebalanceJoined,
+		kgo.GroupRebalanceSynced,
+		kgo.GroupRebalanceAssigned,
+		kgo.GroupRebalanceOffsetsFetched,
+		kgo.GroupRebalanceComplete,
+	}
+	if !reflect.DeepEqual(ms, exp) {
+		t.Fatalf("got stages %v, exp %v", ms, exp)
+	}
+	var last time.Duration
+	for _, m := range first {
+		if m.Group != "g" {
+			t.Errorf("%s: got group %q, exp g", m.Stage, m.Group)
+		}
+		if m.RebalanceDuration < last {
+			t.Errorf("%s: rebalance duration %v went backwards from %v", m.Stage, m.RebalanceDuration, last)
+		}
+		last = m.RebalanceDuration
+		if m.Stage >= kgo.GroupRebalanceJoined && (m.MemberID == "" || m.Generation < 1 || m.Protocol != "cooperative-sticky" || !m.Leader) {
+			t.Errorf("%s: got member %q generation %d protocol %q leader %v, exp the joined state", m.Stage, m.MemberID, m.Generation, m.Protocol, m.Leader)
+		}
+		switch m.Stage {
+		case kgo.GroupRebalanceStart, kgo.GroupRebalanceComplete:
+			if m.StageDuration != 0 {
+				t.Errorf("%s: got stage duration %v, exp 0", m.Stage, m.StageDuration)
+			}
+		case kgo.GroupRebalanceAssigned:
+			if m.StageDuration < 50*time.Millisecond {
+				t.Errorf("%s: got stage duration %v, exp at least the 50ms OnPartitionsAssigned took", m.Stage, m.StageDuration)
+			}
+		}
+		if m.Stage >= kgo.GroupRebalanceAssigned {
+			if ps := partitions(m.Assigned); !reflect.DeepEqual(ps, []int32{0, 1, 2, 3}) || len(m.Revoked) != 0 {
+				t.Errorf("%s: got assigned %v revoked %v, exp all partitions assigned", m.Stage, m.Assigned, m.Revoked)
+			}
+		}
+	}
+	if complete := first[len(first)-1]; complete.RebalanceDuration < 50*time.Millisecond {
+		t.Errorf("got complete rebalance duration %v, exp at least 50ms", complete.RebalanceDuration)
+	}
+
+	// A second member joins: the cooperative first member revokes half
+	// of its partitions in one rebalance, and the second member is
+	// assigned them in the next.
+	h2 := newRebalanceHook()
+	cl2 := newConsumer(h2)
+	defer cl2.Close()
+
+	var revoked []int32
+	for len(revoked) == 0 {
+		ms := h1.until(t)
+		if ms[0].Stage != kgo.GroupRebalanceStart || ms[0].Reason == "" {
+			t.Errorf("got first rebalance metrics %+v, exp a start with a reason", ms[0])
+		}
+		revoked = partitions(ms[len(ms)-1].Revoked)
+	}
+	if len(revoked) != 2 {
+		t.Fatalf("got revoked %v from the first member, exp two partitions", revoked)
+	}
+	var assigned []int32
+	for len(assigned) == 0 {
+		ms := h2.until(t)
+		assigned = partitions(ms[len(ms)-1].Assigned)
+	}
+	if !reflect.DeepEqual(assigned, revoked) {
+		t.Errorf("got %v assigned to the second member, exp %v revoked from the first", assigned, revoked)
+	}
+}
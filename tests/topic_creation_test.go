@@ -0,0 +1,188 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+type topicCreatedHook struct {
+	mu    sync.Mutex
+	calls []error
+}
+
+func (h *topicCreatedHook) OnProduceTopicCreated(_ string, _ int32, _ int16, _ time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.calls = append(h.calls, err)
+}
+
+// Concurrent producers in multiple clients create an unknown topic once.
+func TestCreateTopicOnProduce(t *testing.T) {
+	c, err := kfake.NewCluster(kfake.NumBrokers(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var (
+		createsMu sync.Mutex
+		creates   int
+	)
+	c.ControlKey(int16(kmsg.CreateTopics), func(kmsg.Request) (kmsg.Response, error, bool) {
+		c.KeepControl()
+		createsMu.Lock()
+		creates++
+		createsMu.Unlock()
+		return nil, nil, false
+	})
+
+	const (
+		topic           = "foo"
+		partitions      = 3
+		nclients, nrecs = 3, 20
+	)
+	var (
+		hooks   [nclients]*topicCreatedHook
+		wg      sync.WaitGroup
+		errsMu  sync.Mutex
+		errs    []error
+		promise = func(_ *kgo.Record, err error) {
+			if err != nil {
+				errsMu.Lock()
+				errs = append(errs, err)
+				errsMu.Unlock()
+			}
+			wg.Done()
+		}
+	)
+	for i := range hooks {
+		hooks[i] = new(topicCreatedHook)
+		cl, err := kgo.NewClient(
+			kgo.SeedBrokers(c.ListenAddrs()...),
+			kgo.DefaultProduceTopic(topic),
+			kgo.CreateTopicOnProduce(topic, kgo.TopicCreation{Partitions: partitions}),
+			kgo.WithHooks(hooks[i]),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer cl.Close()
+
+		wg.Add(nrecs)
+		for j := 0; j < nrecs; j++ {
+			go cl.Produce(context.Background(), kgo.StringRecord(strconv.Itoa(j)), promise)
+		}
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		t.Fatalf("got %d produce errors, first: %v", len(errs), errs[0])
+	}
+
+	// Each client creates the topic at most once no matter how many
+	// records were waiting on it, and only one creation succeeds: every
+	// other client sees TOPIC_ALREADY_EXISTS, which is success.
+	var created, calls int
+	for i, h := range hooks {
+		h.mu.Lock()
+		if len(h.calls) > 1 {
+			t.Errorf("client %d: got %d creations, expected at most 1", i, len(h.calls))
+		}
+		for _, err := range h.calls {
+			calls++
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, kerr.TopicAlreadyExists):
+				t.Errorf("client %d: got creation error %v", i, err)
+			}
+		}
+		h.mu.Unlock()
+	}
+	if created != 1 {
+		t.Errorf("got %d successful creations, expected 1", created)
+	}
+	createsMu.Lock()
+	if creates != calls {
+		t.Errorf("got %d create requests, expected one per hook call (%d)", creates, calls)
+	}
+	createsMu.Unlock()
+
+	adm, err := kgo.NewClient(kgo.SeedBrokers(c.ListenAddrs()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer adm.Close()
+	req := kmsg.NewPtrMetadataRequest()
+	reqTopic := kmsg.NewMetadataRequestTopic()
+	reqTopic.Topic = kmsg.StringPtr(topic)
+	req.Topics = append(req.Topics, reqTopic)
+	resp, err := req.RequestWith(context.Background(), adm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := kerr.ErrorForCode(resp.Topics[0].ErrorCode); err != nil || len(resp.Topics[0].Partitions) != partitions {
+		t.Errorf("got topic err %v and %d partitions, expected no error and %d", err, len(resp.Topics[0].Partitions), partitions)
+	}
+}
+
+func TestCreateTopicOnProduceFails(t *testing.T) {
+	for _, test := range []struct {
+		name   string
+		code   int16
+		expErr error
+	}{
+		// A non-retryable creation error fails the records immediately.
+		{"fatal", kerr.TopicAuthorizationFailed.Code, kerr.TopicAuthorizationFailed},
+		// A retryable one is retried, but unknown topic errors are
+		// counted again once the creation fails.
+		{"retryable", kerr.RequestTimedOut.Code, kerr.UnknownTopicOrPartition},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			c, err := kfake.NewCluster(kfake.NumBrokers(1))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			c.ControlKey(int16(kmsg.CreateTopics), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+				c.KeepControl()
+				req := kreq.(*kmsg.CreateTopicsRequest)
+				resp := req.ResponseKind().(*kmsg.CreateTopicsResponse)
+				for _, rt := range req.Topics {
+					st := kmsg.NewCreateTopicsResponseTopic()
+					st.Topic = rt.Topic
+					st.ErrorCode = test.code
+					resp.Topics = append(resp.Topics, st)
+				}
+				return resp, nil, true
+			})
+
+			cl, err := kgo.NewClient(
+				kgo.SeedBrokers(c.ListenAddrs()...),
+				kgo.DefaultProduceTopic("foo"),
+				kgo.CreateTopicOnProduce("foo", kgo.TopicCreation{}),
+				kgo.UnknownTopicRetries(2),
+				kgo.RetryBackoffFn(func(int) time.Duration { return 10 * time.Millisecond }),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cl.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := cl.ProduceSync(ctx, kgo.StringRecord("v")).FirstErr(); !errors.Is(err, test.expErr) {
+				t.Errorf("got produce err %v, expected %v", err, test.expErr)
+			}
+		})
+	}
+}